@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"strings"
 
 	"bitbucket.org/backend/core/entities"
@@ -14,11 +15,14 @@ import (
 // Auth interface for user authentication with Trinacia
 type Auth interface {
 	GetUser(authentication string) (*entities.User, error)
+	// WithContext returns the authentication with its requests bound to the context
+	WithContext(ctx context.Context) Auth
 }
 
 type cognito struct {
 	svc     *cognitoidentityprovider.CognitoIdentityProvider
 	storage user.Storage
+	ctx     context.Context
 }
 
 // NewCognitoAuth instanciates a cognito service
@@ -26,9 +30,19 @@ func NewCognitoAuth(sess *session.Session) Auth {
 	return &cognito{
 		svc:     cognitoidentityprovider.New(sess),
 		storage: user.New(sess),
+		ctx:     context.Background(),
 	}
 }
 
+// WithContext returns a copy of the authentication whose requests are cancelled with the context
+func (c *cognito) WithContext(ctx context.Context) Auth {
+	cc := *c
+	cc.ctx = ctx
+	cc.storage = c.storage.WithContext(ctx)
+
+	return &cc
+}
+
 // GetUser checks the authorization header and retrieves the user token
 func (c *cognito) GetUser(authentication string) (*entities.User, error) {
 	if !strings.Contains(authentication, "Bearer ") {
@@ -42,7 +56,7 @@ func (c *cognito) GetUser(authentication string) (*entities.User, error) {
 	in := &cognitoidentityprovider.GetUserInput{
 		AccessToken: aws.String(token),
 	}
-	out, err := c.svc.GetUser(in)
+	out, err := c.svc.GetUserWithContext(c.ctx, in)
 	if err != nil {
 		return nil, &logger.Error{
 			Level:   "Error",
@@ -0,0 +1,62 @@
+// Command schema creates or updates the trinacia table and applies its data migrations
+//
+//	schema -region us-east-1 -migrate
+//	schema -endpoint http://localhost:8000 -table trinacia-dev
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"bitbucket.org/backend/core/storage/schema"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func main() {
+	table := flag.String("table", schema.Trinacia.Name, "name of the table")
+	region := flag.String("region", "", "region of the table, the default region of the environment when empty")
+	endpoint := flag.String("endpoint", "", "endpoint of dynamo, e.g. of a local stand-in")
+	migrate := flag.Bool("migrate", false, "apply the pending data migrations")
+	flag.Parse()
+
+	config := aws.NewConfig()
+	if *region != "" {
+		config = config.WithRegion(*region)
+	}
+	if *endpoint != "" {
+		config = config.WithEndpoint(*endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	svc := dynamodb.New(sess)
+	ctx := context.Background()
+
+	t := schema.Trinacia
+	t.Name = *table
+	if err := t.Apply(ctx, svc); err != nil {
+		log.Fatalf("unable to apply the schema of %s: %s", t.Name, err)
+	}
+	log.Printf("table %s is up to date", t.Name)
+
+	if !*migrate {
+		version, err := schema.Version(ctx, svc, t.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("version %d, %d migrations available", version, len(schema.Migrations))
+		return
+	}
+	version, err := schema.Migrate(ctx, svc, t.Name, schema.Migrations)
+	if err != nil {
+		log.Fatalf("migration failed at version %d: %s", version, err)
+	}
+	log.Printf("migrated to version %d", version)
+}
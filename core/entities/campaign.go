@@ -5,6 +5,7 @@ import "bitbucket.org/backend/core/genetic"
 // Campaign object represent a Trinacia campaign
 type Campaign struct {
 	ID        string                `json:"id"`
+	Status    string                `json:"status,omitempty"`
 	Budget    string                `json:"budget"`
 	StartTime string                `json:"start_time"`
 	EndTime   string                `json:"end_time"`
@@ -20,3 +21,15 @@ type Media struct {
 	URL       string `json:"url,omitempty"`
 	ImageHash string `json:"image_hash,omitempty"`
 }
+
+// Evolution settings of the genetic algorithm used to optimize a segment
+type Evolution struct {
+	// PopulationSize is the number of ad sets of each campaign
+	PopulationSize int `json:"population_size"`
+	// EliteCount is the number of selected ad sets kept in the next campaign
+	EliteCount int `json:"elite_count"`
+	// mutation rate bounds of the campaign requests
+	MinMutationRate float64 `json:"min_mutation_rate"`
+	MaxMutationRate float64 `json:"max_mutation_rate"`
+	CrossoverRate   float64 `json:"crossover_rate"`
+}
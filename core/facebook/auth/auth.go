@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,19 +22,24 @@ import (
 type Auth interface {
 	AuthUser(code, userID string) (*entities.Facebook, error)
 	GetUser(userID string) (*entities.Facebook, bool, error)
+	// WithContext returns the authentication with its requests bound to the context
+	WithContext(ctx context.Context) Auth
 }
 
 type facebook struct {
 	userStore     user.Storage
 	platformStore platform.Storage
 	client        server.Client
+	// ctx is the context of the graph api and storage requests
+	ctx context.Context
 }
 
 // New auth facebook interface
 func New(sess *session.Session, config ...func(*facebook)) Auth {
 	f := &facebook{
 		platformStore: platform.NewFacebook(sess),
-		client:        server.New(),
+		client:        internal.NewClient(),
+		ctx:           context.Background(),
 	}
 
 	for _, fn := range config {
@@ -43,6 +49,29 @@ func New(sess *session.Session, config ...func(*facebook)) Auth {
 	return f
 }
 
+// WithContext returns a copy of the authentication whose graph api
+// and storage requests are cancelled with the context
+func (f *facebook) WithContext(ctx context.Context) Auth {
+	fc := *f
+	fc.ctx = ctx
+	fc.client = server.WithContext(ctx, f.client)
+	if f.userStore != nil {
+		fc.userStore = f.userStore.WithContext(ctx)
+	}
+	if f.platformStore != nil {
+		fc.platformStore = f.platformStore.WithContext(ctx)
+	}
+
+	return &fc
+}
+
+// Client configures the http client of the graph api calls
+func Client(config ...server.Option) func(*facebook) {
+	return func(f *facebook) {
+		f.client = internal.NewClient(config...)
+	}
+}
+
 var (
 	// ErrorNilCode the code to exchange for a facebook access token is nil
 	ErrorNilCode = errors.New("The code to exchange for a facebook access token is nil")
@@ -163,169 +192,69 @@ func (f *facebook) exchangeCode(code string) (string, error) {
 	}
 
 	if result.Error != nil {
-		return "", &logger.Error{
-			Level:   "Warning",
-			Err:     result.Error,
-			Message: "Response to the code exchange for a facebook  authentication contained an error",
-		}
+		return "", internal.ResponseError(result.Error, "Warning",
+			"Response to the code exchange for a facebook  authentication contained an error", nil)
 	}
 
 	return result.Token, nil
 }
 
 func (f *facebook) getPages(t, id string) ([]entities.Page, error) {
-	var (
-		result = struct {
-			Pages []entities.Page         `json:"data"`
-			Error *internal.FacebookError `json:"error"`
-		}{}
-	)
+	var pages = []entities.Page{}
 
 	uV := url.Values{}
 	uV.Add("access_token", t)
 	uV.Add("fields", "id,name,category,access_token")
 	u := internal.SetURL(fmt.Sprintf("%s/accounts", id), uV)
 
-	resp, err := f.client.Get(u)
+	err := internal.List(f.ctx, f.client, u, &pages)
 	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to perform get request to retrieve user facebook pages",
-		}
-	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to read response body during the retrieval of facebook pages",
-		}
-	}
-	err = json.Unmarshal(b, &result)
-	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to unmarshal result during the retrieval of facebook pages",
-		}
+		return nil, internal.ListError(err, "Unable to perform get request to retrieve user facebook pages",
+			"Response to retrieve facebook pages contained an error", nil)
 	}
 
-	if result.Error != nil {
-		return nil, &logger.Error{
-			Level:   "Error",
-			Err:     result.Error,
-			Message: "Response to retrieve facebook pages contained an error",
-		}
-	}
-
-	err = f.getInstagram(result.Pages)
+	err = f.getInstagram(pages)
 	if err != nil {
 		return nil, err
 	}
 
-	return result.Pages, nil
+	return pages, nil
 }
 
 func (f *facebook) getInstagram(pages []entities.Page) error {
-	type result struct {
-		Instagram []entities.Instagram    `json:"data"`
-		Error     *internal.FacebookError `json:"error"`
-	}
-
 	for i, p := range pages {
-		re := result{}
+		var instagram = []entities.Instagram{}
 		uV := url.Values{}
 		uV.Add("access_token", p.AccessToken)
 		uV.Add("fields", "id,username")
 		u := internal.SetURL(fmt.Sprintf("%s/instagram_accounts", p.ID), uV)
 
-		resp, err := f.client.Get(u)
-		if err != nil {
-			return &logger.Error{
-				Level:   "Panic",
-				Err:     err,
-				Message: "Unable to make get request during the retrieval of a page instagram",
-			}
-		}
-		defer resp.Body.Close()
-		b, err := ioutil.ReadAll(resp.Body)
+		err := internal.List(f.ctx, f.client, u, &instagram)
 		if err != nil {
-			return &logger.Error{
-				Level:   "Panic",
-				Err:     err,
-				Message: "Unable to read response body during the retrieval of a page instagram",
-			}
+			return internal.ListError(err, "Unable to make get request during the retrieval of a page instagram",
+				"Response to retrieve a page instagram contained an error", nil)
 		}
-		err = json.Unmarshal(b, &re)
-		if err != nil {
-			return &logger.Error{
-				Level:   "Panic",
-				Err:     err,
-				Message: "Unable to unmarshal result during the retrieval of a page instagram",
-			}
-		}
-		if re.Error != nil {
-			return &logger.Error{
-				Level:   "Error",
-				Err:     re.Error,
-				Message: "Response to retrieve a page instagram contained an error",
-			}
-		}
-		pages[i].Instagram = re.Instagram
+		pages[i].Instagram = instagram
 	}
 
 	return nil
 }
 
 func (f *facebook) getAdAccounts(t, id string) ([]entities.AdAccount, error) {
-	var (
-		result = struct {
-			AdAccounts []entities.AdAccount    `json:"data"`
-			Error      *internal.FacebookError `json:"error"`
-		}{}
-	)
+	var adAccounts = []entities.AdAccount{}
 
 	uV := url.Values{}
 	uV.Add("access_token", t)
 	uV.Add("fields", "id,account_id,name,currency")
 	u := internal.SetURL(fmt.Sprintf("%s/adaccounts", id), uV)
 
-	resp, err := f.client.Get(u)
-	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to perform get request to retrieve user ad accounts",
-		}
-	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	err := internal.List(f.ctx, f.client, u, &adAccounts)
 	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to read response body during the retrieval of user ad accounts",
-		}
-	}
-	err = json.Unmarshal(b, &result)
-	if err != nil {
-		return nil, &logger.Error{
-			Level:   "Panic",
-			Err:     err,
-			Message: "Unable to unmarshal result during the retrieval of user ad accounts",
-		}
-	}
-	if result.Error != nil {
-		return nil, &logger.Error{
-			Level:   "Error",
-			Err:     result.Error,
-			Message: "Response to retrieve a user's ad accounts contained an error",
-		}
+		return nil, internal.ListError(err, "Unable to perform get request to retrieve user ad accounts",
+			"Response to retrieve a user's ad accounts contained an error", nil)
 	}
 
-	return result.AdAccounts, nil
+	return adAccounts, nil
 }
 
 func (f *facebook) debugToken(t string) (*debugAccessToken, error) {
@@ -368,11 +297,7 @@ func (f *facebook) debugToken(t string) (*debugAccessToken, error) {
 	}
 
 	if result.Error != nil {
-		return nil, &logger.Error{
-			Level:   "Error",
-			Err:     result.Error,
-			Message: "Response to debug a user's token contained an error",
-		}
+		return nil, internal.ResponseError(result.Error, "Error", "Response to debug a user's token contained an error", nil)
 	}
 
 	return result.DebugAccessToken, nil
@@ -1,14 +1,15 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"flag"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -24,6 +25,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// record sends the requests of the replay tests to the graph api and writes their fixtures,
+// the code and the access token of the recordings are read from the environment
+var record = flag.Bool("record", false, "record the graph api fixtures of the replay tests")
+
 var (
 	// error to provide after failing client request
 	errFailRequest = errors.New("request failed by client")
@@ -31,27 +36,55 @@ var (
 	errFailStorage = errors.New("request failed by store")
 )
 
-type client struct {
-	// client request errors
-	FailExchangeCodeRequest  bool
-	FailDebugTokenRequest    bool
-	FailGetPagesRequest      bool
-	FailGetInstagramRequest  bool
-	FailGetAdAccountsRequest bool
-
-	// facebook api error
-	FailExchangeCode  bool
-	FailDebugToken    bool
-	FailGetPages      bool
-	FailGetInstagram  bool
-	FailGetAdAccounts bool
-
-	// fail unmarshal operation
-	// by sending malformed json response
-	FailUnmarshal bool
+// replayClient returns a client that replays the graph api fixture, the fixture
+// is recorded again by the tests that save it when they run with the -record flag
+func replayClient(t *testing.T, fixture string, save bool) (server.Client, *server.Recorder) {
+	mode := server.Replay
+	if *record && save {
+		mode = server.Record
+	}
+	// the code and the app settings of the code exchange change with every recording
+	r, err := server.NewRecorder(filepath.Join("test-fixtures", "graph", fixture+".json"), mode,
+		server.Redact("code", "client_id", "redirect_uri"))
+	if err != nil {
+		t.Fatal("Unable to load graph api fixture: ", err)
+	}
+	if mode == server.Record {
+		t.Cleanup(func() {
+			if err := r.Save(); err != nil {
+				t.Error("Unable to save graph api fixture: ", err)
+			}
+		})
+	}
+
+	return server.New(server.Transport(r)), r
+}
 
+// failingClient replays the graph api requests and fails the requests whose
+// path ends with the failing path, with a request error or an error response
+type failingClient struct {
 	server.Client
-	t *testing.T
+	path string
+	// response fails the request with a graph api error response
+	response bool
+}
+
+func (c *failingClient) Get(u string) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	if c.path == "" || !strings.HasSuffix(requestURL.Path, c.path) {
+		return c.Client.Get(u)
+	}
+	if !c.response {
+		return nil, errFailRequest
+	}
+
+	w := httptest.NewRecorder()
+	io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+
+	return w.Result(), nil
 }
 
 type platformStore struct {
@@ -65,6 +98,10 @@ type platformStore struct {
 	t *testing.T
 }
 
+func (s *platformStore) WithContext(ctx context.Context) platform.Storage {
+	return s
+}
+
 func (s *platformStore) StoreFacebook(userID string, f *entities.Facebook) error {
 	if s.FailStoreFacebook {
 		return errFailStorage
@@ -81,108 +118,9 @@ func (s *platformStore) GetFacebook(userID string) (*entities.Facebook, error) {
 	return s.expected, nil
 }
 
-func (c *client) Get(u string) (*http.Response, error) {
-	requestURL, err := url.Parse(u)
-	if err != nil {
-		c.t.Fatal("Unable to parse request body from get request: ", err)
-	}
-
-	switch {
-	case requestURL.Path == "/v8.0/oauth/access_token":
-		if c.FailExchangeCodeRequest {
-			return nil, errFailRequest
-		}
-	case requestURL.Path == "/v8.0/debug_token":
-		if c.FailDebugTokenRequest {
-			return nil, errFailRequest
-		}
-	case strings.Contains(requestURL.Path, "/accounts"):
-		if c.FailGetPagesRequest {
-			return nil, errFailRequest
-		}
-	case strings.Contains(requestURL.Path, "/instagram_accounts"):
-		if c.FailGetInstagramRequest {
-			return nil, errFailRequest
-		}
-	case strings.Contains(requestURL.Path, "/adaccounts"):
-		if c.FailGetAdAccountsRequest {
-			return nil, errFailRequest
-		}
-	}
-
-	w := httptest.NewRecorder()
-	handler := c.authHandler
-	handler(w, requestURL)
-
-	return w.Result(), nil
-}
-
-func (c *client) authHandler(w http.ResponseWriter, requestURL *url.URL) {
-	if c.FailUnmarshal {
-		io.WriteString(w, `{"status":200,}`)
-		return
-	}
-
-	// handle controlled failures
-	switch {
-	case requestURL.Path == "/v8.0/oauth/access_token":
-		if c.FailExchangeCode {
-			io.WriteString(w, `{"error":{"message":"failing operation"}}`)
-			return
-		}
-
-	case requestURL.Path == "/v8.0/debug_token":
-		if c.FailDebugToken {
-			io.WriteString(w, `{"error":{"message":"failing operation"}}`)
-			return
-		}
-	case strings.Contains(requestURL.Path, "/accounts"):
-		if c.FailGetPages {
-			io.WriteString(w, `{"error":{"message":"failing operation"}}`)
-			return
-		}
-
-	case strings.Contains(requestURL.Path, "/instagram_accounts"):
-		if c.FailGetInstagram {
-			io.WriteString(w, `{"error":{"message":"failing operation"}}`)
-			return
-		}
-
-	case strings.Contains(requestURL.Path, "/adaccounts"):
-		if c.FailGetAdAccounts {
-			io.WriteString(w, `{"error":{"message":"failing operation"}}`)
-			return
-		}
-	}
-	// read data from files
-	if _, err := os.Open(fmt.Sprintf("test-fixtures%s.json", requestURL.Path)); err != nil {
-		io.WriteString(w, fmt.Sprintf(`{
-			"error": {
-			  "message": "(#803) Some of the aliases you requested do not exist: %s",
-			  "type": "OAuthException",
-			  "code": 803,
-			  "fbtrace_id": "AOFibG3hmX4AtsTdmEelqLn"
-			}
-		}`, requestURL.Path))
-		return
-	}
-
-	b, err := ioutil.ReadFile(fmt.Sprintf("test-fixtures%s.json", requestURL.Path))
-	if err != nil {
-		c.t.Fatalf("err: %s", err)
-	}
-
-	io.WriteString(w, string(b))
-}
-
 type helper struct {
-	// clientFailures array of failures
-	// for the requests or unmarshal errors
-	clientFailures []string
-
-	// facebookFailures is an array
-	// of errors from facebook api operations
-	facebookFailures []string
+	// client of the graph api requests
+	client server.Client
 
 	// storageFailures is an array of
 	// failures from the storage
@@ -200,23 +138,11 @@ func (h *helper) testConfig(f *facebook) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			h.t.Fatalf("Unable to set client failure value: %v", r)
+			h.t.Fatalf("Unable to set storage failure value: %v", r)
 		}
 	}()
 
-	c := &client{
-		t: h.t,
-	}
-	cV := reflect.ValueOf(c)
-	for _, clientFailure := range h.clientFailures {
-		v := reflect.Indirect(cV).FieldByName(clientFailure)
-		v.SetBool(true)
-	}
-	for _, apiFailure := range h.facebookFailures {
-		v := reflect.Indirect(cV).FieldByName(apiFailure)
-		v.SetBool(true)
-	}
-	f.client = c
+	f.client = h.client
 
 	p := &platformStore{
 		t:        h.t,
@@ -231,24 +157,34 @@ func (h *helper) testConfig(f *facebook) {
 }
 
 func TestAuthUser(t *testing.T) {
+	// the code is redacted from the fixture, the recordings exchange the code of the environment
+	code := "AQB2JdlAuasdfasdfasdfqwefwqeejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1"
+	if *record {
+		code = os.Getenv("code")
+	}
 	cases := []struct {
 		Name, Code, UserID string
-		clientFailures     []string
-		facebookFailures   []string
-		storageFailures    []string
-		Expected           *entities.Facebook
-		Error              error
+		// FailPath fails the requests whose path ends with it, with
+		// a graph api error response when FailResponse is set
+		FailPath        string
+		FailResponse    bool
+		storageFailures []string
+		// Record saves the fixture when the tests run with the -record flag
+		Record   bool
+		Expected *entities.Facebook
+		Error    error
 	}{
 		{
 			Name:   "Auth User",
-			Code:   "AQB2JdlAuasdfasdfasdfqwefwqeejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
+			Code:   code,
 			UserID: "123451432",
+			Record: true,
 			Expected: &entities.Facebook{
 				ID: "2730207623713666",
 				Pages: []entities.Page{
 					{
 						Category:    "Accessories",
-						AccessToken: "EAAHu3c2xquQBAJ3fU0ZAjo4E3BEAn5AaBZA37KlswrUMMAZAuTkWGmwMHfkljacanwUhRQT8bL7o0FbVUBltTjraXic9rTzOZB7ZAIqa6MVshlOjOstaIfs315MZBnfvk3ub24VtzHD4ITpfMSWS2woo4q0ZBDu9zPObq96WQWBGPO3NVaaApgPNR9SybcR9MyPnFxcwieVK27OrxskVax8",
+						AccessToken: "REDACTED",
 						ID:          "101564201278325",
 						Name:        "The gossip corner",
 						Instagram: []entities.Instagram{
@@ -260,55 +196,46 @@ func TestAuthUser(t *testing.T) {
 					},
 					{
 						Category:    "Food Delivery Service",
-						AccessToken: "EAAHu3c22fweqfqwefasdfasdApqAjsLgIeROCSgfxut1cXEtCYbRCyKVeKNdPKZBzkZCDwlUT0j50oWTFRweE48SmN5RZCp4DUZAFxcQlZCUNJSzDCtji8ldfqrfiMjWkwBzzfExddhhoeEETQ5OJniMMA8mkC85zJR4isYkmVidGL7Cwm0mFfISFEWuuRNZBbwfZANnkYaV7lqZAR4TYw",
+						AccessToken: "REDACTED",
 						ID:          "694235647641117",
 						Name:        "Ignis Cuisine",
-						Instagram: []entities.Instagram{
-							{
-								ID:   "3240241866073010",
-								Name: "thegossipocorner",
-							},
-						},
+						Instagram:   []entities.Instagram{},
 					},
 				},
 				AdAccounts: []entities.AdAccount{
 					{
 						AccountID: "656522844415498",
 						ID:        "act_656522844415498",
+						Name:      "Trinacia",
+						Currency:  "USD",
 					},
 				},
-				AccessToken: "EasdfasdfasdfasdfasdjUfPSZB5VAnpTfplKdYKYRMdf9L3kRzemFgquPHZBZB7ZCEgSUlLqqvobDpslavSX6hWsyjo3xrHuc40OYvRdR2ZCcmDUiyZAVsHgRgbBLiSCH5M2bNpv6nR7rAqZBurkvTNo4JGdSxqyKQNNX9yfoPaWKHiRY6oZAwZBJGmrRFLZCkSMBNyVpCZAWYMdZBf",
+				AccessToken: "REDACTED",
 			},
-			Error: nil,
 		},
 		{
-			Name:     "Missing User",
-			Code:     "AQB2JdlAuasdfasdfwqefasdfsajaZRRihLKxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID:   "",
-			Expected: nil,
+			Name:   "Missing User",
+			Code:   code,
+			UserID: "",
 			Error: &logger.Error{
 				Level: "Warning",
 				Err:   ErrorNilUser,
 			},
 		},
 		{
-			Name:     "Missing Code",
-			Code:     "",
-			UserID:   "12345",
-			Expected: nil,
+			Name:   "Missing Code",
+			Code:   "",
+			UserID: "12345",
 			Error: &logger.Error{
 				Level: "Warning",
 				Err:   ErrorNilCode,
 			},
 		},
 		{
-			Name:   "Fail Exchange Code Request",
-			Code:   "AQB2asdfasdfwqerf234efdsaLKxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailExchangeCodeRequest",
-			},
-			Expected: nil,
+			Name:     "Fail Exchange Code Request",
+			Code:     code,
+			UserID:   "123451432",
+			FailPath: "/oauth/access_token",
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailRequest,
@@ -316,59 +243,45 @@ func TestAuthUser(t *testing.T) {
 			},
 		},
 		{
-			Name:   "Fail API Operation to Exchange Code",
-			Code:   "AQB2JdlAuDV61cIy4fsadfasdasdfasdfasdfas23ejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailExchangeCode",
-			},
-			Expected: nil,
+			Name:         "Fail API Operation to Exchange Code",
+			Code:         code,
+			UserID:       "123451432",
+			FailPath:     "/oauth/access_token",
+			FailResponse: true,
 			Error: &logger.Error{
-				Level: "Warning",
-				Err: &internal.FacebookError{
-					Message: "failing operation",
-				},
+				Level:   "Warning",
+				Err:     &internal.FacebookError{Message: "failing operation"},
 				Message: "Response to the code exchange for a facebook  authentication contained an error",
 			},
 		},
 		{
-			Name:   "Fail Debug Token Request",
-			Code:   "AQB2JdlAuDVasdfasdfasdf7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailDebugTokenRequest",
-			},
-			Expected: nil,
+			Name:     "Fail Debug Token Request",
+			Code:     code,
+			UserID:   "123451432",
+			FailPath: "/debug_token",
 			Error: &logger.Error{
 				Level:   "Panic",
-				Message: "Unable to make get request during the debugging of a facebook access token",
 				Err:     errFailRequest,
+				Message: "Unable to make get request during the debugging of a facebook access token",
 			},
 		},
 		{
-			Name:   "Fail API Operation to Debug Token",
-			Code:   "AQB2JdlAuDV61cIyvJKH3eTlSixcGsajaZRRihLKasdfasdfasdfVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailDebugToken",
-			},
-			Expected: nil,
+			Name:         "Fail API Operation to Debug Token",
+			Code:         code,
+			UserID:       "123451432",
+			FailPath:     "/debug_token",
+			FailResponse: true,
 			Error: &logger.Error{
-				Level: "Error",
-				Err: &internal.FacebookError{
-					Message: "failing operation",
-				},
+				Level:   "Error",
+				Err:     &internal.FacebookError{Message: "failing operation"},
 				Message: "Response to debug a user's token contained an error",
 			},
 		},
 		{
-			Name:   "Fail Get Pages Request",
-			Code:   "AQB2JdlAuDV61cIyvJKH3eTlSasdfasdfasdfasdr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetPagesRequest",
-			},
-			Expected: nil,
+			Name:     "Fail Get Pages Request",
+			Code:     code,
+			UserID:   "123451432",
+			FailPath: "/accounts",
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailRequest,
@@ -376,29 +289,22 @@ func TestAuthUser(t *testing.T) {
 			},
 		},
 		{
-			Name:   "Fail API Operation to Get Pages",
-			Code:   "AQB2JdlAuDVasdfasdfasdB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetPages",
-			},
-			Expected: nil,
+			Name:         "Fail API Operation to Get Pages",
+			Code:         code,
+			UserID:       "123451432",
+			FailPath:     "/accounts",
+			FailResponse: true,
 			Error: &logger.Error{
-				Level: "Error",
-				Err: &internal.FacebookError{
-					Message: "failing operation",
-				},
+				Level:   "Error",
+				Err:     &internal.FacebookError{Message: "failing operation"},
 				Message: "Response to retrieve facebook pages contained an error",
 			},
 		},
 		{
-			Name:   "Fail Get Instagram Request",
-			Code:   "AQB2JdlAuDVasdfasdfasdfajaZRRihLKxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetInstagramRequest",
-			},
-			Expected: nil,
+			Name:     "Fail Get Instagram Request",
+			Code:     code,
+			UserID:   "123451432",
+			FailPath: "/instagram_accounts",
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailRequest,
@@ -406,29 +312,22 @@ func TestAuthUser(t *testing.T) {
 			},
 		},
 		{
-			Name:   "Fail API Operation to Get Instagram",
-			Code:   "AQB2JdlAuDV61cIyvJKH3easdfasdfejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetInstagram",
-			},
-			Expected: nil,
+			Name:         "Fail API Operation to Get Instagram",
+			Code:         code,
+			UserID:       "123451432",
+			FailPath:     "/instagram_accounts",
+			FailResponse: true,
 			Error: &logger.Error{
-				Level: "Error",
-				Err: &internal.FacebookError{
-					Message: "failing operation",
-				},
+				Level:   "Error",
+				Err:     &internal.FacebookError{Message: "failing operation"},
 				Message: "Response to retrieve a page instagram contained an error",
 			},
 		},
 		{
-			Name:   "Fail Get Ad Accounts Request",
-			Code:   "AQB2asdfasdfasdfasdfGsajaZRRihLKxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetAdAccountsRequest",
-			},
-			Expected: nil,
+			Name:     "Fail Get Ad Accounts Request",
+			Code:     code,
+			UserID:   "123451432",
+			FailPath: "/adaccounts",
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailRequest,
@@ -436,29 +335,24 @@ func TestAuthUser(t *testing.T) {
 			},
 		},
 		{
-			Name:   "Fail API Operation to Get Ad Accounts",
-			Code:   "AQB2JdlAuDasdfasdfajaZRRihLKxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
-			UserID: "123451432",
-			clientFailures: []string{
-				"FailGetAdAccounts",
-			},
-			Expected: nil,
+			Name:         "Fail API Operation to Get Ad Accounts",
+			Code:         code,
+			UserID:       "123451432",
+			FailPath:     "/adaccounts",
+			FailResponse: true,
 			Error: &logger.Error{
-				Level: "Error",
-				Err: &internal.FacebookError{
-					Message: "failing operation",
-				},
+				Level:   "Error",
+				Err:     &internal.FacebookError{Message: "failing operation"},
 				Message: "Response to retrieve a user's ad accounts contained an error",
 			},
 		},
 		{
 			Name:   "Fail Store Facebook",
-			Code:   "AQB2JdlAuDV61cIyvJKH3eTlSasdfasdfasdfxmejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1",
+			Code:   code,
 			UserID: "123451432",
 			storageFailures: []string{
 				"FailStoreFacebook",
 			},
-			Expected: nil,
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailStorage,
@@ -478,21 +372,55 @@ func TestAuthUser(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
+			client, r := replayClient(t, "auth_user", tc.Record)
 			h := &helper{
-				t:                t,
-				clientFailures:   tc.clientFailures,
-				facebookFailures: tc.facebookFailures,
-				storageFailures:  tc.storageFailures,
+				t:               t,
+				client:          &failingClient{Client: client, path: tc.FailPath, response: tc.FailResponse},
+				storageFailures: tc.storageFailures,
 			}
 			auth := New(sess, h.testConfig)
 			f, err := auth.AuthUser(tc.Code, tc.UserID)
 			assert.Equal(tc.Expected, f)
 			assert.Equal(tc.Error, err)
+			if tc.Record && !*record {
+				assert.Equal(0, r.Remaining())
+			}
 		})
 	}
 }
 
 func TestGetUser(t *testing.T) {
+	// the access token is redacted from the fixture, the recordings debug the token of the environment
+	accessToken := "EAAHu3c2xquQBANZBMTd8nzLxSv4jhjUfPSZB5VAnpTfplKdYKYRMdf9L3kRzemFgquPHZBZB7ZCEgSUlLqqvobDpslavSX6hWsyjo3xrHuc40OYvRdR2ZCcmDUiyZAVsHgRgbBLiSCH5M2bNpv6nR7rAqZBurkvTNo4JGdSxqyKQNNX9yfoPaWKHiRY6oZAwZBJGmrRFLZCkSMBNyVpCZAWYMdZBf"
+	if *record {
+		accessToken = os.Getenv("accessToken")
+	}
+	stored := &entities.Facebook{
+		ID: "2730207623713666",
+		Pages: []entities.Page{
+			{
+				Category:    "Accessories",
+				AccessToken: "EAAHu3c2xquQBAJ3fU0ZAjo4E3BEAn5AaBZA37KlswrUMMAZAuTkWGmwMHfkljacanwUhRQT8bL7o0FbVUBltTjraXic9rTzOZB7ZAIqa6MVshlOjOstaIfs315MZBnfvk3ub24VtzHD4ITpfMSWS2woo4q0ZBDu9zPObq96WQWBGPO3NVaaApgPNR9SybcR9MyPnFxcwieVK27OrxskVax8",
+				ID:          "101564201278325",
+				Name:        "The gossip corner",
+				Instagram: []entities.Instagram{
+					{
+						ID:   "3240241866073010",
+						Name: "thegossipocorner",
+					},
+				},
+			},
+		},
+		AdAccounts: []entities.AdAccount{
+			{
+				AccountID: "656522844415498",
+				ID:        "act_656522844415498",
+				Name:      "Trinacia",
+				Currency:  "USD",
+			},
+		},
+		AccessToken: accessToken,
+	}
 	cases := []struct {
 		Name   string
 		UserID string
@@ -501,66 +429,30 @@ func TestGetUser(t *testing.T) {
 		ExpectedFacebook *entities.Facebook
 		ExpectedValidity bool
 		Error            error
-		clientFailures   []string
-		storageFailures  []string
+		// FailPath fails the requests whose path ends with it
+		FailPath        string
+		storageFailures []string
+		// Record saves the fixture when the tests run with the -record flag
+		Record bool
 	}{
 		{
-			Name:   "Get User Valid Token",
-			UserID: "12341234",
-			ExpectedFacebook: &entities.Facebook{
-				ID: "2730207623713666",
-				Pages: []entities.Page{
-					{
-						Category:    "Accessories",
-						AccessToken: "asdfasdfsadlswrUMMAZAuTkWGmwMHfkljacanwUhRQT8bL7o0FbVUBltTjraXic9rTzOZB7ZAIqa6MVshlOjOstaIfs315MZBnfvk3ub24VtzHD4ITpfMSWS2woo4q0ZBDu9zPObq96WQWBGPO3NVaaApgPNR9SybcR9MyPnFxcwieVK27OrxskVax8",
-						ID:          "101564201278325",
-						Name:        "The gossip corner",
-						Instagram: []entities.Instagram{
-							{
-								ID:   "3240241866073010",
-								Name: "thegossipocorner",
-							},
-						},
-					},
-					{
-						Category:    "Food Delivery Service",
-						AccessToken: "EAAHu3c2xquQBAC2ZARBNcwSw4xZBApqAjsLgIeROCSgfxut1cXEtCYbRCyKVeKNdPKZBzkZCDwlUT0j50oWTFRweE48SmN5RZCp4DUZAFxcQlZCUNJSzDCtji8ldfqrfiMjWkwBzzfExddhhoeEETQ5OJniMMA8mkC85zJR4isYkmVidGL7Cwm0mFfISFEWuuRNZBbwfZANnkYaV7lqZAR4TYw",
-						ID:          "694235647641117",
-						Name:        "Ignis Cuisine",
-						Instagram: []entities.Instagram{
-							{
-								ID:   "3240241866073010",
-								Name: "thegossipocorner",
-							},
-						},
-					},
-				},
-				AdAccounts: []entities.AdAccount{
-					{
-						AccountID: "656522844415498",
-						ID:        "act_656522844415498",
-					},
-				},
-				AccessToken: "EAAHuasdfasdNZBMTd8nzLxSv4jhjUfPSZB5VAnpTfplKdYKYRMdf9L3kRzemFgquPHZBZB7ZCEgSUlLqqvobDpslavSX6hWsyjo3xrHuc40OYvRdR2ZCcmDUiyZAVsHgRgbBLiSCH5M2bNpv6nR7rAqZBurkvTNo4JGdSxqyKQNNX9yfoPaWKHiRY6oZAwZBJGmrRFLZCkSMBNyVpCZAWYMdZBf",
-			},
+			Name:             "Get User Valid Token",
+			UserID:           "12341234",
+			ExpectedFacebook: stored,
 			ExpectedValidity: true,
-			Error:            nil,
+			Record:           true,
 		},
 		{
-			Name:             "Missing User ID",
-			UserID:           "",
-			ExpectedFacebook: nil,
-			ExpectedValidity: false,
+			Name:   "Missing User ID",
+			UserID: "",
 			Error: &logger.Error{
 				Level: "Warning",
 				Err:   ErrorNilUser,
 			},
 		},
 		{
-			Name:             "Fail to Get Data from Data Base",
-			UserID:           "1234123412",
-			ExpectedFacebook: nil,
-			ExpectedValidity: false,
+			Name:   "Fail to Get Data from Data Base",
+			UserID: "1234123412",
 			Error: &logger.Error{
 				Level:   "Panic",
 				Message: "Unable to Get Facebook Data for user",
@@ -571,62 +463,20 @@ func TestGetUser(t *testing.T) {
 			},
 		},
 		{
-			Name:             "Facebook not found in Data Base",
-			UserID:           "14312341234",
-			defaultFacebook:  &entities.Facebook{},
-			ExpectedFacebook: nil,
-			ExpectedValidity: false,
-			Error:            nil,
+			Name:            "Facebook not found in Data Base",
+			UserID:          "14312341234",
+			defaultFacebook: &entities.Facebook{},
 		},
 		{
-			Name:   "Fail to Debug Token",
-			UserID: "12341234",
-			defaultFacebook: &entities.Facebook{
-				ID: "2730207623713666",
-				Pages: []entities.Page{
-					{
-						Category:    "Accessories",
-						AccessToken: "EAAHu3c2xquQBAJ3fU0ZAjo4E3BEAn5AaBZA37KlswrUMMAZAuTkWGmwMHfkljacanwUhRQT8bL7o0FbVUBltTjraXic9rTzOZB7ZAIqa6MVshlOjOstaIfs315MZBnfvk3ub24VtzHD4ITpfMSWS2woo4q0ZBDu9zPObq96WQWBGPO3NVaaApgPNR9SybcR9MyPnFxcwieVK27OrxskVasdf",
-						ID:          "101564201278325",
-						Name:        "The gossip corner",
-						Instagram: []entities.Instagram{
-							{
-								ID:   "3240241866073010",
-								Name: "thegossipocorner",
-							},
-						},
-					},
-					{
-						Category:    "Food Delivery Service",
-						AccessToken: "EAAHu3c2xquQBAC2ZARBNcwSw4xZBApqAjsLgIeROCSgfxut1cXEtCYbRCyKVeKNdPKZBzkZCDwlUT0j50oWTFRweE48SmN5RZCp4DUZAFxcQlZCUNJSzDCtji8ldfqrfiMjWkwBzzfExddhhoeEE2wer23fasdMA8mkC85zJR4isYkmVidGL7Cwm0mFfasdfasdfasdfZANnkYaV7lqZAR4TYw",
-						ID:          "694235647641117",
-						Name:        "Ignis Cuisine",
-						Instagram: []entities.Instagram{
-							{
-								ID:   "3240241866073010",
-								Name: "thegossipocorner",
-							},
-						},
-					},
-				},
-				AdAccounts: []entities.AdAccount{
-					{
-						AccountID: "656522844415498",
-						ID:        "act_656522844415498",
-					},
-				},
-				AccessToken: "EAAHasdfasdfuQBANZBMTd8nzLxSv4jhjUfPSZB5VAnpTfplKdYKYRMdf9L3kRzemFgquPHZBZB7ZCEgSUlLqqvobDpslavSX6hWsyjo3xrHuc40OYvRdR2ZCcmDUiyZAVsHgRgbBLiSCH5M2bNpv6nR7rAqZBurkvTNo4JGdSxqyKQNNX9yfoPaWKHiRY6oZAwZBJGmrRFLZCkSMBNyVpCZAWYMdZBf",
-			},
-			ExpectedFacebook: nil,
-			ExpectedValidity: false,
+			Name:            "Fail to Debug Token",
+			UserID:          "12341234",
+			defaultFacebook: stored,
 			Error: &logger.Error{
 				Level:   "Panic",
 				Err:     errFailRequest,
 				Message: "Unable to make get request during the debugging of a facebook access token",
 			},
-			clientFailures: []string{
-				"FailDebugTokenRequest",
-			},
+			FailPath: "/debug_token",
 		},
 	}
 
@@ -641,10 +491,11 @@ func TestGetUser(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
+			client, r := replayClient(t, "get_user", tc.Record)
 			h := &helper{
 				t:               t,
+				client:          &failingClient{Client: client, path: tc.FailPath},
 				expected:        tc.ExpectedFacebook,
-				clientFailures:  tc.clientFailures,
 				storageFailures: tc.storageFailures,
 			}
 			if tc.defaultFacebook != nil {
@@ -656,6 +507,29 @@ func TestGetUser(t *testing.T) {
 			assert.Equal(tc.ExpectedFacebook, f)
 			assert.Equal(tc.ExpectedValidity, valid)
 			assert.Equal(tc.Error, err)
+			if tc.Record && !*record {
+				assert.Equal(0, r.Remaining())
+			}
 		})
 	}
 }
+
+func TestAuthUserWithContext(t *testing.T) {
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client, _ := replayClient(t, "auth_user", false)
+	h := &helper{t: t, client: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	auth := New(sess, h.testConfig).WithContext(ctx)
+	f, err := auth.AuthUser("AQB2JdlAuasdfasdfasdfqwefwqeejB7p7nwtn9nInwoR2kYrr4GmCVhoksVwsAhs4rrkYYdpize8PrDMmjAQBQUDKjLLqWnh4CdJ_3F4MW9ezV9z79oOBKCHjNayZr_FbQfSrBNZKkkGdHt1", "123451432")
+	assert.Nil(f)
+	assert.True(errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,158 @@
+package campaign
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+)
+
+const (
+	defaultExplorationShare = 0.2
+	// bidStrategy of the campaign or of the ad sets when they have their own budget
+	bidStrategy = "LOWEST_COST_WITHOUT_CAP"
+)
+
+var (
+	errUnknownAdAccount   = errors.New("The ad account doesn't belong to the user")
+	errInvalidExploration = errors.New("The exploration share must be between zero and one")
+)
+
+// minimumAdSetBudgets is the minimum daily budget of an ad set billed by impressions
+// for each currency, in the minimum unit of the currency used by the graph api. The
+// ad sets of other currencies don't have a minimum unless it's set with MinimumAdSetBudget
+var minimumAdSetBudgets = map[string]float64{
+	"USD": 100,
+	"EUR": 100,
+	"GBP": 100,
+	"CAD": 100,
+	"AUD": 100,
+	"BRL": 500,
+	"MXN": 2000,
+	"ARS": 10000,
+	"CLP": 900,
+	"COP": 3000,
+	"PEN": 400,
+}
+
+// AdSetBudgets sets a daily budget for every ad set instead of a campaign budget,
+// the exploration share of the budget is split evenly between the offspring and the
+// rest is split between the elites proportionally to their fitness
+func AdSetBudgets(exploration float64) func(*facebook) {
+	return func(f *facebook) {
+		f.adSetBudgets = true
+		f.exploration = exploration
+	}
+}
+
+// MinimumAdSetBudget overrides the minimum daily budget of
+// the ad sets of ad accounts with the currency
+func MinimumAdSetBudget(currency string, amount float64) func(*facebook) {
+	return func(f *facebook) {
+		f.minimumAdSetBudgets[currency] = amount
+	}
+}
+
+// minimumAdSetBudget returns the minimum ad set budget for the currency of the ad
+// account, it's zero for a currency without a minimum and facebook checks the budgets
+func (f *facebook) minimumAdSetBudget(u *entities.Facebook, adAccount string) (float64, error) {
+	for _, a := range u.AdAccounts {
+		if a.ID != adAccount && a.AccountID != adAccount {
+			continue
+		}
+
+		return f.minimumAdSetBudgets[a.Currency], nil
+	}
+
+	return 0.0, errUnknownAdAccount
+}
+
+// checkAdSetBudgets returns the campaign budget and the minimum ad set budget of the
+// ad account currency, the budget must fund the minimum of every ad set
+func (f *facebook) checkAdSetBudgets(u *entities.Facebook, req *Request, e *entities.Evolution) (float64, float64, error) {
+	if f.exploration < 0 || f.exploration > 1 {
+		return 0.0, 0.0, errInvalidExploration
+	}
+	minimum, err := f.minimumAdSetBudget(u, req.AdAccount)
+	if err != nil {
+		return 0.0, 0.0, err
+	}
+	budget, err := parseBudget(req.Budget)
+	if err != nil {
+		return 0.0, 0.0, err
+	}
+	if budget < minimum*float64(e.PopulationSize) {
+		return 0.0, 0.0, errInsufficientBudget
+	}
+
+	return budget, minimum, nil
+}
+
+// allocateBudget splits the daily budget between the ad sets of the population, the
+// first elites chromosomes are the selected ones. Every ad set gets the minimum budget
+// and the rest is split with the exploration share for the offspring, when there is no
+// offspring the elites get the whole budget. The elites share their budget evenly when
+// their fitness can't be used, like NaN, infinite or negative values. The budgets are
+// rounded down and the remainder is assigned to the fittest elite
+func allocateBudget(population []*genetic.Chromosome, elites int, budget, exploration, minimum float64) ([]string, error) {
+	switch {
+	case exploration < 0 || exploration > 1:
+		return nil, errInvalidExploration
+	case elites < 1 || elites > len(population):
+		return nil, errInvalidEliteCount
+	case budget < minimum*float64(len(population)):
+		return nil, errInsufficientBudget
+	}
+
+	var (
+		amounts   = make([]float64, len(population))
+		remaining = budget - minimum*float64(len(population))
+		offspring = len(population) - elites
+		fitness   float64
+		fittest   int
+		even      bool
+	)
+	if offspring == 0 {
+		exploration = 0
+	}
+	for i, c := range population[:elites] {
+		if math.IsNaN(c.Fitness) || math.IsInf(c.Fitness, 0) || c.Fitness < 0 {
+			even = true
+			continue
+		}
+		fitness += c.Fitness
+		if c.Fitness > population[fittest].Fitness || math.IsNaN(population[fittest].Fitness) {
+			fittest = i
+		}
+	}
+	if fitness <= 0 || math.IsInf(fitness, 0) {
+		even = true
+	}
+	for i, c := range population {
+		amounts[i] = minimum
+		switch {
+		case i >= elites:
+			amounts[i] += remaining * exploration / float64(offspring)
+		case even:
+			amounts[i] += remaining * (1 - exploration) / float64(elites)
+		default:
+			amounts[i] += remaining * (1 - exploration) * c.Fitness / fitness
+		}
+	}
+
+	var allocated float64
+	for i := range amounts {
+		amounts[i] = math.Floor(amounts[i])
+		allocated += amounts[i]
+	}
+	amounts[fittest] += math.Floor(budget) - allocated
+
+	budgets := make([]string, len(amounts))
+	for i, a := range amounts {
+		budgets[i] = strconv.FormatFloat(a, 'f', 0, 64)
+	}
+
+	return budgets, nil
+}
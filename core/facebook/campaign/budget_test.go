@@ -0,0 +1,243 @@
+package campaign
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFitnessPopulation(fitness ...float64) []*genetic.Chromosome {
+	population := []*genetic.Chromosome{}
+	for _, f := range fitness {
+		population = append(population, &genetic.Chromosome{Fitness: f})
+	}
+
+	return population
+}
+
+func TestAllocateBudget(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Population  []*genetic.Chromosome
+		Elites      int
+		Budget      float64
+		Exploration float64
+		Expected    []string
+		Error       error
+	}{
+		{
+			Name:        "Fitness Proportional",
+			Population:  testFitnessPopulation(0.3, 0.1, 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			// 600 over the minimums, 120 for the offspring
+			// and 480 split 3 to 1 between the elites
+			Expected: []string{"460", "220", "160", "160"},
+			Error:    nil,
+		},
+		{
+			Name:        "Remainder To The Fittest",
+			Population:  testFitnessPopulation(0.1, 0.3, 0, 0),
+			Elites:      2,
+			Budget:      1001,
+			Exploration: 0.2,
+			Expected:    []string{"220", "461", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "Elites Without Fitness",
+			Population:  testFitnessPopulation(0, 0, 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			Expected:    []string{"340", "340", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "NaN Fitness",
+			Population:  testFitnessPopulation(math.NaN(), math.NaN(), 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			Expected:    []string{"340", "340", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "Fittest Without NaN",
+			Population:  testFitnessPopulation(math.NaN(), 0.5, 0, 0),
+			Elites:      2,
+			Budget:      1001,
+			Exploration: 0.2,
+			Expected:    []string{"340", "341", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "Infinite Fitness",
+			Population:  testFitnessPopulation(math.Inf(1), 0.5, 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			Expected:    []string{"340", "340", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "Negative Fitness",
+			Population:  testFitnessPopulation(-0.5, 1, 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			Expected:    []string{"340", "340", "160", "160"},
+			Error:       nil,
+		},
+		{
+			Name:        "Without Offspring",
+			Population:  testFitnessPopulation(0.5, 0.5),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 0.2,
+			Expected:    []string{"500", "500"},
+			Error:       nil,
+		},
+		{
+			Name:        "Insufficient Budget",
+			Population:  testFitnessPopulation(0.3, 0.1, 0, 0),
+			Elites:      2,
+			Budget:      399,
+			Exploration: 0.2,
+			Error:       errInsufficientBudget,
+		},
+		{
+			Name:        "Invalid Exploration",
+			Population:  testFitnessPopulation(0.3, 0.1, 0, 0),
+			Elites:      2,
+			Budget:      1000,
+			Exploration: 1.5,
+			Error:       errInvalidExploration,
+		},
+		{
+			Name:        "Invalid Elite Count",
+			Population:  testFitnessPopulation(0.3, 0.1, 0, 0),
+			Elites:      0,
+			Budget:      1000,
+			Exploration: 0.2,
+			Error:       errInvalidEliteCount,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			budgets, err := allocateBudget(tc.Population, tc.Elites, tc.Budget, tc.Exploration, 100)
+			assert.Equal(tc.Error, err)
+			assert.Equal(tc.Expected, budgets)
+		})
+	}
+}
+
+func TestAllocateBudgetZeroQuality(t *testing.T) {
+	assert := assert.New(t)
+	population := []*genetic.Chromosome{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}
+	g := genetic.New(func(c *genetic.Chromosome) (float64, error) {
+		return 0, nil
+	})
+	if !assert.Nil(g.Fitness(population)) {
+		return
+	}
+
+	budgets, err := allocateBudget(population, 2, 1000, 0.2, 100)
+	assert.Nil(err)
+	assert.Equal([]string{"340", "340", "160", "160"}, budgets)
+}
+
+func TestCheckAdSetBudgets(t *testing.T) {
+	var u = &entities.Facebook{
+		AdAccounts: []entities.AdAccount{
+			{ID: "act_1", AccountID: "1", Currency: "USD"},
+			{ID: "act_2", AccountID: "2", Currency: "XTS"},
+		},
+	}
+	cases := []struct {
+		Name      string
+		AdAccount string
+		Budget    string
+		Config    []func(*facebook)
+		Minimum   float64
+		Error     error
+	}{
+		{
+			Name:      "Currency Minimum",
+			AdAccount: "act_1",
+			Budget:    "3000",
+			Minimum:   100,
+			Error:     nil,
+		},
+		{
+			Name:      "Account ID Without Prefix",
+			AdAccount: "1",
+			Budget:    "3000",
+			Minimum:   100,
+			Error:     nil,
+		},
+		{
+			Name:      "Overridden Minimum",
+			AdAccount: "act_2",
+			Budget:    "3000",
+			Config:    []func(*facebook){MinimumAdSetBudget("XTS", 50)},
+			Minimum:   50,
+			Error:     nil,
+		},
+		{
+			// facebook checks the budgets of a currency without a minimum
+			Name:      "Unknown Currency",
+			AdAccount: "act_2",
+			Budget:    "3000",
+			Minimum:   0,
+			Error:     nil,
+		},
+		{
+			Name:      "Unknown Ad Account",
+			AdAccount: "act_3",
+			Budget:    "3000",
+			Error:     errUnknownAdAccount,
+		},
+		{
+			Name:      "Insufficient Budget",
+			AdAccount: "act_1",
+			Budget:    "3000",
+			Config:    []func(*facebook){MinimumAdSetBudget("USD", 200)},
+			Error:     errInsufficientBudget,
+		},
+		{
+			Name:      "Invalid Exploration",
+			AdAccount: "act_1",
+			Budget:    "3000",
+			Config:    []func(*facebook){AdSetBudgets(-0.1)},
+			Error:     errInvalidExploration,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			f := &facebook{
+				exploration:         defaultExplorationShare,
+				minimumAdSetBudgets: map[string]float64{"USD": 100},
+			}
+			for _, fn := range tc.Config {
+				fn(f)
+			}
+			e := &entities.Evolution{PopulationSize: defaultPopulationSize}
+
+			budget, minimum, err := f.checkAdSetBudgets(u, &Request{AdAccount: tc.AdAccount, Budget: tc.Budget}, e)
+			assert.Equal(tc.Error, err)
+			if tc.Error == nil {
+				assert.Equal(3000.0, budget)
+				assert.Equal(tc.Minimum, minimum)
+			}
+		})
+	}
+}
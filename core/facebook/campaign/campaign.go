@@ -1,8 +1,11 @@
 package campaign
 
 import (
+	"context"
+
 	"bitbucket.org/backend/core/entities"
 	"bitbucket.org/backend/core/facebook/auth"
+	"bitbucket.org/backend/core/facebook/internal"
 	"bitbucket.org/backend/core/genetic"
 	"bitbucket.org/backend/core/server"
 	"bitbucket.org/backend/core/storage/campaigns"
@@ -20,6 +23,18 @@ type Request struct {
 	// Population and optimization data
 	Segment      string  `json:"segment"`
 	MutationRate float64 `json:"mutation_rate"`
+	// Selection is the name of the selection strategy used to optimize
+	// the segment, the campaign default is used when it's empty
+	Selection string `json:"selection,omitempty"`
+	// Objectives are the insight metrics of a multi objective optimization,
+	// they can't be requested with a selection strategy
+	Objectives []string `json:"objectives,omitempty"`
+	// Quality is the name of the function used to compute the quality of the
+	// ad sets, the default function of the campaign objective is used when it's empty
+	Quality string `json:"quality,omitempty"`
+	// ConversionEvent is the action type counted as a conversion by the quality
+	// and the objectives, the purchases are counted when it's empty
+	ConversionEvent string `json:"conversion_event,omitempty"`
 	// Ad set data
 	PixelID   string      `json:"pixel_id"`
 	StartTime string      `json:"start"`
@@ -97,12 +112,31 @@ type targetingByType struct {
 // Campaign methods for facebook
 type Campaign interface {
 	Create(userID string, req *Request) (*entities.Campaign, error)
+	Plan(userID string, req *Request) (*Plan, error)
+	Pause(userID, campaignID string) (*entities.Campaign, error)
+	Resume(userID, campaignID string) (*entities.Campaign, error)
+	Archive(userID, campaignID string) (*entities.Campaign, error)
+	Delete(userID, campaignID string) (*entities.Campaign, error)
+	UpdateBudget(userID, campaignID, budget string) (*entities.Campaign, error)
+	UpdateSchedule(userID, campaignID, startTime, endTime string) (*entities.Campaign, error)
+	RateLimit(adAccount string) *RateLimit
+	// WithContext returns the campaign interface with its graph
+	// api and storage requests bound to the context
+	WithContext(ctx context.Context) Campaign
 }
 
 type facebook struct {
 	store  campaigns.Storage
 	client server.Client
 	auth   auth.Auth
+	// throttle keeps the usage of the rate limits
+	// from the responses of the campaign calls
+	throttle *internal.Throttle
+	// ctx is the context of the requests, root is the campaign interface a copy
+	// bound to a context was made from, it rolls back the failed creations so
+	// the rollback isn't cancelled with the context
+	ctx  context.Context
+	root *facebook
 	// campaign objects configuration
 	status string
 	// billing event for adsets
@@ -110,23 +144,164 @@ type facebook struct {
 	// optimization algorithm
 	quality   *q
 	selection genetic.Genetic
+	// defaultSelection is true when the selection is
+	// created from the optimization configuration
+	defaultSelection bool
+	// configuration of the genetic algorithm, the selection
+	// interface is created from it when it isn't provided
+	optimization []genetic.Option
+	// probability of combining two selected chromosomes
+	// instead of only mutating a copy of one of them
+	crossoverRate float64
+	// random source shared by the campaign and
+	// the genetic algorithm
+	random genetic.Source
+	// objectiveQuality maps a campaign objective
+	// to the name of its quality function
+	objectiveQuality map[string]string
+	// adSetBudgets splits the budget between the ad sets instead
+	// of setting a campaign budget, exploration is the share of
+	// the budget for the offspring
+	adSetBudgets        bool
+	exploration         float64
+	minimumAdSetBudgets map[string]float64
 }
 
 // New campaign facebook interface
 func New(sess *session.Session, config ...func(*facebook)) Campaign {
+	throttle := internal.NewClient()
 	f := &facebook{
-		auth:         auth.New(sess),
-		store:        campaigns.New(sess),
-		client:       server.New(),
-		status:       "ACTIVE",
-		billingEvent: "IMPRESSIONS",
-		quality:      quality(),
+		auth:          auth.New(sess),
+		store:         campaigns.New(sess),
+		client:        throttle,
+		throttle:      throttle,
+		ctx:           context.Background(),
+		status:        "ACTIVE",
+		billingEvent:  "IMPRESSIONS",
+		quality:       quality(func(qu *q) { qu.client = throttle }),
+		crossoverRate: defaultCrossoverRate,
+		random:        genetic.NewCryptoSource(),
+		exploration:   defaultExplorationShare,
+	}
+	f.objectiveQuality = make(map[string]string)
+	for objective, name := range objectiveQuality {
+		f.objectiveQuality[objective] = name
+	}
+	f.minimumAdSetBudgets = make(map[string]float64)
+	for currency, amount := range minimumAdSetBudgets {
+		f.minimumAdSetBudgets[currency] = amount
 	}
-	f.selection = genetic.New(f.quality.compute)
 
 	for _, fn := range config {
 		fn(f)
 	}
 
+	if f.selection == nil {
+		f.defaultSelection = true
+		f.selection = f.newSelection()
+	}
+
 	return f
 }
+
+// newSelection returns the genetic algorithm of the optimization configuration
+func (f *facebook) newSelection() genetic.Genetic {
+	opts := append([]genetic.Option{genetic.RandomSource(f.random)}, f.optimization...)
+	return genetic.New(f.quality.compute, opts...)
+}
+
+// WithContext returns a copy of the campaign interface whose graph
+// api and storage requests are cancelled with the context
+func (f *facebook) WithContext(ctx context.Context) Campaign {
+	fc := *f
+	fc.ctx = ctx
+	fc.root = f.detached()
+	fc.client = server.WithContext(ctx, f.client)
+	fc.store = f.store.WithContext(ctx)
+	fc.auth = f.auth.WithContext(ctx)
+	fc.quality = f.quality.withContext(ctx)
+	if f.defaultSelection {
+		fc.selection = fc.newSelection()
+	}
+
+	return &fc
+}
+
+// detached returns the campaign interface that isn't bound to a context
+func (f *facebook) detached() *facebook {
+	if f.root != nil {
+		return f.root
+	}
+
+	return f
+}
+
+// Client configures the http client of the graph api calls
+func Client(config ...server.Option) func(*facebook) {
+	return func(f *facebook) {
+		f.throttle = internal.NewClient(config...)
+		f.client = f.throttle
+		f.quality.client = f.throttle
+	}
+}
+
+// Optimization configures the genetic algorithm used to
+// compute the population of new campaigns
+func Optimization(config ...genetic.Option) func(*facebook) {
+	return func(f *facebook) {
+		f.optimization = append(f.optimization, config...)
+	}
+}
+
+// optimizer returns the genetic algorithm used to optimize a segment with the
+// quality of the request and the requested selection strategy or objectives,
+// a provided selection interface is used when neither are requested. The
+// objectives use the crowded selection so they can't have a selection strategy
+func (f *facebook) optimizer(qu *q, selection string, objectives []string) (genetic.Genetic, error) {
+	if selection == "" && len(objectives) == 0 && !f.defaultSelection {
+		return f.selection, nil
+	}
+	if selection != "" && len(objectives) != 0 {
+		return nil, genetic.ErrorObjectivesSelection
+	}
+	opts := append([]genetic.Option{genetic.RandomSource(f.random)}, f.optimization...)
+	if selection != "" {
+		strategy, err := genetic.SelectionStrategy(selection)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, strategy)
+	}
+	if len(objectives) != 0 {
+		if err := checkObjectives(objectives); err != nil {
+			return nil, err
+		}
+		opts = append(opts, genetic.MultiObjective(qu.objectives(objectives)))
+	}
+
+	return genetic.New(qu.compute, opts...), nil
+}
+
+// ObjectiveQuality overrides the quality function used
+// by default for campaigns with the objective
+func ObjectiveQuality(objective, name string) func(*facebook) {
+	return func(f *facebook) {
+		f.objectiveQuality[objective] = name
+	}
+}
+
+// RandomSource sets the source used by the optimization, a seeded
+// source makes the computed population reproducible
+func RandomSource(s genetic.Source) func(*facebook) {
+	return func(f *facebook) {
+		f.random = s
+	}
+}
+
+// CrossoverRate sets the probability of creating offspring
+// by crossing over two selected chromosomes
+func CrossoverRate(rate float64) func(*facebook) {
+	return func(f *facebook) {
+		f.crossoverRate = rate
+	}
+}
@@ -1,6 +1,7 @@
 package campaign
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/url"
@@ -16,6 +17,8 @@ import (
 // given an ad set
 type Constructor interface {
 	GenerateChromosome(adSetID, accessToken string) (*genetic.Chromosome, error)
+	// WithContext returns the constructor with its requests bound to the context
+	WithContext(ctx context.Context) Constructor
 }
 
 type constructor struct {
@@ -26,7 +29,7 @@ type constructor struct {
 // NewConstructor creates a new constructor interface
 func NewConstructor(config ...func(*constructor)) Constructor {
 	c := &constructor{
-		client: server.New(),
+		client: internal.NewClient(),
 	}
 
 	for _, fn := range config {
@@ -36,6 +39,14 @@ func NewConstructor(config ...func(*constructor)) Constructor {
 	return c
 }
 
+// WithContext returns a copy of the constructor whose requests are cancelled with the context
+func (constructor *constructor) WithContext(ctx context.Context) Constructor {
+	c := *constructor
+	c.client = server.WithContext(ctx, constructor.client)
+
+	return &c
+}
+
 // GenerateChromosome creates a chromosome from an adset ID and an access token
 func (constructor *constructor) GenerateChromosome(adSetID, accessToken string) (*genetic.Chromosome, error) {
 	c, idx, err := constructor.loadDefaultChromosome()
@@ -120,11 +131,7 @@ func (constructor *constructor) getAdSetTargeting(adSetID, accessToken string) (
 		}
 	}
 	if result.Error != nil {
-		return nil, &logger.Error{
-			Level:   "Error",
-			Message: "Response from request for an adset's targeting contains an error.",
-			Err:     result.Error,
-		}
+		return nil, internal.ResponseError(result.Error, "Error", "Response from request for an adset's targeting contains an error.", nil)
 	}
 
 	return result.Targeting, nil
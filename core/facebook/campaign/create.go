@@ -13,15 +13,11 @@ import (
 	"bitbucket.org/backend/core/facebook/internal"
 	"bitbucket.org/backend/core/genetic"
 	"bitbucket.org/backend/core/logger"
-)
-
-const (
-	populationSize = 30
-	selectionSize  = 5
+	"bitbucket.org/backend/core/storage/schema"
 )
 
 var (
-	errInvalidToken = errors.New("Facebook access token has expired or is invalid")
+	errInvalidToken = internal.ErrorInvalidToken
 	// configuration parameters errors
 	errorMissingSegment      = errors.New("Request missing segment name")
 	errorInvalidMutationRage = errors.New("Request invalid mutation rate")
@@ -36,6 +32,7 @@ var (
 	// adsets parameters errors
 	errorMissingStartTime = errors.New("Request missing start time")
 	errorMissingEndTime   = errors.New("Request missing end time")
+	errorInvalidEndTime   = errors.New("Request end time must be an ISO-8601 time")
 	// TODO add campaign without endtime
 	errorMissingLocation = errors.New("Request missing end time")
 	// TODO add gender and age property to segment
@@ -47,13 +44,36 @@ var (
 	errorMissingCallToAction  = errors.New("Request missing call to action")
 	errorMissingCreativeName  = errors.New("Request missing creative name")
 	errorMissingCreativeMedia = errors.New("Request missing ads media")
+
+	// errChromosomes the adset or ad of some chromosomes wasn't created
+	errChromosomes = errors.New("Unable to create the adsets of the chromosomes")
+)
+
+// the ad of a chromosome references its adset in the batch request
+const (
+	adSetOperation = "adset-%d"
+	adSetReference = "{result=adset-%d:$.id}"
 )
 
+// createdObject is the result of a batch operation that creates an object
+type createdObject struct {
+	ID string `json:"id"`
+}
+
+// chromosomeError is the error of the creation of the adset or ad of a chromosome,
+// Chromosome is the index in the population and AdSet the id of the adset when
+// only the ad failed
+type chromosomeError struct {
+	Chromosome int    `json:"chromosome"`
+	AdSet      string `json:"adset,omitempty"`
+	Err        string `json:"error"`
+}
+
 type newCampaign struct {
 	Name              string   `json:"name"`
 	Objective         string   `json:"objective"`
-	DailyBudget       string   `json:"daily_budget"`
-	BidStrategy       string   `json:"bid_strategy"`
+	DailyBudget       string   `json:"daily_budget,omitempty"`
+	BidStrategy       string   `json:"bid_strategy,omitempty"`
 	Status            string   `json:"status"`
 	SpecialAdCategory []string `json:"special_ad_categories"`
 	Token             string   `json:"access_token"`
@@ -65,6 +85,8 @@ type newAdSet struct {
 	CampaignID     string         `json:"campaign_id"`
 	PromotedObject promotedObject `json:"promoted_object,omitempty"`
 	Targeting      *targeting     `json:"targeting"`
+	DailyBudget    string         `json:"daily_budget,omitempty"`
+	BidStrategy    string         `json:"bid_strategy,omitempty"`
 	Status         string         `json:"status"`
 	StartTime      string         `json:"start_time"`
 	EndTime        string         `json:"end_time"`
@@ -120,100 +142,91 @@ type newAd struct {
 	AccessToken string   `json:"access_token"`
 }
 
+// creation has the validated data shared by the creation of a campaign and its plan
+type creation struct {
+	user      *entities.Facebook
+	evolution *entities.Evolution
+	// quality computes the quality of the request ad sets with the user access token
+	quality *q
+	// endTime is the request end time normalized as it's stored
+	endTime string
+	// campaignBudget is empty when the ad sets have their own budget
+	campaignBudget string
+	budget         float64
+	minimum        float64
+}
+
 func (f *facebook) Create(userID string, req *Request) (*entities.Campaign, error) {
-	var (
-		c *entities.Campaign
-		u *entities.Facebook
-	)
+	var c *entities.Campaign
 
-	if err := checkRequest(req); err != nil {
-		return nil, &logger.Error{
-			Level:   "Error",
-			Message: "Invalid Request",
-			Err:     err,
-		}
-	}
-	u, valid, err := f.auth.GetUser(userID)
+	cr, err := f.prepare(userID, req)
 	if err != nil {
 		return nil, err
 	}
-	if !valid {
+	u := cr.user
+	// the optimizer run is postponed while the ad account calls are
+	// throttled instead of failing in the middle of the creation
+	if d := f.RateLimit(req.AdAccount).Delay; d > 0 {
 		return nil, &logger.Error{
-			Level:   "Warning",
-			Message: "Unable to create campaign because the user access token is not valid",
-			Err:     errInvalidToken,
+			Level:         "Warning",
+			Message:       fmt.Sprintf("Campaign creation postponed %s because the ad account calls are throttled", d),
+			Err:           internal.ErrorThrottled,
+			ClientMessage: internal.ClientMessage(internal.ErrorThrottled),
 		}
 	}
 
-	// initialice quality structure to enable
-	// selection algorithm to query facebook with
-	// a valid access token
-	f.quality.accessToken = u.AccessToken
-
-	campaignID, err := f.createCampaign(req.AdAccount, req.Name, u.AccessToken, req.Objective, req.Budget, req.SpecialAdCategory)
+	previousPopulation, newPopulation, budgets, err := f.populate(userID, req, cr)
 	if err != nil {
 		return nil, err
 	}
 
-	// get current segment population
-	initialPopulation, err := f.store.GetSegment(userID, req.Segment)
+	campaignID, err := f.createCampaign(req.AdAccount, req.Name, u.AccessToken, req.Objective, cr.campaignBudget, req.SpecialAdCategory)
 	if err != nil {
-		return nil, &logger.Error{
-			Level:   "panic",
-			Message: "Unable to get segment population from data base",
-			Err:     err,
-		}
+		return nil, err
 	}
+	// the objects created from here on are removed when a later step fails
+	// so a failed creation doesn't leave an active campaign spending money
+	s := f.newSaga(u.AccessToken)
+	s.created(campaignObject, campaignID)
 
-	// optimize current population using genetic algorithm
-	newPopulation, err := f.newPopulation(initialPopulation, req.MutationRate)
+	creativeID, err := f.createCreative(req, u.AccessToken)
 	if err != nil {
-		return nil, err
+		return nil, s.rollback(err)
 	}
+	s.created(creativeObject, creativeID)
 
-	// create adsets and update IDs to the segment population
-	// to point to the new adsets
+	// create adsets and their ads, and update IDs to the segment
+	// population to point to the new adsets
 	//
 	// IDs are used by the quality function to retrieve performance
 	// data from facebook
-	var promotedObjectID string
-	switch req.Objective {
-	case "PAGE_LIKES":
-		promotedObjectID = req.Page.ID
-	case "CONVERSIONS":
-		promotedObjectID = req.PixelID
-	}
-	adSets, err := f.createAdSets(req.AdAccount, campaignID, req.Objective, promotedObjectID, req.StartTime, req.EndTime,
-		u.AccessToken, req.Location, req.Gender, req.AgeMin, req.AgeMax, newPopulation)
+	adSets, ads, err := f.createAdSets(req.AdAccount, campaignID, req.Objective, promotedObjectID(req), req.StartTime, cr.endTime,
+		u.AccessToken, req.Location, req.Gender, req.AgeMin, req.AgeMax, newPopulation, budgets, creativeID)
+	s.created(adSetObject, adSets...)
+	s.created(adObject, ads...)
 	if err != nil {
-		return nil, err
+		return nil, s.rollback(err)
 	}
 
-	// update segment current population
+	// update segment current population once every ad was created
 	err = f.store.SetSegment(userID, req.Segment, newPopulation)
 	if err != nil {
-		return nil, &logger.Error{
+		return nil, s.rollback(&logger.Error{
 			Level:   "panic",
 			Message: "Unable to update segment population in the data base",
 			Err:     err,
-		}
-	}
-
-	creativeID, err := f.createCreative(req, u.AccessToken)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = f.createAds(req.AdAccount, adSets, creativeID, u.AccessToken)
-	if err != nil {
-		return nil, err
+		})
 	}
+	s.compensate(fmt.Sprintf("segment %s", req.Segment), func() error {
+		return s.f.store.SetSegment(userID, req.Segment, previousPopulation)
+	})
 
 	c = &entities.Campaign{
 		ID:        campaignID,
+		Status:    f.status,
 		Budget:    req.Budget,
 		StartTime: req.StartTime,
-		EndTime:   req.EndTime,
+		EndTime:   cr.endTime,
 		Targeting: newPopulation,
 		Media: []entities.Media{
 			{
@@ -228,23 +241,164 @@ func (f *facebook) Create(userID string, req *Request) (*entities.Campaign, erro
 
 	err = f.store.StoreCampaign(userID, "facebook", req.AdAccount, req.Segment, c)
 	if err != nil {
-		return nil, &logger.Error{
+		return nil, s.rollback(&logger.Error{
 			Level:   "panic",
 			Message: "Unable to store user campaign.",
 			Err:     err,
 			Context: c,
-		}
+		})
 	}
 
 	return c, nil
 }
 
+// prepare validates the request, the user and the segment evolution settings
+func (f *facebook) prepare(userID string, req *Request) (*creation, error) {
+	if err := checkRequest(req); err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+		}
+	}
+	// the storage normalizes the end time too, an end time it can't
+	// store fails before any object is created on facebook
+	endTime, err := schema.NormalizeTime(req.EndTime)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     errorInvalidEndTime,
+		}
+	}
+	u, valid, err := f.auth.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, &logger.Error{
+			Level:         "Warning",
+			Message:       "Unable to create campaign because the user access token is not valid",
+			Err:           errInvalidToken,
+			ClientMessage: internal.ClientMessage(errInvalidToken),
+		}
+	}
+
+	// the quality of the request is computed with the user access token, it's not
+	// shared with other requests so they can be created concurrently
+	function, err := qualityByName(req.Quality, req.Objective, f.objectiveQuality)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+		}
+	}
+
+	// validate the segment evolution settings before creating
+	// the campaign so every ad set can be funded
+	evolution, err := f.evolution(userID, req.Segment)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "panic",
+			Message: "Unable to get segment evolution settings from data base",
+			Err:     err,
+		}
+	}
+	// with ad set budgets every ad set gets at least the minimum budget of the ad account currency
+	budget, minimum, err := f.checkEvolution(u, evolution, req)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+			Context: evolution,
+		}
+	}
+
+	// ad sets with their own budget replace the campaign budget
+	cr := &creation{
+		user:           u,
+		evolution:      evolution,
+		quality:        f.quality.request(u.AccessToken, req.ConversionEvent, function),
+		endTime:        endTime,
+		campaignBudget: req.Budget,
+	}
+	if f.adSetBudgets {
+		cr.budget, cr.minimum = budget, minimum
+		cr.campaignBudget = ""
+	}
+
+	return cr, nil
+}
+
+// populate returns a copy of the segment population, the new population optimized
+// from it and the budgets of the ad sets, budgets is nil without ad set budgets
+func (f *facebook) populate(userID string, req *Request, cr *creation) (previous, population []*genetic.Chromosome, budgets []string, err error) {
+	// get current segment population
+	initialPopulation, err := f.store.GetSegment(userID, req.Segment)
+	if err != nil {
+		return nil, nil, nil, &logger.Error{
+			Level:   "panic",
+			Message: "Unable to get segment population from data base",
+			Err:     err,
+		}
+	}
+	// the elites of the new population are chromosomes of the initial population
+	// and their IDs are replaced by the new adsets, the copy restores the segment
+	previous = make([]*genetic.Chromosome, len(initialPopulation))
+	for i, c := range initialPopulation {
+		previous[i] = c.Clone()
+	}
+
+	// optimize current population using genetic algorithm
+	optimizer, err := f.optimizer(cr.quality, req.Selection, req.Objectives)
+	if err != nil {
+		return nil, nil, nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+		}
+	}
+	population, err = f.newPopulation(optimizer, initialPopulation, req.MutationRate, cr.evolution)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// split the budget between the elites and the offspring
+	if f.adSetBudgets {
+		budgets, err = allocateBudget(population, cr.evolution.EliteCount, cr.budget, f.exploration, cr.minimum)
+		if err != nil {
+			return nil, nil, nil, &logger.Error{
+				Level:   "Error",
+				Message: "Unable to allocate the budget of the adsets.",
+				Err:     err,
+			}
+		}
+	}
+
+	return previous, population, budgets, nil
+}
+
+// promotedObjectID returns the id of the object promoted by the ad sets of the campaign objective
+func promotedObjectID(req *Request) string {
+	switch req.Objective {
+	case "PAGE_LIKES":
+		return req.Page.ID
+	case "CONVERSIONS":
+		return req.PixelID
+	}
+
+	return ""
+}
+
 func checkRequest(req *Request) error {
 	switch {
 	// configuration parameters
 	case req.Segment == "":
 		return errorMissingSegment
-	case req.MutationRate == 0.0 || req.MutationRate > 0.20:
+	// the mutation rate bounds are checked against the segment evolution settings
+	case req.MutationRate <= 0.0:
 		return errorInvalidMutationRage
 	case req.AdAccount == "":
 		return errorMissingAdAccount
@@ -252,7 +406,7 @@ func checkRequest(req *Request) error {
 	// campaign parameters
 	case req.Name == "":
 		return errorMissingCampaignName
-	case req.Budget < "3000" || req.Budget == "":
+	case !validBudget(req.Budget):
 		return errorInvalidBudget
 	case req.SpecialAdCategory == nil:
 		return errorMissingSpecialAdCategory
@@ -287,21 +441,32 @@ func checkRequest(req *Request) error {
 	}
 }
 
+func (f *facebook) campaignPayload(name, accessToken, objective, budget string, specialAdCategory []string) newCampaign {
+	newCampaign := newCampaign{
+		Name:              name,
+		Objective:         objective,
+		DailyBudget:       budget,
+		BidStrategy:       bidStrategy,
+		Status:            f.status,
+		SpecialAdCategory: specialAdCategory,
+		Token:             accessToken,
+	}
+	// the bid strategy belongs to the ad sets
+	// when the campaign doesn't have a budget
+	if budget == "" {
+		newCampaign.BidStrategy = ""
+	}
+
+	return newCampaign
+}
+
 func (f *facebook) createCampaign(adAccount, name, accessToken, objective, budget string, specialAdCategory []string) (string, error) {
 	var (
 		result = struct {
 			ID    string                  `json:"id"`
 			Error *internal.FacebookError `json:"error"`
 		}{}
-		newCampaign = newCampaign{
-			Name:              name,
-			Objective:         objective,
-			DailyBudget:       budget,
-			BidStrategy:       "LOWEST_COST_WITHOUT_CAP",
-			Status:            f.status,
-			SpecialAdCategory: specialAdCategory,
-			Token:             accessToken,
-		}
+		newCampaign = f.campaignPayload(name, accessToken, objective, budget, specialAdCategory)
 	)
 	u := internal.SetURL(fmt.Sprintf("%s/campaigns", adAccount), nil)
 	b, err := json.Marshal(newCampaign)
@@ -338,85 +503,156 @@ func (f *facebook) createCampaign(adAccount, name, accessToken, objective, budge
 		}
 	}
 	if result.Error != nil {
-		return "", &logger.Error{
-			Level:   "Error",
-			Message: "Response from the creation of a campaign contained an error.",
-			Context: []interface{}{newCampaign, u},
-			Err:     result.Error,
-		}
+		return "", internal.ResponseError(result.Error, "Error", "Response from the creation of a campaign contained an error.", []interface{}{newCampaign, u})
 	}
 
 	return result.ID, nil
 }
 
-func (f *facebook) newPopulation(initialPopulation []*genetic.Chromosome, mutationRate float64) ([]*genetic.Chromosome, error) {
+func (f *facebook) newPopulation(g genetic.Genetic, initialPopulation []*genetic.Chromosome, mutationRate float64, e *entities.Evolution) ([]*genetic.Chromosome, error) {
 	// compute initial population fitness
-	err := f.selection.Fitness(initialPopulation)
+	err := g.Fitness(initialPopulation)
 	if err != nil {
 		return nil, err
 	}
 
 	// compute selected population from initial population
-	selected, err := f.selection.Selection(initialPopulation, selectionSize)
+	selected, err := g.Selection(initialPopulation, e.EliteCount)
 	if err != nil {
 		return nil, err
 	}
 
-	var population = []*genetic.Chromosome{}
-	population = append(population, selected...)
-	idx := 0
-	for len(population) < populationSize {
-		if idx == selectionSize {
-			idx = 0
-		}
-		c := *selected[idx]
-		f.selection.Mutate(&c, mutationRate)
-		population = append(population, &c)
-		idx++
-	}
-
-	return population, nil
+	// fill the population with the offspring of the selected chromosomes
+	return genetic.Reproduce(g, selected, e.PopulationSize, mutationRate, e.CrossoverRate, f.random)
 }
 
-func (f *facebook) createAdSets(adAccount, campaignID, campaignObjective, promotedObject, startTime, endTime, accessToken string, location geolocation, gender [2]int, ageMin, ageMax int, population []*genetic.Chromosome) ([]string, error) {
-	var adSets = make([]string, len(population))
+// createAdSets creates an adset and its ad for every chromosome of the population in
+// batch requests and sets the chromosome IDs to their adsets. The adsets and ads
+// created are returned with an error of the chromosomes that failed
+func (f *facebook) createAdSets(adAccount, campaignID, campaignObjective, promotedObject, startTime, endTime, accessToken string, location geolocation, gender [2]int, ageMin, ageMax int, population []*genetic.Chromosome, budgets []string, creativeID string) (adSets, ads []string, err error) {
+	// the ad of a chromosome follows its adset so both are
+	// in the same batch request and the ad can reference it
+	var operations = make([]internal.BatchOperation, 0, 2*len(population))
 	for i, c := range population {
-		var t = &targeting{
-			GeoLocation: location,
-			Gender:      gender,
-			AgeMin:      ageMin,
-			AgeMax:      ageMax,
+		var budget string
+		if budgets != nil {
+			budget = budgets[i]
 		}
-		genotype := f.selection.Genesis(c)
-		var wg sync.WaitGroup
-		wg.Add(4)
-		go func(wg *sync.WaitGroup) {
-			t.Behaviors = setTargeting(genotype["behaviors"])
-			wg.Done()
-		}(&wg)
-		go func(wg *sync.WaitGroup) {
-			t.LifeEvents = setTargeting(genotype["interests"])
-			wg.Done()
-		}(&wg)
-		go func(wg *sync.WaitGroup) {
-			t.FamilyStatuses = setTargeting(genotype["family_statuses"])
-			wg.Done()
-		}(&wg)
-		go func(wg *sync.WaitGroup) {
-			t.Industries = setTargeting(genotype["industries"])
-			wg.Done()
-		}(&wg)
-		wg.Wait()
-
-		adSetID, err := f.createAdSet(adAccount, campaignID, campaignObjective, promotedObject, startTime, endTime, accessToken, t)
+		t := f.adSetTargeting(c, location, gender, ageMin, ageMax)
+		newAdSet, err := f.adSetPayload(campaignID, campaignObjective, promotedObject, startTime, endTime, accessToken, budget, t)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		adSets[i] = adSetID
-		population[i].ID = adSetID
+		newAd, err := f.adPayload(fmt.Sprintf(adSetReference, i), creativeID, accessToken)
+		if err != nil {
+			return nil, nil, err
+		}
+		adSetBody, err := internal.BatchBody(newAdSet)
+		if err != nil {
+			return nil, nil, &logger.Error{
+				Level:   "Panic",
+				Message: "Unable to encode new adset data.",
+				Err:     err,
+			}
+		}
+		adBody, err := internal.BatchBody(newAd)
+		if err != nil {
+			return nil, nil, &logger.Error{
+				Level:   "Panic",
+				Message: "Unable to encode new ad object.",
+				Err:     err,
+			}
+		}
+		operations = append(operations,
+			internal.BatchOperation{
+				Method:      "POST",
+				RelativeURL: fmt.Sprintf("%s/adsets", adAccount),
+				Body:        adSetBody,
+				Name:        fmt.Sprintf(adSetOperation, i),
+			},
+			internal.BatchOperation{
+				Method:      "POST",
+				RelativeURL: fmt.Sprintf("%s/ads", adAccount),
+				Body:        adBody,
+			},
+		)
 	}
 
-	return adSets, nil
+	results, err := internal.Batch(f.ctx, f.client, accessToken, adAccount, operations)
+	// the results of a failed batch request are the ones of the previous requests
+	var failed = []chromosomeError{}
+	for i := 0; 2*i < len(results); i++ {
+		var adSet, ad createdObject
+		if err := results[2*i].Decode(&adSet); err != nil {
+			failed = append(failed, chromosomeError{Chromosome: i, Err: err.Error()})
+			continue
+		}
+		adSets = append(adSets, adSet.ID)
+		population[i].ID = adSet.ID
+		if err := results[2*i+1].Decode(&ad); err != nil {
+			failed = append(failed, chromosomeError{Chromosome: i, AdSet: adSet.ID, Err: err.Error()})
+			continue
+		}
+		ads = append(ads, ad.ID)
+	}
+	if err != nil && f.ctx.Err() != nil {
+		return adSets, ads, &logger.Error{
+			Level:   "Warning",
+			Message: "The creation of the adsets and ads was cancelled.",
+			Err:     err,
+			Context: failed,
+		}
+	}
+	if err != nil {
+		return adSets, ads, &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to perform batch request to create adsets and ads.",
+			Err:     err,
+			Context: failed,
+		}
+	}
+	if len(failed) != 0 {
+		return adSets, ads, &logger.Error{
+			Level:   "Error",
+			Message: "Response to create the adsets and ads contained errors.",
+			Err:     errChromosomes,
+			Context: failed,
+		}
+	}
+
+	return adSets, ads, nil
+}
+
+// adSetTargeting returns the targeting of the chromosome genes
+func (f *facebook) adSetTargeting(c *genetic.Chromosome, location geolocation, gender [2]int, ageMin, ageMax int) *targeting {
+	var t = &targeting{
+		GeoLocation: location,
+		Gender:      gender,
+		AgeMin:      ageMin,
+		AgeMax:      ageMax,
+	}
+	genotype := f.selection.Genesis(c)
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func(wg *sync.WaitGroup) {
+		t.Behaviors = setTargeting(genotype["behaviors"])
+		wg.Done()
+	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		t.LifeEvents = setTargeting(genotype["interests"])
+		wg.Done()
+	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		t.FamilyStatuses = setTargeting(genotype["family_statuses"])
+		wg.Done()
+	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		t.Industries = setTargeting(genotype["industries"])
+		wg.Done()
+	}(&wg)
+	wg.Wait()
+
+	return t
 }
 
 func setTargeting(genes []*genetic.Gene) []targetingByType {
@@ -431,22 +667,20 @@ func setTargeting(genes []*genetic.Gene) []targetingByType {
 	return t
 }
 
-func (f *facebook) createAdSet(adAccount, campaignID, campaignObjective, promotedObjectID, startTime, endTime, accessToken string, t *targeting) (string, error) {
-	var (
-		result = struct {
-			ID    string                  `json:"id"`
-			Error *internal.FacebookError `json:"error"`
-		}{}
-		newAdSet = newAdSet{
-			BillingEvent: f.billingEvent,
-			CampaignID:   campaignID,
-			Targeting:    t,
-			Status:       f.status,
-			StartTime:    startTime,
-			EndTime:      endTime,
-			AccessToken:  accessToken,
-		}
-	)
+func (f *facebook) adSetPayload(campaignID, campaignObjective, promotedObjectID, startTime, endTime, accessToken, budget string, t *targeting) (newAdSet, error) {
+	newAdSet := newAdSet{
+		BillingEvent: f.billingEvent,
+		CampaignID:   campaignID,
+		Targeting:    t,
+		DailyBudget:  budget,
+		Status:       f.status,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		AccessToken:  accessToken,
+	}
+	if budget != "" {
+		newAdSet.BidStrategy = bidStrategy
+	}
 	switch campaignObjective {
 	case "PAGE_LIKES":
 		newAdSet.PromotedObject = promotedObject{
@@ -460,63 +694,15 @@ func (f *facebook) createAdSet(adAccount, campaignID, campaignObjective, promote
 
 	name, err := randomName()
 	if err != nil {
-		return "", err
+		return newAdSet, err
 	}
 	newAdSet.Name = name
-	b, err := json.Marshal(newAdSet)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to marshal new adset data.",
-			Err:     err,
-		}
-	}
-	u := internal.SetURL(fmt.Sprintf("%s/adsets", adAccount), nil)
-	resp, err := f.client.Post(u, bytes.NewReader(b))
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to perform post request to create adset.",
-			Err:     err,
-		}
-	}
-	defer resp.Body.Close()
-	b, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to read response to create adset.",
-			Err:     err,
-		}
-	}
-	err = json.Unmarshal(b, &result)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to unmarshal response to create adset.",
-			Err:     err,
-		}
-	}
-
-	if result.Error != nil {
-		return "", &logger.Error{
-			Level:   "Error",
-			Message: "Response to create an adset contained an error.",
-			Err:     result.Error,
-		}
-	}
 
-	return result.ID, nil
+	return newAdSet, nil
 }
 
-func (f *facebook) createCreative(req *Request, accessToken string) (string, error) {
-	var (
-		result = struct {
-			ID    string                  `json:"id"`
-			Error *internal.FacebookError `json:"error"`
-		}{}
-		newCreative = newCreative{}
-	)
+func (f *facebook) creativePayload(req *Request, accessToken string) newCreative {
+	newCreative := newCreative{}
 	newCreative.Status = f.status
 	if req.Objective == "PAGE_LIKES" {
 		newCreative.Title = req.Title
@@ -546,6 +732,18 @@ func (f *facebook) createCreative(req *Request, accessToken string) (string, err
 	}
 
 	newCreative.AccessToken = accessToken
+
+	return newCreative
+}
+
+func (f *facebook) createCreative(req *Request, accessToken string) (string, error) {
+	var (
+		result = struct {
+			ID    string                  `json:"id"`
+			Error *internal.FacebookError `json:"error"`
+		}{}
+		newCreative = f.creativePayload(req, accessToken)
+	)
 	b, err := json.Marshal(newCreative)
 	if err != nil {
 		return "", &logger.Error{
@@ -582,90 +780,25 @@ func (f *facebook) createCreative(req *Request, accessToken string) (string, err
 	}
 
 	if result.Error != nil {
-		return "", &logger.Error{
-			Level:   "Error",
-			Message: "The response to create a creative contained an error.",
-			Err:     result.Error,
-		}
+		return "", internal.ResponseError(result.Error, "Error", "The response to create a creative contained an error.", nil)
 	}
 
 	return result.ID, nil
 }
 
-func (f *facebook) createAds(adAccount string, adSets []string, creativeID, accessToken string) ([]string, error) {
-	var ads = make([]string, len(adSets))
-	for i, adset := range adSets {
-		id, err := f.createAd(adAccount, adset, creativeID, accessToken)
-		if err != nil {
-			return nil, err
-		}
-		ads[i] = id
-	}
-
-	return ads, nil
-}
-
-func (f *facebook) createAd(adAccount, adsetID, creativeID, accessToken string) (string, error) {
+func (f *facebook) adPayload(adsetID, creativeID, accessToken string) (newAd, error) {
 	name, err := randomName()
 	if err != nil {
-		return "", err
-	}
-	var (
-		result = struct {
-			ID    string                  `json:"id"`
-			Error *internal.FacebookError `json:"error"`
-		}{}
-		newAd = newAd{
-			Name:    name,
-			AdsetID: adsetID,
-			Creative: creative{
-				CreativeID: creativeID,
-			},
-			Status:      f.status,
-			AccessToken: accessToken,
-		}
-	)
-	b, err := json.Marshal(newAd)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to marshal new ad object.",
-			Err:     err,
-		}
-	}
-	u := internal.SetURL(fmt.Sprintf("%s/ads", adAccount), nil)
-	resp, err := f.client.Post(u, bytes.NewReader(b))
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to perform request to create ad.",
-			Err:     err,
-		}
-	}
-	defer resp.Body.Close()
-	b, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to read response to create ad.",
-			Err:     err,
-		}
-	}
-	err = json.Unmarshal(b, &result)
-	if err != nil {
-		return "", &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to unmarshal response to create ad.",
-			Err:     err,
-		}
-	}
-	if result.Error != nil {
-		return "", &logger.Error{
-			Level:   "Error",
-			Message: "The response to create an ad contained an error.",
-			Err:     err,
-		}
+		return newAd{}, err
 	}
 
-	return result.ID, nil
+	return newAd{
+		Name:    name,
+		AdsetID: adsetID,
+		Creative: creative{
+			CreativeID: creativeID,
+		},
+		Status:      f.status,
+		AccessToken: accessToken,
+	}, nil
 }
@@ -2,10 +2,12 @@ package campaign
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -16,7 +18,9 @@ import (
 
 	"bitbucket.org/backend/core/entities"
 	"bitbucket.org/backend/core/facebook/auth"
+	"bitbucket.org/backend/core/facebook/internal"
 	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/logger"
 	"bitbucket.org/backend/core/server"
 	"bitbucket.org/backend/core/storage/campaigns"
 	"github.com/aws/aws-sdk-go/aws"
@@ -62,6 +66,10 @@ type createClient struct {
 	adSetID    string
 	adID       string
 	creativeID string
+	// objects deleted by a rollback
+	deleted []string
+	// cancel is called once the creative is created
+	cancel func()
 
 	server.Client
 	t *testing.T
@@ -73,8 +81,19 @@ type store struct {
 	failGetSegment    bool
 	failtSetSegment   bool
 	failStoreCampaign bool
+	failGetEvolution  bool
 
-	segment []*genetic.Chromosome
+	segment   []*genetic.Chromosome
+	evolution *entities.Evolution
+	// populations set to the segment
+	setSegments [][]*genetic.Chromosome
+
+	// lifecycle operations storage
+	failGetCampaign    bool
+	failUpdateCampaign bool
+	campaign           *entities.Campaign
+	userCampaigns      map[string][]string
+	updated            *entities.Campaign
 
 	campaigns.Storage
 	t *testing.T
@@ -106,6 +125,8 @@ type helper struct {
 	storageFailures []string
 	// expectedSegment return from segment stored targeting
 	expectedSegment []*genetic.Chromosome
+	// expectedEvolution return from segment stored evolution settings
+	expectedEvolution *entities.Evolution
 
 	// authFailures is an array
 	// of auth configurations
@@ -148,8 +169,9 @@ func (h *helper) testConfig(f *facebook) {
 	f.client = c
 
 	p := &store{
-		t:       h.t,
-		segment: h.expectedSegment,
+		t:         h.t,
+		segment:   h.expectedSegment,
+		evolution: h.expectedEvolution,
 	}
 	pV := reflect.ValueOf(p)
 	for _, storeFailure := range h.storageFailures {
@@ -194,6 +216,8 @@ func (c *createClient) Post(u string, body io.Reader) (*http.Response, error) {
 	}
 
 	switch {
+	case requestURL.Path == "/v8.0/":
+		return c.batch(body)
 	case strings.Contains(requestURL.Path, "/campaigns"):
 		if c.failCreateCampaignRequest {
 			return nil, errFailRequest
@@ -206,6 +230,9 @@ func (c *createClient) Post(u string, body io.Reader) (*http.Response, error) {
 		if c.failCreateAdCreativeRequest {
 			return nil, errFailRequest
 		}
+		if c.cancel != nil {
+			defer c.cancel()
+		}
 	case strings.Contains(requestURL.Path, "/ads"):
 		if c.failCreateAdRequest {
 			return nil, errFailRequest
@@ -219,6 +246,94 @@ func (c *createClient) Post(u string, body io.Reader) (*http.Response, error) {
 	return w.Result(), nil
 }
 
+// batch performs every operation of a batch request with the handler
+func (c *createClient) batch(body io.Reader) (*http.Response, error) {
+	var (
+		batch = struct {
+			AccessToken string                    `json:"access_token"`
+			Batch       []internal.BatchOperation `json:"batch"`
+		}{}
+		results = []internal.BatchResult{}
+		ids     = map[string]string{}
+	)
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		c.t.Fatal("unable to read body", err)
+	}
+	testUmarshal(c.t, b, &batch)
+	if batch.AccessToken == "" {
+		c.t.Fatal("Missing access token to perform batch request")
+	}
+
+	for _, o := range batch.Batch {
+		operationURL, err := url.Parse(o.RelativeURL)
+		if err != nil {
+			c.t.Fatal("Unable to parse batch operation url: ", err)
+		}
+		switch {
+		case strings.Contains(operationURL.Path, "/adsets") && c.failCreateAdsetRequest:
+			return nil, errFailRequest
+		case strings.Contains(operationURL.Path, "/ads") && c.failCreateAdRequest:
+			return nil, errFailRequest
+		}
+
+		operationBody := o.Body
+		for name, id := range ids {
+			operationBody = strings.ReplaceAll(operationBody, fmt.Sprintf("{result=%s:$.id}", name), id)
+		}
+		if strings.Contains(operationBody, "{result=") {
+			results = append(results, internal.BatchResult{Code: 400, Body: `{"error":{"message":"failing dependency"}}`})
+			continue
+		}
+
+		w := httptest.NewRecorder()
+		c.handler(w, operationURL, bytes.NewReader(testBatchBody(c.t, operationBody)))
+		result := internal.BatchResult{Code: 200, Body: w.Body.String()}
+		results = append(results, result)
+		if o.Name != "" {
+			created := createdObject{}
+			if json.Unmarshal([]byte(result.Body), &created) == nil && created.ID != "" {
+				ids[o.Name] = created.ID
+			}
+		}
+	}
+
+	b, err = json.Marshal(results)
+	if err != nil {
+		c.t.Fatal("Unable to marshal batch results: ", err)
+	}
+	w := httptest.NewRecorder()
+	w.Write(b)
+
+	return w.Result(), nil
+}
+
+// testBatchBody returns the json object of the url encoded body of a batch operation
+func testBatchBody(t *testing.T, body string) []byte {
+	t.Helper()
+
+	uV, err := url.ParseQuery(body)
+	if err != nil {
+		t.Fatal("Unable to parse batch operation body: ", err)
+	}
+	fields := map[string]json.RawMessage{}
+	for k := range uV {
+		v := uV.Get(k)
+		switch k {
+		case "targeting", "promoted_object", "creative":
+			fields[k] = json.RawMessage(v)
+		default:
+			fields[k], _ = json.Marshal(v)
+		}
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal("Unable to marshal batch operation body: ", err)
+	}
+
+	return b
+}
+
 func (c *createClient) handler(w http.ResponseWriter, requestURL *url.URL, body io.Reader) {
 	if c.failUnmarshal {
 		io.WriteString(w, `{"status":200,}`)
@@ -370,6 +485,10 @@ func (c *createClient) testCheckAdRequest(newAd *newAd) {
 	}
 }
 
+func (s *store) WithContext(ctx context.Context) campaigns.Storage {
+	return s
+}
+
 func (s *store) GetSegment(userID, segment string) ([]*genetic.Chromosome, error) {
 	if s.failGetSegment {
 		return nil, errorFailStorage
@@ -382,10 +501,19 @@ func (s *store) SetSegment(userID, segment string, initialPopulation []*genetic.
 	if s.failtSetSegment {
 		return errorFailStorage
 	}
+	s.setSegments = append(s.setSegments, initialPopulation)
 
 	return nil
 }
 
+func (s *store) GetEvolution(userID, segment string) (*entities.Evolution, error) {
+	if s.failGetEvolution {
+		return nil, errorFailStorage
+	}
+
+	return s.evolution, nil
+}
+
 func (s *store) StoreCampaign(userID, platform, adAccount, segment string, c *entities.Campaign) error {
 	if s.failStoreCampaign {
 		return errorFailStorage
@@ -394,6 +522,10 @@ func (s *store) StoreCampaign(userID, platform, adAccount, segment string, c *en
 	return nil
 }
 
+func (a *platformAuth) WithContext(ctx context.Context) auth.Auth {
+	return a
+}
+
 func (a *platformAuth) GetUser(userID string) (*entities.Facebook, bool, error) {
 	if a.failAuth {
 		return nil, false, errorFailAuth
@@ -477,7 +609,9 @@ func TestCreate(t *testing.T) {
 		Helper *helper
 		UserID string
 		Req    *Request
-		Error  error
+		// EndTime is the normalized end time of the created campaign
+		EndTime string
+		Error   error
 	}{
 		{
 			Name: "Conversion Image Campaign",
@@ -486,6 +620,7 @@ func TestCreate(t *testing.T) {
 				expectedAuth: &entities.Facebook{
 					ID:          "1234",
 					AccessToken: "unicorn60",
+					AdAccounts:  []entities.AdAccount{{ID: "act_1234123", AccountID: "1234123", Currency: "USD"}},
 				},
 				expectedSegment: basicChromosome,
 				t:               t,
@@ -499,7 +634,7 @@ func TestCreate(t *testing.T) {
 				Segment:           "techUnicorn",
 				MutationRate:      0.01,
 				StartTime:         time.Now().String(),
-				EndTime:           time.Now().Add(time.Hour * 365).String(),
+				EndTime:           "2030-10-08T10:00:00-0500",
 				Location: geolocation{
 					Countries: []string{"CO"},
 				},
@@ -525,7 +660,8 @@ func TestCreate(t *testing.T) {
 				},
 				AdAccount: "act_1234123",
 			},
-			Error: nil,
+			EndTime: "2030-10-08T15:00:00Z",
+			Error:   nil,
 		},
 	}
 
@@ -540,7 +676,416 @@ func TestCreate(t *testing.T) {
 
 	for _, tc := range cases {
 		campaign := New(sess, tc.Helper.testConfig)
-		_, err := campaign.Create(tc.UserID, tc.Req)
+		c, err := campaign.Create(tc.UserID, tc.Req)
 		assert.Equal(tc.Error, err)
+		if err == nil {
+			assert.Equal(tc.EndTime, c.EndTime)
+		}
+	}
+
+	t.Run("Invalid End Time", func(t *testing.T) {
+		h := &helper{
+			campaignID:      "1234",
+			expectedAuth:    cases[0].Helper.expectedAuth,
+			expectedSegment: basicChromosome,
+			t:               t,
+		}
+		f := New(sess, h.testConfig).(*facebook)
+		// the plan client fails the test on any object created on facebook
+		f.client = &planClient{t: t}
+		req := *cases[0].Req
+		// a date without time can't be stored so it fails before creating the campaign
+		req.EndTime = "2030-10-08"
+		_, err := f.Create("andres", &req)
+		assert.Equal(&logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     errorInvalidEndTime,
+		}, err)
+	})
+}
+
+func TestNewPopulation(t *testing.T) {
+	var initialPopulation = func() []*genetic.Chromosome {
+		population := []*genetic.Chromosome{}
+		for i := 1; i <= defaultEliteCount; i++ {
+			root := &genetic.Gene{}
+			root.Children = []*genetic.Gene{
+				{
+					ID:     fmt.Sprintf("interest%d", i),
+					Value:  1,
+					Type:   "interests",
+					Parent: root,
+				},
+				{
+					ID:     fmt.Sprintf("behavior%d", i),
+					Value:  0,
+					Type:   "behaviors",
+					Parent: root,
+				},
+			}
+			population = append(population, &genetic.Chromosome{
+				ID:      fmt.Sprint(i),
+				Quality: float64(i),
+				Root:    root,
+			})
+		}
+
+		return population
 	}
+	cases := []struct {
+		Name          string
+		CrossoverRate float64
+		Population    int
+		Elites        int
+	}{
+		{
+			Name:          "Mutation Only",
+			CrossoverRate: 0.0,
+			Population:    defaultPopulationSize,
+			Elites:        defaultEliteCount,
+		},
+		{
+			Name:          "Crossover And Mutation",
+			CrossoverRate: 1.0,
+			Population:    defaultPopulationSize,
+			Elites:        defaultEliteCount,
+		},
+		{
+			Name:          "Small Segment",
+			CrossoverRate: 0.0,
+			Population:    4,
+			Elites:        2,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			f := &facebook{
+				selection: genetic.New(func(c *genetic.Chromosome) (float64, error) {
+					return c.Quality, nil
+				}),
+				random: genetic.NewSeededSource(1),
+			}
+			e := &entities.Evolution{
+				PopulationSize:  tc.Population,
+				EliteCount:      tc.Elites,
+				MaxMutationRate: 1.0,
+				CrossoverRate:   tc.CrossoverRate,
+			}
+
+			population, err := f.newPopulation(f.selection, initialPopulation(), 1.0, e)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.Equal(tc.Population, len(population))
+
+			elites := population[:tc.Elites]
+			for _, c := range elites {
+				// elites keep their original targeting
+				assert.Equal(1.0, c.Root.Children[0].Value)
+				assert.Equal(0.0, c.Root.Children[1].Value)
+			}
+			for _, c := range population[tc.Elites:] {
+				assert.Equal("", c.ID)
+				for _, e := range elites {
+					assert.False(c.Root == e.Root, "offspring shares its targeting tree with an elite")
+				}
+				for _, g := range c.Root.Children {
+					assert.True(g.Parent == c.Root, "offspring gene doesn't point to its own tree")
+				}
+			}
+			if tc.CrossoverRate == 0.0 {
+				// every gene of a mutant is flipped with a mutation rate of 1
+				for _, c := range population[tc.Elites:] {
+					assert.Equal(0.0, c.Root.Children[0].Value)
+					assert.Equal(1.0, c.Root.Children[1].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizer(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Selection  string
+		Objectives []string
+		Error      error
+	}{
+		{
+			Name:      "Default Selection",
+			Selection: "",
+			Error:     nil,
+		},
+		{
+			Name:      "Tournament Selection",
+			Selection: "tournament",
+			Error:     nil,
+		},
+		{
+			Name:      "Unknown Selection",
+			Selection: "lottery",
+			Error:     genetic.ErrorUnknownSelection,
+		},
+		{
+			Name:       "Multi Objective",
+			Objectives: []string{"reach", "cpm"},
+			Error:      nil,
+		},
+		{
+			Name:       "Unknown Objective",
+			Objectives: []string{"reach", "happiness"},
+			Error:      errUnknownMetric,
+		},
+		{
+			Name:       "Selection With Objectives",
+			Selection:  "tournament",
+			Objectives: []string{"reach", "cpm"},
+			Error:      genetic.ErrorObjectivesSelection,
+		},
+	}
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f := New(sess, Optimization(genetic.SinglePointCrossover)).(*facebook)
+	qu := f.quality.request("unicorn60", "", qualityFunctions[defaultQuality])
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			g, err := f.optimizer(qu, tc.Selection, tc.Objectives)
+			assert.Equal(tc.Error, err)
+			if tc.Error == nil {
+				// every request gets an algorithm with its own quality
+				assert.NotNil(g)
+				assert.False(g == f.selection)
+			}
+		})
+	}
+
+	t.Run("Provided Selection", func(t *testing.T) {
+		f := &facebook{selection: genetic.New(nil)}
+		g, err := f.optimizer(qu, "", nil)
+		assert.Nil(err)
+		assert.True(g == f.selection)
+	})
+}
+
+func TestCheckEvolution(t *testing.T) {
+	var defaults = func() *entities.Evolution {
+		return &entities.Evolution{
+			PopulationSize:  defaultPopulationSize,
+			EliteCount:      defaultEliteCount,
+			MaxMutationRate: defaultMaxMutationRate,
+			CrossoverRate:   defaultCrossoverRate,
+		}
+	}
+	var u = &entities.Facebook{
+		AdAccounts: []entities.AdAccount{
+			{ID: "act_1", AccountID: "1", Currency: "USD"},
+			{ID: "act_2", AccountID: "2", Currency: "MXN"},
+		},
+	}
+	cases := []struct {
+		Name      string
+		Evolution func(e *entities.Evolution)
+		AdAccount string
+		// CampaignBudget uses the budget for the campaign instead of splitting it between the ad sets
+		CampaignBudget bool
+		Budget         string
+		MutationRate   float64
+		Error          error
+	}{
+		{
+			Name:         "Default Evolution",
+			Evolution:    func(e *entities.Evolution) {},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        nil,
+		},
+		{
+			Name: "Small Population",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize, e.EliteCount = 10, 2
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        nil,
+		},
+		{
+			Name: "Insufficient Budget",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize = 40
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInsufficientBudget,
+		},
+		{
+			// the default population can't be funded in a currency with a higher minimum
+			Name:         "Currency Minimum",
+			Evolution:    func(e *entities.Evolution) {},
+			AdAccount:    "act_2",
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInsufficientBudget,
+		},
+		{
+			Name: "Small Population In Currency",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize, e.EliteCount = 1, 1
+			},
+			AdAccount:    "act_2",
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        nil,
+		},
+		{
+			Name:         "Unknown Ad Account",
+			Evolution:    func(e *entities.Evolution) {},
+			AdAccount:    "act_3",
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errUnknownAdAccount,
+		},
+		{
+			Name: "Campaign Budget",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize = 40
+			},
+			CampaignBudget: true,
+			Budget:         "3000",
+			MutationRate:   0.01,
+			Error:          nil,
+		},
+		{
+			// the campaign budget doesn't need the currency of the ad account
+			Name:           "Campaign Budget Of Unknown Ad Account",
+			Evolution:      func(e *entities.Evolution) {},
+			AdAccount:      "act_3",
+			CampaignBudget: true,
+			Budget:         "3000",
+			MutationRate:   0.01,
+			Error:          nil,
+		},
+		{
+			Name:           "Campaign Budget Under Minimum",
+			Evolution:      func(e *entities.Evolution) {},
+			CampaignBudget: true,
+			Budget:         "2999",
+			MutationRate:   0.01,
+			Error:          errorInvalidBudget,
+		},
+		{
+			Name:         "Budget Under Minimum",
+			Evolution:    func(e *entities.Evolution) {},
+			Budget:       "2999",
+			MutationRate: 0.01,
+			Error:        errorInvalidBudget,
+		},
+		{
+			Name:         "Invalid Budget",
+			Evolution:    func(e *entities.Evolution) {},
+			Budget:       "a lot",
+			MutationRate: 0.01,
+			Error:        errorInvalidBudget,
+		},
+		{
+			Name: "Invalid Population Size",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize = 0
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInvalidPopulationSize,
+		},
+		{
+			Name: "Invalid Elite Count",
+			Evolution: func(e *entities.Evolution) {
+				e.EliteCount = defaultPopulationSize + 1
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInvalidEliteCount,
+		},
+		{
+			Name: "Population Smaller Than Elites",
+			Evolution: func(e *entities.Evolution) {
+				e.PopulationSize = defaultEliteCount - 1
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInvalidEliteCount,
+		},
+		{
+			Name: "Invalid Mutation Bounds",
+			Evolution: func(e *entities.Evolution) {
+				e.MinMutationRate = 0.3
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInvalidMutationBounds,
+		},
+		{
+			Name: "Invalid Crossover Rate",
+			Evolution: func(e *entities.Evolution) {
+				e.CrossoverRate = 1.5
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errInvalidCrossoverRate,
+		},
+		{
+			Name: "Mutation Rate Out Of Bounds",
+			Evolution: func(e *entities.Evolution) {
+				e.MinMutationRate, e.MaxMutationRate = 0.05, 0.1
+			},
+			Budget:       "3000",
+			MutationRate: 0.01,
+			Error:        errorInvalidMutationRage,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			f := &facebook{
+				adSetBudgets:        !tc.CampaignBudget,
+				exploration:         defaultExplorationShare,
+				minimumAdSetBudgets: minimumAdSetBudgets,
+			}
+			adAccount := tc.AdAccount
+			if adAccount == "" {
+				adAccount = "act_1"
+			}
+			e := defaults()
+			tc.Evolution(e)
+			_, _, err := f.checkEvolution(u, e, &Request{
+				AdAccount:    adAccount,
+				Budget:       tc.Budget,
+				MutationRate: tc.MutationRate,
+			})
+			assert.Equal(tc.Error, err)
+		})
+	}
+
+	t.Run("Stored Evolution", func(t *testing.T) {
+		stored := &entities.Evolution{PopulationSize: 10, EliteCount: 2}
+		f := &facebook{store: &store{evolution: stored}, crossoverRate: 0.3}
+		e, err := f.evolution("1234", "segment")
+		assert.Nil(err)
+		assert.Equal(stored, e)
+
+		f.store = &store{}
+		e, err = f.evolution("1234", "segment")
+		assert.Nil(err)
+		assert.Equal(defaultPopulationSize, e.PopulationSize)
+		assert.Equal(0.3, e.CrossoverRate)
+	})
 }
@@ -0,0 +1,87 @@
+package campaign
+
+import (
+	"errors"
+	"strconv"
+
+	"bitbucket.org/backend/core/entities"
+)
+
+const (
+	defaultPopulationSize  = 30
+	defaultEliteCount      = 5
+	defaultMaxMutationRate = 0.20
+	defaultCrossoverRate   = 0.6
+	// minimumBudget is the minimum daily budget of a campaign
+	minimumBudget = 3000
+)
+
+var (
+	errInvalidPopulationSize = errors.New("Segment population size must be greater than zero")
+	errInvalidEliteCount     = errors.New("Segment elite count must be between one and the population size")
+	errInvalidMutationBounds = errors.New("Segment mutation rate bounds must be between zero and one")
+	errInvalidCrossoverRate  = errors.New("Segment crossover rate must be between zero and one")
+	errInsufficientBudget    = errors.New("Request budget can't fund the minimum daily spend of every ad set")
+)
+
+// evolution returns the evolution settings of the segment, segments
+// without stored settings use the defaults of the campaign
+func (f *facebook) evolution(userID, segment string) (*entities.Evolution, error) {
+	e, err := f.store.GetEvolution(userID, segment)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entities.Evolution{
+			PopulationSize:  defaultPopulationSize,
+			EliteCount:      defaultEliteCount,
+			MaxMutationRate: defaultMaxMutationRate,
+			CrossoverRate:   f.crossoverRate,
+		}
+	}
+
+	return e, nil
+}
+
+// checkEvolution validates the evolution settings of the segment and checks that the
+// request mutation rate is in the bounds, it returns the campaign budget and the minimum
+// ad set budget of the ad account currency. With ad set budgets the budget must fund the
+// minimum of every ad set, otherwise the campaign budget only needs the minimum budget
+func (f *facebook) checkEvolution(u *entities.Facebook, e *entities.Evolution, req *Request) (float64, float64, error) {
+	switch {
+	case e.PopulationSize < 1:
+		return 0.0, 0.0, errInvalidPopulationSize
+	case e.EliteCount < 1 || e.EliteCount > e.PopulationSize:
+		return 0.0, 0.0, errInvalidEliteCount
+	case e.MinMutationRate < 0 || e.MaxMutationRate > 1 || e.MinMutationRate > e.MaxMutationRate:
+		return 0.0, 0.0, errInvalidMutationBounds
+	case e.CrossoverRate < 0 || e.CrossoverRate > 1:
+		return 0.0, 0.0, errInvalidCrossoverRate
+	case req.MutationRate < e.MinMutationRate || req.MutationRate > e.MaxMutationRate:
+		return 0.0, 0.0, errorInvalidMutationRage
+	}
+
+	if !f.adSetBudgets {
+		budget, err := parseBudget(req.Budget)
+		return budget, 0.0, err
+	}
+
+	return f.checkAdSetBudgets(u, req, e)
+}
+
+// validBudget returns true when the budget is a number greater than the minimum budget
+func validBudget(budget string) bool {
+	_, err := parseBudget(budget)
+
+	return err == nil
+}
+
+// parseBudget parses a daily budget in the minimum unit of the currency
+func parseBudget(budget string) (float64, error) {
+	b, err := strconv.ParseFloat(budget, 64)
+	if err != nil || b < minimumBudget {
+		return 0.0, errorInvalidBudget
+	}
+
+	return b, nil
+}
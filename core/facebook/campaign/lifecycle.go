@@ -0,0 +1,532 @@
+package campaign
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strconv"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/facebook/internal"
+	"bitbucket.org/backend/core/logger"
+	"bitbucket.org/backend/core/storage/schema"
+)
+
+const (
+	statusActive   = "ACTIVE"
+	statusPaused   = "PAUSED"
+	statusArchived = "ARCHIVED"
+	statusDeleted  = "DELETED"
+)
+
+var (
+	errUnknownCampaign = errors.New("The campaign doesn't belong to the user")
+	errInvalidStatus   = errors.New("The operation isn't allowed for the campaign status")
+	errInvalidSchedule = errors.New("The end time must be after the start time")
+)
+
+// graphObject is an ad set or ad of a campaign
+type graphObject struct {
+	ID          string `json:"id"`
+	DailyBudget string `json:"daily_budget,omitempty"`
+	AccountID   string `json:"account_id,omitempty"`
+	Status      string `json:"status,omitempty"`
+	StartTime   string `json:"start_time,omitempty"`
+	EndTime     string `json:"end_time,omitempty"`
+}
+
+// kindObject is a graph object with its kind, used to name the steps of a saga
+type kindObject struct {
+	kind string
+	graphObject
+}
+
+// Pause stops the delivery of the campaign, its ad sets and ads
+func (f *facebook) Pause(userID, campaignID string) (*entities.Campaign, error) {
+	return f.setStatus(userID, campaignID, statusPaused)
+}
+
+// Resume restarts the delivery of a paused campaign, its ad sets and ads
+func (f *facebook) Resume(userID, campaignID string) (*entities.Campaign, error) {
+	return f.setStatus(userID, campaignID, statusActive)
+}
+
+// Archive stops the campaign, its ad sets and ads and hides them from
+// the active objects, an archived campaign can only be deleted
+func (f *facebook) Archive(userID, campaignID string) (*entities.Campaign, error) {
+	return f.setStatus(userID, campaignID, statusArchived)
+}
+
+// Delete deletes the campaign, its ad sets and ads, the stored
+// campaign is kept with a deleted status
+func (f *facebook) Delete(userID, campaignID string) (*entities.Campaign, error) {
+	return f.setStatus(userID, campaignID, statusDeleted)
+}
+
+// setStatus updates the status of the campaign, its ad sets and ads, the updated
+// objects get back their status when a later update fails
+func (f *facebook) setStatus(userID, campaignID, status string) (*entities.Campaign, error) {
+	c, u, err := f.userCampaign(userID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken := u.AccessToken
+	// an archived campaign can only be deleted
+	if c.Status == statusDeleted || (c.Status == statusArchived && status != statusDeleted) {
+		return nil, &logger.Error{
+			Level:   "Warning",
+			Message: "Unable to change the status of the campaign.",
+			Err:     errInvalidStatus,
+			Context: []interface{}{c.ID, c.Status, status},
+		}
+	}
+
+	adSets, err := f.getObjects(campaignID, "adsets", "id,status", accessToken)
+	if err != nil {
+		return nil, err
+	}
+	ads, err := f.getObjects(campaignID, "ads", "id,status", accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// the campaign is stopped before its children and started after
+	// them so it never delivers with a partially updated status
+	objects := []kindObject{{campaignObject, graphObject{ID: campaignID, Status: c.Status}}}
+	for _, adSet := range adSets {
+		objects = append(objects, kindObject{adSetObject, adSet})
+	}
+	for _, ad := range ads {
+		objects = append(objects, kindObject{adObject, ad})
+	}
+	if status == statusActive {
+		for i, j := 0, len(objects)-1; i < j; i, j = i+1, j-1 {
+			objects[i], objects[j] = objects[j], objects[i]
+		}
+	}
+
+	// the updated objects get back their status when a later update fails
+	s := f.newSaga(accessToken)
+	for _, o := range objects {
+		err := f.updateObject(o.ID, accessToken, map[string]string{"status": status})
+		if err != nil {
+			return nil, s.rollback(err)
+		}
+		if o.Status == "" || o.Status == status {
+			continue
+		}
+		o := o
+		s.compensate(fmt.Sprintf("%s %s status", o.kind, o.ID), func() error {
+			return s.f.updateObject(o.ID, s.accessToken, map[string]string{"status": o.Status})
+		})
+	}
+
+	c.Status = status
+	if err := f.updateCampaign(c); err != nil {
+		return nil, s.rollback(err)
+	}
+
+	return c, nil
+}
+
+// UpdateBudget changes the daily budget of the campaign, when the ad sets have their
+// own budget they are scaled to keep the same allocation and must fund the minimum ad
+// set budget. The updated budgets are restored when a later update fails
+func (f *facebook) UpdateBudget(userID, campaignID, budget string) (*entities.Campaign, error) {
+	b, err := parseBudget(budget)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+		}
+	}
+	c, u, err := f.userCampaign(userID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken := u.AccessToken
+	if err := checkEditable(c); err != nil {
+		return nil, err
+	}
+
+	adSets, err := f.getObjects(campaignID, "adsets", "id,daily_budget,account_id", accessToken)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := scaleBudgets(adSets, b)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Unable to parse the budget of the adsets.",
+			Err:     err,
+			Context: adSets,
+		}
+	}
+	if budgets != nil {
+		if err := f.checkScaledBudgets(u, adSets, budgets); err != nil {
+			return nil, &logger.Error{
+				Level:   "Error",
+				Message: "The budget doesn't fund the minimum budget of the adsets.",
+				Err:     err,
+				Context: budgets,
+			}
+		}
+	}
+
+	s := f.newSaga(accessToken)
+	if budgets == nil {
+		err = f.updateObject(campaignID, accessToken, map[string]string{"daily_budget": budget})
+		if err != nil {
+			return nil, err
+		}
+		previous := c.Budget
+		s.compensate(fmt.Sprintf("%s %s budget", campaignObject, campaignID), func() error {
+			return s.f.updateObject(campaignID, s.accessToken, map[string]string{"daily_budget": previous})
+		})
+	} else {
+		for i, adSet := range adSets {
+			if adSet.DailyBudget == "" {
+				continue
+			}
+			err := f.updateObject(adSet.ID, accessToken, map[string]string{"daily_budget": budgets[i]})
+			if err != nil {
+				return nil, s.rollback(err)
+			}
+			adSet := adSet
+			s.compensate(fmt.Sprintf("%s %s budget", adSetObject, adSet.ID), func() error {
+				return s.f.updateObject(adSet.ID, s.accessToken, map[string]string{"daily_budget": adSet.DailyBudget})
+			})
+		}
+	}
+
+	c.Budget = budget
+	if err := f.updateCampaign(c); err != nil {
+		return nil, s.rollback(err)
+	}
+
+	return c, nil
+}
+
+// checkScaledBudgets checks every scaled budget of an ad set with its own budget
+// funds the minimum ad set budget of the currency of the campaign ad account
+func (f *facebook) checkScaledBudgets(u *entities.Facebook, adSets []graphObject, budgets []string) error {
+	if len(adSets) == 0 {
+		return nil
+	}
+	minimum, err := f.minimumAdSetBudget(u, adSets[0].AccountID)
+	if err != nil {
+		return err
+	}
+	for i, adSet := range adSets {
+		if adSet.DailyBudget == "" {
+			continue
+		}
+		b, err := strconv.ParseFloat(budgets[i], 64)
+		if err != nil {
+			return err
+		}
+		if b < minimum {
+			return errInsufficientBudget
+		}
+	}
+
+	return nil
+}
+
+// UpdateSchedule changes the start and end time of the campaign and its ad sets, the
+// times are normalized to UTC and the end time must be after the start time. The
+// updated schedules are restored when a later update fails
+func (f *facebook) UpdateSchedule(userID, campaignID, startTime, endTime string) (*entities.Campaign, error) {
+	startTime, endTime, err := parseSchedule(startTime, endTime)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Error",
+			Message: "Invalid Request",
+			Err:     err,
+		}
+	}
+	c, u, err := f.userCampaign(userID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken := u.AccessToken
+	if err := checkEditable(c); err != nil {
+		return nil, err
+	}
+
+	adSets, err := f.getObjects(campaignID, "adsets", "id,start_time,end_time", accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// the campaign and the updated ad sets get back their
+	// schedule when a later update fails
+	s := f.newSaga(accessToken)
+	err = f.updateObject(campaignID, accessToken, map[string]string{
+		"start_time": startTime,
+		"stop_time":  endTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	previousStart, previousEnd := c.StartTime, c.EndTime
+	s.compensate(fmt.Sprintf("%s %s schedule", campaignObject, campaignID), func() error {
+		return s.f.updateObject(campaignID, s.accessToken, map[string]string{
+			"start_time": previousStart,
+			"stop_time":  previousEnd,
+		})
+	})
+	for _, adSet := range adSets {
+		err := f.updateObject(adSet.ID, accessToken, map[string]string{
+			"start_time": startTime,
+			"end_time":   endTime,
+		})
+		if err != nil {
+			return nil, s.rollback(err)
+		}
+		if adSet.StartTime == "" || adSet.EndTime == "" {
+			continue
+		}
+		adSet := adSet
+		s.compensate(fmt.Sprintf("%s %s schedule", adSetObject, adSet.ID), func() error {
+			return s.f.updateObject(adSet.ID, s.accessToken, map[string]string{
+				"start_time": adSet.StartTime,
+				"end_time":   adSet.EndTime,
+			})
+		})
+	}
+
+	c.StartTime, c.EndTime = startTime, endTime
+	if err := f.updateCampaign(c); err != nil {
+		return nil, s.rollback(err)
+	}
+
+	return c, nil
+}
+
+// parseSchedule returns the start and end time normalized to UTC, they are
+// compared as strings because their lexical order is the chronological one
+func parseSchedule(startTime, endTime string) (string, string, error) {
+	switch {
+	case startTime == "":
+		return "", "", errorMissingStartTime
+	case endTime == "":
+		return "", "", errorMissingEndTime
+	}
+	start, err := schema.NormalizeTime(startTime)
+	if err != nil {
+		return "", "", err
+	}
+	end, err := schema.NormalizeTime(endTime)
+	if err != nil {
+		return "", "", err
+	}
+	if end <= start {
+		return "", "", errInvalidSchedule
+	}
+
+	return start, end, nil
+}
+
+func checkEditable(c *entities.Campaign) error {
+	if c.Status == statusArchived || c.Status == statusDeleted {
+		return &logger.Error{
+			Level:   "Warning",
+			Message: "Unable to update an archived or deleted campaign.",
+			Err:     errInvalidStatus,
+			Context: []interface{}{c.ID, c.Status},
+		}
+	}
+
+	return nil
+}
+
+// scaleBudgets returns the ad set budgets scaled to add up to the new budget, nil is
+// returned when the ad sets don't have a budget. The budgets are rounded down and the
+// remainder is assigned to the ad set with the largest budget
+func scaleBudgets(adSets []graphObject, budget float64) ([]string, error) {
+	var (
+		current = make([]float64, len(adSets))
+		total   float64
+		largest int
+	)
+	for i, adSet := range adSets {
+		if adSet.DailyBudget == "" {
+			continue
+		}
+		b, err := strconv.ParseFloat(adSet.DailyBudget, 64)
+		if err != nil {
+			return nil, err
+		}
+		current[i] = b
+		total += b
+		if b > current[largest] {
+			largest = i
+		}
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	var allocated float64
+	for i := range current {
+		current[i] = math.Floor(current[i] * budget / total)
+		allocated += current[i]
+	}
+	current[largest] += math.Floor(budget) - allocated
+
+	budgets := make([]string, len(current))
+	for i, b := range current {
+		budgets[i] = strconv.FormatFloat(b, 'f', 0, 64)
+	}
+
+	return budgets, nil
+}
+
+// userCampaign returns the stored campaign and the facebook data of the user, the
+// campaigns created before the status was stored are active
+func (f *facebook) userCampaign(userID, campaignID string) (*entities.Campaign, *entities.Facebook, error) {
+	u, valid, err := f.auth.GetUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, &logger.Error{
+			Level:         "Warning",
+			Message:       "Unable to update campaign because the user access token is not valid",
+			Err:           errInvalidToken,
+			ClientMessage: internal.ClientMessage(errInvalidToken),
+		}
+	}
+
+	campaigns, err := f.store.GetUserCampaigns(userID)
+	if err != nil {
+		return nil, nil, &logger.Error{
+			Level:   "panic",
+			Message: "Unable to get user campaigns from data base",
+			Err:     err,
+		}
+	}
+	owned := false
+	for _, id := range campaigns["facebook"] {
+		if id == campaignID {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return nil, nil, &logger.Error{
+			Level:   "Warning",
+			Message: "Unable to find the user campaign.",
+			Err:     errUnknownCampaign,
+			Context: []string{userID, campaignID},
+		}
+	}
+
+	c, err := f.store.GetCampaign(campaignID)
+	if err != nil {
+		return nil, nil, &logger.Error{
+			Level:   "panic",
+			Message: "Unable to get campaign from data base",
+			Err:     err,
+		}
+	}
+	if c.Status == "" {
+		c.Status = statusActive
+	}
+
+	return c, u, nil
+}
+
+func (f *facebook) updateCampaign(c *entities.Campaign) error {
+	err := f.store.UpdateCampaign(c)
+	if err != nil {
+		return &logger.Error{
+			Level:   "panic",
+			Message: "Unable to update user campaign.",
+			Err:     err,
+			Context: c,
+		}
+	}
+
+	return nil
+}
+
+// getObjects returns every ad set or ad of the campaign following the response paging
+func (f *facebook) getObjects(campaignID, edge, fields, accessToken string) ([]graphObject, error) {
+	var objects = []graphObject{}
+
+	uV := url.Values{}
+	uV.Add("access_token", accessToken)
+	uV.Add("fields", fields)
+	uV.Add("limit", "100")
+	u := internal.SetURL(fmt.Sprintf("%s/%s", campaignID, edge), uV)
+	err := internal.List(f.ctx, f.client, u, &objects)
+	if err != nil {
+		return nil, internal.ListError(err,
+			fmt.Sprintf("Unable to perform request to get the %s of a campaign.", edge),
+			fmt.Sprintf("Response to get the %s of a campaign contained an error.", edge),
+			campaignID)
+	}
+
+	return objects, nil
+}
+
+// updateObject updates the fields of a campaign, ad set or ad
+func (f *facebook) updateObject(id, accessToken string, fields map[string]string) error {
+	var (
+		result = struct {
+			Success bool                    `json:"success"`
+			Error   *internal.FacebookError `json:"error"`
+		}{}
+		update = map[string]string{"access_token": accessToken}
+	)
+	for k, v := range fields {
+		update[k] = v
+	}
+
+	b, err := json.Marshal(update)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to marshal data to update a campaign object.",
+			Err:     err,
+		}
+	}
+	u := internal.SetURL(id, nil)
+	resp, err := f.client.Post(u, bytes.NewReader(b))
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to perform request to update a campaign object.",
+			Err:     err,
+		}
+	}
+	defer resp.Body.Close()
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to read response to update a campaign object.",
+			Err:     err,
+		}
+	}
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to unmarshal response to update a campaign object.",
+			Err:     err,
+		}
+	}
+	if result.Error != nil {
+		return internal.ResponseError(result.Error, "Error", "Response to update a campaign object contained an error.", []interface{}{id, fields})
+	}
+
+	return nil
+}
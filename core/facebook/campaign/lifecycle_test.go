@@ -0,0 +1,455 @@
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/facebook/internal"
+	"bitbucket.org/backend/core/logger"
+	"bitbucket.org/backend/core/server"
+	"bitbucket.org/backend/core/storage/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// record sends the requests of the replay tests to the graph api and writes their fixtures
+var record = flag.Bool("record", false, "record the graph api fixtures of the replay tests")
+
+// replayClient returns a client that replays the graph api fixture, the
+// fixture is recorded again when the tests run with the -record flag
+func replayClient(t *testing.T, fixture string) (server.Client, *server.Recorder) {
+	mode := server.Replay
+	if *record {
+		mode = server.Record
+	}
+	r, err := server.NewRecorder(filepath.Join("tests-fixtures", "graph", fixture+".json"), mode)
+	if err != nil {
+		t.Fatal("Unable to load graph api fixture: ", err)
+	}
+	if *record {
+		t.Cleanup(func() {
+			if err := r.Save(); err != nil {
+				t.Error("Unable to save graph api fixture: ", err)
+			}
+		})
+	}
+
+	return server.New(server.Transport(r)), r
+}
+
+type objectUpdate struct {
+	ID     string
+	Fields map[string]string
+}
+
+type lifecycleClient struct {
+	// fail the update of the object with the id
+	failUpdate string
+	// fail the retrieval of the campaign objects
+	failGet bool
+
+	adSets []graphObject
+	// ads are returned in two pages
+	ads []graphObject
+
+	updates []objectUpdate
+
+	server.Client
+	t *testing.T
+}
+
+func (c *lifecycleClient) Get(u string) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	if requestURL.Query().Get("access_token") == "" {
+		c.t.Fatal("Missing access token to get campaign objects")
+	}
+
+	w := httptest.NewRecorder()
+	switch {
+	case c.failGet:
+		io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+	case strings.HasSuffix(requestURL.Path, "/adsets"):
+		b, _ := json.Marshal(map[string]interface{}{"data": c.adSets})
+		w.Write(b)
+	case strings.HasSuffix(requestURL.Path, "/ads"):
+		page := map[string]interface{}{"data": c.ads[:1]}
+		if requestURL.Query().Get("after") == "" {
+			uV := requestURL.Query()
+			uV.Set("after", "1")
+			page["paging"] = internal.FacebookPaging{Next: internal.SetURL(strings.TrimPrefix(requestURL.Path, "/v8.0/"), uV)}
+		} else {
+			page["data"] = c.ads[1:]
+		}
+		b, _ := json.Marshal(page)
+		w.Write(b)
+	default:
+		c.t.Fatalf("Unexpected request path: %s", requestURL.Path)
+	}
+
+	return w.Result(), nil
+}
+
+func (c *lifecycleClient) Post(u string, body io.Reader) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		c.t.Fatal("Unable to read request body: ", err)
+	}
+	fields := map[string]string{}
+	testUmarshal(c.t, b, &fields)
+	if fields["access_token"] == "" {
+		c.t.Fatal("Missing access token to update campaign object")
+	}
+	delete(fields, "access_token")
+
+	id := strings.TrimPrefix(requestURL.Path, "/v8.0/")
+	w := httptest.NewRecorder()
+	if id == c.failUpdate {
+		io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+		return w.Result(), nil
+	}
+	c.updates = append(c.updates, objectUpdate{ID: id, Fields: fields})
+	io.WriteString(w, `{"success":true}`)
+
+	return w.Result(), nil
+}
+
+func (s *store) GetUserCampaigns(userID string) (map[string][]string, error) {
+	return s.userCampaigns, nil
+}
+
+func (s *store) GetCampaign(campaignID string) (*entities.Campaign, error) {
+	if s.failGetCampaign {
+		return nil, errorFailStorage
+	}
+	c := *s.campaign
+
+	return &c, nil
+}
+
+func (s *store) UpdateCampaign(c *entities.Campaign) error {
+	if s.failUpdateCampaign {
+		return errorFailStorage
+	}
+	s.updated = c
+
+	return nil
+}
+
+func testStatusUpdates(status string, ids ...string) []objectUpdate {
+	updates := []objectUpdate{}
+	for _, id := range ids {
+		updates = append(updates, objectUpdate{ID: id, Fields: map[string]string{"status": status}})
+	}
+
+	return updates
+}
+
+func TestLifecycle(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Operation  func(c Campaign) (*entities.Campaign, error)
+		Status     string
+		AdSets     []graphObject
+		Client     *lifecycleClient
+		Store      *store
+		Expected   []objectUpdate
+		Campaign   *entities.Campaign
+		Error      error
+		StoreError bool
+	}{
+		{
+			Name: "Pause",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Pause("andres", "c1")
+			},
+			Expected: testStatusUpdates(statusPaused, "c1", "as1", "as2", "ad1", "ad2", "ad3"),
+			Campaign: &entities.Campaign{ID: "c1", Status: statusPaused, Budget: "3000", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Resume",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Resume("andres", "c1")
+			},
+			Status:   statusPaused,
+			Expected: testStatusUpdates(statusActive, "ad3", "ad2", "ad1", "as2", "as1", "c1"),
+			Campaign: &entities.Campaign{ID: "c1", Status: statusActive, Budget: "3000", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Archive",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Archive("andres", "c1")
+			},
+			Expected: testStatusUpdates(statusArchived, "c1", "as1", "as2", "ad1", "ad2", "ad3"),
+			Campaign: &entities.Campaign{ID: "c1", Status: statusArchived, Budget: "3000", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Delete Archived",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Delete("andres", "c1")
+			},
+			Status:   statusArchived,
+			Expected: testStatusUpdates(statusDeleted, "c1", "as1", "as2", "ad1", "ad2", "ad3"),
+			Campaign: &entities.Campaign{ID: "c1", Status: statusDeleted, Budget: "3000", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Resume Archived",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Resume("andres", "c1")
+			},
+			Status: statusArchived,
+			Error:  errInvalidStatus,
+		},
+		{
+			Name: "Campaign Budget",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "6000")
+			},
+			Expected: []objectUpdate{{ID: "c1", Fields: map[string]string{"daily_budget": "6000"}}},
+			Campaign: &entities.Campaign{ID: "c1", Status: statusActive, Budget: "6000", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Ad Set Budgets",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "6001")
+			},
+			AdSets: []graphObject{{ID: "as1", DailyBudget: "1000", AccountID: "1234"}, {ID: "as2", DailyBudget: "3000", AccountID: "1234"}},
+			Expected: []objectUpdate{
+				{ID: "as1", Fields: map[string]string{"daily_budget": "1500"}},
+				{ID: "as2", Fields: map[string]string{"daily_budget": "4501"}},
+			},
+			Campaign: &entities.Campaign{ID: "c1", Status: statusActive, Budget: "6001", StartTime: "start", EndTime: "end"},
+		},
+		{
+			Name: "Insufficient Ad Set Budgets",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "3000")
+			},
+			// the first ad set would get 30 cents
+			AdSets: []graphObject{{ID: "as1", DailyBudget: "100", AccountID: "1234"}, {ID: "as2", DailyBudget: "9900", AccountID: "1234"}},
+			Error:  errInsufficientBudget,
+		},
+		{
+			Name: "Failing Ad Set Budget",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "6001")
+			},
+			AdSets: []graphObject{{ID: "as1", DailyBudget: "1000", AccountID: "1234"}, {ID: "as2", DailyBudget: "3000", AccountID: "1234"}},
+			Client: &lifecycleClient{failUpdate: "as2"},
+			// the updated budget is restored
+			Expected: []objectUpdate{
+				{ID: "as1", Fields: map[string]string{"daily_budget": "1500"}},
+				{ID: "as1", Fields: map[string]string{"daily_budget": "1000"}},
+			},
+			StoreError: true,
+		},
+		{
+			Name: "Failing Budget Storage",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "6000")
+			},
+			Store: &store{failUpdateCampaign: true},
+			Expected: []objectUpdate{
+				{ID: "c1", Fields: map[string]string{"daily_budget": "6000"}},
+				{ID: "c1", Fields: map[string]string{"daily_budget": "3000"}},
+			},
+			Error:      errorFailStorage,
+			StoreError: true,
+		},
+		{
+			Name: "Invalid Budget",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateBudget("andres", "c1", "10")
+			},
+			Error: errorInvalidBudget,
+		},
+		{
+			Name: "Schedule",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "2020-10-01T10:00:00-0500", "2020-10-08T15:00:00Z")
+			},
+			Expected: []objectUpdate{
+				{ID: "c1", Fields: map[string]string{"start_time": "2020-10-01T15:00:00Z", "stop_time": "2020-10-08T15:00:00Z"}},
+				{ID: "as1", Fields: map[string]string{"start_time": "2020-10-01T15:00:00Z", "end_time": "2020-10-08T15:00:00Z"}},
+				{ID: "as2", Fields: map[string]string{"start_time": "2020-10-01T15:00:00Z", "end_time": "2020-10-08T15:00:00Z"}},
+			},
+			Campaign: &entities.Campaign{ID: "c1", Status: statusActive, Budget: "3000", StartTime: "2020-10-01T15:00:00Z", EndTime: "2020-10-08T15:00:00Z"},
+		},
+		{
+			Name: "Failing Ad Set Schedule",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "2020-10-01T15:00:00Z", "2020-10-08T15:00:00Z")
+			},
+			AdSets: []graphObject{
+				{ID: "as1", StartTime: "2020-09-01T15:00:00Z", EndTime: "2020-09-08T15:00:00Z"},
+				{ID: "as2", StartTime: "2020-09-01T15:00:00Z", EndTime: "2020-09-08T15:00:00Z"},
+			},
+			Client: &lifecycleClient{failUpdate: "as2"},
+			// the campaign and the updated ad set get back their schedule
+			Expected: []objectUpdate{
+				{ID: "c1", Fields: map[string]string{"start_time": "2020-10-01T15:00:00Z", "stop_time": "2020-10-08T15:00:00Z"}},
+				{ID: "as1", Fields: map[string]string{"start_time": "2020-10-01T15:00:00Z", "end_time": "2020-10-08T15:00:00Z"}},
+				{ID: "as1", Fields: map[string]string{"start_time": "2020-09-01T15:00:00Z", "end_time": "2020-09-08T15:00:00Z"}},
+				{ID: "c1", Fields: map[string]string{"start_time": "start", "stop_time": "end"}},
+			},
+			StoreError: true,
+		},
+		{
+			Name: "Missing End Time",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "2020-10-01T15:00:00Z", "")
+			},
+			Error: errorMissingEndTime,
+		},
+		{
+			Name: "Invalid Start Time",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "tomorrow", "2020-10-08T15:00:00Z")
+			},
+			Error: schema.ErrorInvalidTime,
+		},
+		{
+			Name: "End Before Start",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "2020-10-08T15:00:00Z", "2020-10-08T10:00:00-0500")
+			},
+			Error: errInvalidSchedule,
+		},
+		{
+			Name: "Schedule Deleted",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.UpdateSchedule("andres", "c1", "2020-10-01T15:00:00Z", "2020-10-08T15:00:00Z")
+			},
+			Status: statusDeleted,
+			Error:  errInvalidStatus,
+		},
+		{
+			Name: "Unknown Campaign",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Pause("andres", "c2")
+			},
+			Error: errUnknownCampaign,
+		},
+		{
+			Name: "Failing Update",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Pause("andres", "c1")
+			},
+			Client: &lifecycleClient{failUpdate: "as2"},
+			// the updated objects get back their status
+			Expected:   append(testStatusUpdates(statusPaused, "c1", "as1"), testStatusUpdates(statusActive, "as1", "c1")...),
+			StoreError: true,
+		},
+		{
+			Name: "Failing Objects",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Pause("andres", "c1")
+			},
+			Client:     &lifecycleClient{failGet: true},
+			StoreError: true,
+		},
+		{
+			Name: "Failing Storage",
+			Operation: func(c Campaign) (*entities.Campaign, error) {
+				return c.Pause("andres", "c1")
+			},
+			Store: &store{failUpdateCampaign: true},
+			Expected: append(testStatusUpdates(statusPaused, "c1", "as1", "as2", "ad1", "ad2", "ad3"),
+				testStatusUpdates(statusActive, "ad3", "ad2", "ad1", "as2", "as1", "c1")...),
+			Error:      errorFailStorage,
+			StoreError: true,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := tc.Client
+			if client == nil {
+				client = &lifecycleClient{}
+			}
+			client.t = t
+			client.adSets = tc.AdSets
+			if client.adSets == nil {
+				client.adSets = []graphObject{{ID: "as1", Status: statusActive}, {ID: "as2", Status: statusActive}}
+			}
+			client.ads = []graphObject{{ID: "ad1", Status: statusActive}, {ID: "ad2", Status: statusActive}, {ID: "ad3", Status: statusActive}}
+			s := tc.Store
+			if s == nil {
+				s = &store{}
+			}
+			s.t = t
+			s.userCampaigns = map[string][]string{"facebook": {"c1"}}
+			s.campaign = &entities.Campaign{ID: "c1", Status: tc.Status, Budget: "3000", StartTime: "start", EndTime: "end"}
+			f := &facebook{
+				client: client,
+				ctx:    context.Background(),
+				store:  s,
+				auth: &platformAuth{t: t, expected: &entities.Facebook{
+					AccessToken: "unicorn60",
+					AdAccounts:  []entities.AdAccount{{AccountID: "1234", ID: "act_1234", Currency: "USD"}},
+				}},
+				minimumAdSetBudgets: minimumAdSetBudgets,
+			}
+
+			c, err := tc.Operation(f)
+			if tc.Error != nil || tc.StoreError {
+				assert.Error(err)
+				if tc.Error != nil {
+					assert.Equal(tc.Error, err.(*logger.Error).Err)
+				}
+				assert.Nil(s.updated)
+				if tc.Expected != nil {
+					assert.Equal(tc.Expected, client.updates)
+				}
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.Expected, client.updates)
+			assert.Equal(tc.Campaign, c)
+			assert.Equal(tc.Campaign, s.updated)
+		})
+	}
+}
+
+func TestLifecycleReplay(t *testing.T) {
+	assert := assert.New(t)
+	client, r := replayClient(t, "pause")
+	s := &store{
+		t:             t,
+		userCampaigns: map[string][]string{"facebook": {"c1"}},
+		campaign:      &entities.Campaign{ID: "c1", Budget: "3000", StartTime: "start", EndTime: "end"},
+	}
+	f := &facebook{
+		client: client,
+		ctx:    context.Background(),
+		store:  s,
+		auth:   &platformAuth{t: t, expected: &entities.Facebook{AccessToken: "unicorn60"}},
+	}
+
+	c, err := f.Pause("andres", "c1")
+	if !assert.Nil(err) {
+		return
+	}
+	expected := &entities.Campaign{ID: "c1", Status: statusPaused, Budget: "3000", StartTime: "start", EndTime: "end"}
+	assert.Equal(expected, c)
+	assert.Equal(expected, s.updated)
+	if !*record {
+		assert.Equal(0, r.Remaining())
+	}
+}
@@ -0,0 +1,188 @@
+package campaign
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/backend/core/facebook/internal"
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/logger"
+)
+
+var (
+	errUnknownMetric = errors.New("Unknown insight metric")
+	errMissingField  = errors.New("The insights row is missing a required field")
+)
+
+// metric is an ad set insight that can be used as an objective of
+// the multi objective optimization
+type metric struct {
+	// field is the insights field requested to the graph api
+	field string
+	// maximize is false for costs, their value is negated
+	// because every objective of the optimization is maximized
+	maximize bool
+	// conversions is true for the metrics that count the conversion event
+	conversions bool
+	// value parses the metric from an insights row
+	value func(row map[string]json.RawMessage) (float64, error)
+}
+
+// metrics supported as optimization objectives
+var metrics = map[string]metric{
+	"reach":       {field: "reach", maximize: true, value: numericField("reach")},
+	"impressions": {field: "impressions", maximize: true, value: numericField("impressions")},
+	"clicks":      {field: "clicks", maximize: true, value: numericField("clicks")},
+	"ctr":         {field: "ctr", maximize: true, value: numericField("ctr")},
+	"unique_ctr":  {field: "unique_ctr", maximize: true, value: numericField("unique_ctr")},
+	"cpm":         {field: "cpm", maximize: false, value: numericField("cpm")},
+	"cpc":         {field: "cpc", maximize: false, value: numericField("cpc")},
+	"spend":       {field: "spend", maximize: false, value: numericField("spend")},
+	"conversions": {field: "actions", maximize: true, conversions: true},
+}
+
+// purchaseActions are the action types of the purchases in order of preference, the graph
+// api reports the pixel purchases in both so only the first one of a row is counted
+var purchaseActions = []string{"omni_purchase", "offsite_conversion.fb_pixel_purchase"}
+
+// conversionActions returns the action types counted as conversions,
+// the purchases are counted when the event is empty
+func conversionActions(event string) []string {
+	if event == "" {
+		return purchaseActions
+	}
+
+	return []string{event}
+}
+
+// parse returns the parser of the metric, the conversions count the actions of the event
+func (m metric) parse(event string) func(row map[string]json.RawMessage) (float64, error) {
+	if m.conversions {
+		return actionsField(m.field, conversionActions(event)...)
+	}
+
+	return m.value
+}
+
+// numericField parses an insights field sent as a string number, the field is required
+func numericField(field string) func(row map[string]json.RawMessage) (float64, error) {
+	return func(row map[string]json.RawMessage) (float64, error) {
+		raw, ok := row[field]
+		if !ok {
+			return 0.0, errMissingField
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0.0, err
+		}
+
+		return strconv.ParseFloat(s, 64)
+	}
+}
+
+// actionsField returns the value of the first of the action types that the row has, the
+// types are alternatives that report the same actions so their values aren't added
+func actionsField(field string, types ...string) func(row map[string]json.RawMessage) (float64, error) {
+	return func(row map[string]json.RawMessage) (float64, error) {
+		raw, ok := row[field]
+		if !ok {
+			return 0.0, nil
+		}
+		actions := []struct {
+			ActionType string `json:"action_type"`
+			Value      string `json:"value"`
+		}{}
+		if err := json.Unmarshal(raw, &actions); err != nil {
+			return 0.0, err
+		}
+		values := map[string]string{}
+		for _, a := range actions {
+			values[a.ActionType] = a.Value
+		}
+		for _, t := range types {
+			if v, ok := values[t]; ok {
+				return strconv.ParseFloat(v, 64)
+			}
+		}
+
+		return 0.0, nil
+	}
+}
+
+// checkObjectives validates the names of the requested objectives
+func checkObjectives(names []string) error {
+	for _, name := range names {
+		if _, ok := metrics[name]; !ok {
+			return errUnknownMetric
+		}
+	}
+
+	return nil
+}
+
+// objectives returns a function that computes the vector of the requested
+// metrics of an ad set for the multi objective optimization
+func (qu *q) objectives(names []string) func(c *genetic.Chromosome) ([]float64, error) {
+	return func(c *genetic.Chromosome) ([]float64, error) {
+		if qu.accessToken == "" {
+			return nil, errMissingAccessToken
+		}
+
+		var (
+			data   = []map[string]json.RawMessage{}
+			fields = []string{}
+		)
+		for _, name := range names {
+			fields = append(fields, metrics[name].field)
+		}
+
+		uV := url.Values{}
+		uV.Add("access_token", qu.accessToken)
+		uV.Add("date_preset", "lifetime")
+		uV.Add("fields", strings.Join(fields, ","))
+		u := internal.SetURL(fmt.Sprintf("%s/insights", c.ID), uV)
+		err := internal.List(qu.ctx, qu.client, u, &data)
+		if err != nil {
+			return nil, internal.ListError(err,
+				"Unable to perform request to get the objectives of an adset.",
+				"Response of an adset objectives contained an error.",
+				nil)
+		}
+
+		o := make([]float64, len(names))
+		for i, name := range names {
+			m := metrics[name]
+			value := m.parse(qu.conversionEvent)
+			// an ad set without data is the worst
+			// possible solution for every objective
+			if len(data) == 0 {
+				if !m.maximize {
+					o[i] = -math.MaxFloat64
+				}
+				continue
+			}
+			for _, row := range data {
+				v, err := value(row)
+				if err != nil {
+					return nil, &logger.Error{
+						Level:   "Error",
+						Message: "Unable to parse objective response data as a float.",
+						Err:     err,
+					}
+				}
+				o[i] += v
+			}
+			o[i] /= float64(len(data))
+			if !m.maximize {
+				o[i] = -o[i]
+			}
+		}
+
+		return o, nil
+	}
+}
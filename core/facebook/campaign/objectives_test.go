@@ -0,0 +1,199 @@
+package campaign
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type insightsClient struct {
+	// fail the request
+	failRequest bool
+	// response body of the insights request
+	response string
+
+	server.Client
+	t *testing.T
+}
+
+func (c *insightsClient) Get(u string) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	if !strings.HasSuffix(requestURL.Path, "/insights") {
+		c.t.Fatalf("Unexpected request path: %s", requestURL.Path)
+	}
+	if c.failRequest {
+		return nil, errFailRequest
+	}
+
+	w := httptest.NewRecorder()
+	io.WriteString(w, c.response)
+
+	return w.Result(), nil
+}
+
+func TestObjectives(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Objectives  []string
+		Client      *insightsClient
+		AccessToken string
+		Expected    []float64
+		Error       bool
+	}{
+		{
+			Name:       "Reach Against Cost",
+			Objectives: []string{"reach", "cpm", "conversions"},
+			Client: &insightsClient{
+				response: `{"data":[{"reach":"1000","cpm":"2.5","actions":[{"action_type":"offsite_conversion.fb_pixel_purchase","value":"3"},{"action_type":"link_click","value":"40"}]}]}`,
+			},
+			AccessToken: "1234",
+			Expected:    []float64{1000, -2.5, 3},
+		},
+		{
+			Name:       "Ad Set Without Data",
+			Objectives: []string{"reach", "cpm"},
+			Client: &insightsClient{
+				response: `{"data":[]}`,
+			},
+			AccessToken: "1234",
+			Expected:    []float64{0, -math.MaxFloat64},
+		},
+		{
+			Name:        "Missing Access Token",
+			Objectives:  []string{"reach"},
+			Client:      &insightsClient{},
+			AccessToken: "",
+			Error:       true,
+		},
+		{
+			Name:       "Failing Request",
+			Objectives: []string{"reach"},
+			Client: &insightsClient{
+				failRequest: true,
+			},
+			AccessToken: "1234",
+			Error:       true,
+		},
+		{
+			Name:       "Facebook Error",
+			Objectives: []string{"reach"},
+			Client: &insightsClient{
+				response: `{"error":{"message":"failing operation"}}`,
+			},
+			AccessToken: "1234",
+			Error:       true,
+		},
+		{
+			Name:       "Missing Field",
+			Objectives: []string{"reach", "cpm"},
+			Client: &insightsClient{
+				response: `{"data":[{"reach":"1000"}]}`,
+			},
+			AccessToken: "1234",
+			Error:       true,
+		},
+		{
+			Name:       "Invalid Data",
+			Objectives: []string{"reach"},
+			Client: &insightsClient{
+				response: `{"data":[{"reach":"many"}]}`,
+			},
+			AccessToken: "1234",
+			Error:       true,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Client.t = t
+			qu := quality(func(qu *q) {
+				qu.client = tc.Client
+				qu.accessToken = tc.AccessToken
+			})
+			o, err := qu.objectives(tc.Objectives)(&genetic.Chromosome{ID: "1234"})
+			if tc.Error {
+				assert.Error(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.Expected, o)
+		})
+	}
+}
+
+func TestActionsField(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Row      string
+		Types    []string
+		Expected float64
+		Error    bool
+	}{
+		{
+			Name:     "Preferred Type",
+			Row:      `{"actions":[{"action_type":"offsite_conversion.fb_pixel_purchase","value":"3"},{"action_type":"omni_purchase","value":"3"}]}`,
+			Types:    purchaseActions,
+			Expected: 3,
+		},
+		{
+			Name:     "Fallback Type",
+			Row:      `{"actions":[{"action_type":"offsite_conversion.fb_pixel_purchase","value":"3"}]}`,
+			Types:    purchaseActions,
+			Expected: 3,
+		},
+		{
+			Name:     "Exact Type",
+			Row:      `{"actions":[{"action_type":"offsite_conversion.fb_pixel_add_to_cart","value":"7"},{"action_type":"offsite_conversion.fb_pixel_view_content","value":"40"}]}`,
+			Types:    conversionActions("offsite_conversion.fb_pixel_add_to_cart"),
+			Expected: 7,
+		},
+		{
+			Name:     "Missing Type",
+			Row:      `{"actions":[{"action_type":"link_click","value":"40"}]}`,
+			Types:    purchaseActions,
+			Expected: 0,
+		},
+		{
+			Name:     "Missing Field",
+			Row:      `{}`,
+			Types:    purchaseActions,
+			Expected: 0,
+		},
+		{
+			Name:  "Invalid Value",
+			Row:   `{"actions":[{"action_type":"omni_purchase","value":"many"}]}`,
+			Types: purchaseActions,
+			Error: true,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			row := map[string]json.RawMessage{}
+			if err := json.Unmarshal([]byte(tc.Row), &row); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			v, err := actionsField("actions", tc.Types...)(row)
+			if tc.Error {
+				assert.Error(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.Expected, v)
+		})
+	}
+}
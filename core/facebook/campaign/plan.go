@@ -0,0 +1,159 @@
+package campaign
+
+import (
+	"fmt"
+	"sort"
+
+	"bitbucket.org/backend/core/genetic"
+)
+
+// ids of the objects that would be created by the plan, the ads
+// reference their adset as they do in the batch request
+const (
+	planCampaignID = "{campaign}"
+	planCreativeID = "{creative}"
+)
+
+// Plan is the result of a dry run of the creation of a campaign, it has the
+// payloads Create would send to facebook without the user access token and
+// with placeholders for the ids of the objects that would be created
+type Plan struct {
+	Campaign newCampaign    `json:"campaign"`
+	AdSets   []PlanAdSet    `json:"adsets"`
+	Creative newCreative    `json:"creative"`
+	Diff     PopulationDiff `json:"diff"`
+}
+
+// PlanAdSet is an ad set of the plan and its ad
+type PlanAdSet struct {
+	// ID is the id of the segment ad set kept as an elite,
+	// it's empty for the offspring
+	ID        string     `json:"id,omitempty"`
+	Targeting *targeting `json:"targeting"`
+	Budget    string     `json:"budget,omitempty"`
+	AdSet     newAdSet   `json:"adset"`
+	Ad        newAd      `json:"ad"`
+}
+
+// PopulationDiff compares the planned population with the segment population
+type PopulationDiff struct {
+	// Kept are the segment ad sets selected as elites
+	Kept []string `json:"kept"`
+	// Dropped are the segment ad sets that aren't in the planned population
+	Dropped []string `json:"dropped"`
+	// Offspring is the number of new chromosomes
+	Offspring int `json:"offspring"`
+	// Added and Removed are the targeting genes of the planned
+	// population that the segment doesn't target and vice versa
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// Plan runs the creation of a campaign without creating any object, the segment
+// population is optimized and every payload is built but nothing is posted to
+// facebook and the segment isn't updated
+func (f *facebook) Plan(userID string, req *Request) (*Plan, error) {
+	cr, err := f.prepare(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	previousPopulation, newPopulation, budgets, err := f.populate(userID, req, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{
+		Campaign: f.campaignPayload(req.Name, "", req.Objective, cr.campaignBudget, req.SpecialAdCategory),
+		AdSets:   make([]PlanAdSet, len(newPopulation)),
+		Creative: f.creativePayload(req, ""),
+		Diff:     f.populationDiff(previousPopulation, newPopulation),
+	}
+	previous := map[string]bool{}
+	for _, c := range previousPopulation {
+		previous[c.ID] = true
+	}
+	for i, c := range newPopulation {
+		var budget string
+		if budgets != nil {
+			budget = budgets[i]
+		}
+		t := f.adSetTargeting(c, req.Location, req.Gender, req.AgeMin, req.AgeMax)
+		adSet, err := f.adSetPayload(planCampaignID, req.Objective, promotedObjectID(req), req.StartTime, cr.endTime, "", budget, t)
+		if err != nil {
+			return nil, err
+		}
+		ad, err := f.adPayload(fmt.Sprintf(adSetReference, i), planCreativeID, "")
+		if err != nil {
+			return nil, err
+		}
+		p.AdSets[i] = PlanAdSet{
+			Targeting: t,
+			Budget:    budget,
+			AdSet:     adSet,
+			Ad:        ad,
+		}
+		if c.ID != "" && previous[c.ID] {
+			p.AdSets[i].ID = c.ID
+		}
+	}
+
+	return p, nil
+}
+
+// populationDiff returns the ad sets and targeting genes that change between the populations
+func (f *facebook) populationDiff(previous, population []*genetic.Chromosome) PopulationDiff {
+	var (
+		d    = PopulationDiff{Kept: []string{}, Dropped: []string{}}
+		kept = map[string]bool{}
+	)
+	ids := map[string]bool{}
+	for _, c := range previous {
+		ids[c.ID] = true
+	}
+	for _, c := range population {
+		// the offspring don't have an id until their ad set is created
+		if c.ID != "" && ids[c.ID] && !kept[c.ID] {
+			kept[c.ID] = true
+			d.Kept = append(d.Kept, c.ID)
+			continue
+		}
+		d.Offspring++
+	}
+	for _, c := range previous {
+		if !kept[c.ID] {
+			d.Dropped = append(d.Dropped, c.ID)
+		}
+	}
+
+	before, after := f.targetedGenes(previous), f.targetedGenes(population)
+	d.Added, d.Removed = difference(after, before), difference(before, after)
+
+	return d
+}
+
+// targetedGenes returns the ids of the genes targeted by any chromosome of the population
+func (f *facebook) targetedGenes(population []*genetic.Chromosome) map[string]bool {
+	genes := map[string]bool{}
+	for _, c := range population {
+		for _, byType := range f.selection.Genesis(c) {
+			for _, g := range byType {
+				genes[g.ID] = true
+			}
+		}
+	}
+
+	return genes
+}
+
+// difference returns the sorted keys of a that aren't in b
+func difference(a, b map[string]bool) []string {
+	keys := []string{}
+	for k := range a {
+		if !b[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
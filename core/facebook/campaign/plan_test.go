@@ -0,0 +1,195 @@
+package campaign
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/server"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type planClient struct {
+	server.Client
+	t *testing.T
+}
+
+func (c *planClient) Post(u string, body io.Reader) (*http.Response, error) {
+	c.t.Fatalf("Unexpected post request on a dry run: %s", u)
+	return nil, nil
+}
+
+func testPlanRequest() *Request {
+	return &Request{
+		Name:              "testing C",
+		Objective:         "PAGE_LIKES",
+		Budget:            "3000",
+		SpecialAdCategory: []string{},
+		Segment:           "techUnicorn",
+		MutationRate:      0.01,
+		StartTime:         time.Now().String(),
+		EndTime:           "2030-10-08T10:00:00-0500",
+		Location: geolocation{
+			Countries: []string{"CO"},
+		},
+		Gender:       [2]int{1, 1},
+		AgeMax:       45,
+		AgeMin:       25,
+		Page:         entities.Page{ID: "trinacia official"},
+		CreativeName: "first campaign",
+		ImageHash:    "123412341234123",
+		Message:      "start now!",
+		CallToAction: callToAction{
+			Type: "LIKE_PAGE",
+			Value: callToActionValue{
+				Page: "trinacia official",
+			},
+		},
+		AdAccount: "act_1",
+	}
+}
+
+func TestPlan(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Config         []func(*facebook)
+		CampaignBudget string
+		// Budgets is true when the ad sets have their own budget
+		Budgets bool
+	}{
+		{
+			Name:           "Campaign Budget",
+			CampaignBudget: "3000",
+		},
+		{
+			Name:    "Ad Set Budgets",
+			Config:  []func(*facebook){AdSetBudgets(0.2)},
+			Budgets: true,
+		},
+	}
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := &helper{
+				expectedAuth: &entities.Facebook{
+					ID:          "1234",
+					AccessToken: "unicorn60",
+					AdAccounts:  []entities.AdAccount{{ID: "act_1", AccountID: "1", Currency: "USD"}},
+				},
+				expectedSegment: testSegment(),
+				t:               t,
+			}
+			f := New(sess, append([]func(*facebook){h.testConfig}, tc.Config...)...).(*facebook)
+			f.client = &planClient{t: t}
+			s := f.store.(*store)
+
+			p, err := f.Plan("andres", testPlanRequest())
+			if !assert.Nil(err) {
+				return
+			}
+			assert.Empty(s.setSegments)
+
+			assert.Equal("", p.Campaign.Token)
+			assert.Equal(tc.CampaignBudget, p.Campaign.DailyBudget)
+			assert.Equal("", p.Creative.AccessToken)
+			assert.Equal("trinacia official", p.Creative.ObjectStroySpec.PageID)
+
+			if !assert.Len(p.AdSets, defaultPopulationSize) {
+				return
+			}
+			var total int
+			for i, adSet := range p.AdSets {
+				assert.Equal(planCampaignID, adSet.AdSet.CampaignID)
+				assert.Equal("trinacia official", adSet.AdSet.PromotedObject.PageID)
+				assert.Equal("", adSet.AdSet.AccessToken)
+				assert.Equal(adSet.Targeting, adSet.AdSet.Targeting)
+				assert.Equal(adSet.Budget, adSet.AdSet.DailyBudget)
+				assert.Equal("2030-10-08T15:00:00Z", adSet.AdSet.EndTime)
+				assert.Equal(fmt.Sprintf(adSetReference, i), adSet.Ad.AdsetID)
+				assert.Equal(planCreativeID, adSet.Ad.Creative.CreativeID)
+				assert.Equal(tc.Budgets, adSet.Budget != "")
+
+				var b int
+				fmt.Sscan(adSet.Budget, &b)
+				total += b
+			}
+			if tc.Budgets {
+				assert.Equal(3000, total)
+			}
+			// the elites keep the ids of the segment ad sets
+			elites := []string{}
+			for _, adSet := range p.AdSets[:defaultEliteCount] {
+				elites = append(elites, adSet.ID)
+				assert.Len(adSet.Targeting.LifeEvents, 1)
+			}
+			assert.ElementsMatch([]string{"1", "2", "3", "4", "5"}, elites)
+			assert.ElementsMatch([]string{"1", "2", "3", "4", "5"}, p.Diff.Kept)
+			assert.Empty(p.Diff.Dropped)
+			assert.Equal(defaultPopulationSize-defaultEliteCount, p.Diff.Offspring)
+		})
+	}
+}
+
+func TestPopulationDiff(t *testing.T) {
+	var chromosome = func(id string, genes ...string) *genetic.Chromosome {
+		root := &genetic.Gene{}
+		for _, g := range genes {
+			root.Children = append(root.Children, &genetic.Gene{ID: g, Value: 1, Type: "interests"})
+		}
+
+		return &genetic.Chromosome{ID: id, Root: root}
+	}
+	cases := []struct {
+		Name       string
+		Previous   []*genetic.Chromosome
+		Population []*genetic.Chromosome
+		Expected   PopulationDiff
+	}{
+		{
+			Name:       "Elites And Offspring",
+			Previous:   []*genetic.Chromosome{chromosome("1", "a", "b"), chromosome("2", "c"), chromosome("3", "d")},
+			Population: []*genetic.Chromosome{chromosome("1", "a", "b"), chromosome("3", "d"), chromosome("", "a", "e"), chromosome("", "d")},
+			Expected: PopulationDiff{
+				Kept:      []string{"1", "3"},
+				Dropped:   []string{"2"},
+				Offspring: 2,
+				Added:     []string{"e"},
+				Removed:   []string{"c"},
+			},
+		},
+		{
+			Name:       "Empty Segment",
+			Previous:   []*genetic.Chromosome{},
+			Population: []*genetic.Chromosome{chromosome("", "a")},
+			Expected: PopulationDiff{
+				Kept:      []string{},
+				Dropped:   []string{},
+				Offspring: 1,
+				Added:     []string{"a"},
+				Removed:   []string{},
+			},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			f := &facebook{selection: genetic.New(nil)}
+			assert.Equal(tc.Expected, f.populationDiff(tc.Previous, tc.Population))
+		})
+	}
+}
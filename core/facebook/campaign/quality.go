@@ -1,12 +1,12 @@
 package campaign
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/url"
-	"strconv"
+	"strings"
 	"time"
 
 	"bitbucket.org/backend/core/facebook/internal"
@@ -17,16 +17,143 @@ import (
 
 var (
 	errMissingAccessToken = errors.New("The access token hasn't been set to the quality struct")
+	errUnknownQuality     = errors.New("Unknown quality function")
 )
 
 type q struct {
 	client      server.Client
+	ctx         context.Context
 	accessToken string
+	// function used to compute the quality of the ad sets
+	function qualityFunction
+	// conversionEvent is the action type counted as a conversion
+	conversionEvent string
+}
+
+// qualityFunction computes the quality of an ad set from a row of its insights
+type qualityFunction struct {
+	// fields are the insights fields required by the function
+	fields []string
+	// actions are the action types counted by the function
+	actions []string
+	// conversions is true for the functions that count the conversion event
+	conversions bool
+	// value computes the quality of a row of insights
+	// covering the provided number of days
+	value func(fn qualityFunction, row map[string]json.RawMessage, days float64) (float64, error)
+}
+
+const (
+	defaultQuality = "reach_ctr_cpm"
+)
+
+// qualityFunctions is the registry of quality functions
+var qualityFunctions = map[string]qualityFunction{
+	// reach weighted by unique ctr per unit of cpm and day, an ad
+	// set without cpm or without a whole day has no quality
+	defaultQuality: {
+		fields: []string{"reach", "unique_ctr", "cpm"},
+		value: func(fn qualityFunction, row map[string]json.RawMessage, days float64) (float64, error) {
+			reach, err := numericField("reach")(row)
+			if err != nil {
+				return 0.0, err
+			}
+			uniqueCTR, err := numericField("unique_ctr")(row)
+			if err != nil {
+				return 0.0, err
+			}
+			cpm, err := numericField("cpm")(row)
+			if err != nil {
+				return 0.0, err
+			}
+			if cpm == 0 || days == 0 {
+				return 0.0, nil
+			}
+
+			return (reach * uniqueCTR / cpm) / days, nil
+		},
+	},
+	// conversions per unit of spend, the inverse of the cost per conversion
+	"cost_per_conversion": {
+		fields:      []string{"spend", "actions"},
+		conversions: true,
+		value:       actionsPerSpend("actions"),
+	},
+	// return on ad spend, value of the conversion event per unit
+	// of spend, the purchases value when there isn't an event
+	"roas": {
+		fields:      []string{"spend", "action_values"},
+		conversions: true,
+		value:       actionsPerSpend("action_values"),
+	},
+	// page likes per unit of spend, the inverse of the cost per like
+	"cost_per_like": {
+		fields:  []string{"spend", "actions"},
+		actions: []string{"like"},
+		value:   actionsPerSpend("actions"),
+	},
+}
+
+// objectiveQuality is the default quality function of each campaign objective,
+// objectives that aren't listed use the default quality function
+var objectiveQuality = map[string]string{
+	"CONVERSIONS": "cost_per_conversion",
+	"PAGE_LIKES":  "cost_per_like",
+}
+
+// actionsPerSpend divides the value of the function actions in field
+// by the spend, an ad set without spend has no quality
+func actionsPerSpend(field string) func(fn qualityFunction, row map[string]json.RawMessage, days float64) (float64, error) {
+	return func(fn qualityFunction, row map[string]json.RawMessage, days float64) (float64, error) {
+		spend, err := numericField("spend")(row)
+		if err != nil {
+			return 0.0, err
+		}
+		if spend == 0 {
+			return 0.0, nil
+		}
+		actions, err := actionsField(field, fn.actions...)(row)
+		if err != nil {
+			return 0.0, err
+		}
+
+		return actions / spend, nil
+	}
+}
+
+// qualityByName returns the quality function of the request, the name has priority
+// over the campaign objective and the default function is used when neither match
+func qualityByName(name, objective string, objectives map[string]string) (qualityFunction, error) {
+	if name == "" {
+		name = objectives[objective]
+	}
+	if name == "" {
+		name = defaultQuality
+	}
+	fn, ok := qualityFunctions[name]
+	if !ok {
+		return qualityFunction{}, errUnknownQuality
+	}
+
+	return fn, nil
+}
+
+// stringField returns an insights field sent as a string, a missing or
+// invalid field is an empty string that fails the parsing of its value
+func stringField(row map[string]json.RawMessage, field string) string {
+	var s string
+	if err := json.Unmarshal(row[field], &s); err != nil {
+		return ""
+	}
+
+	return s
 }
 
 func quality(config ...func(*q)) *q {
 	q := &q{
-		client: server.New(),
+		client:   server.New(),
+		ctx:      context.Background(),
+		function: qualityFunctions[defaultQuality],
 	}
 
 	for _, fn := range config {
@@ -36,73 +163,59 @@ func quality(config ...func(*q)) *q {
 	return q
 }
 
+// withContext returns a copy of the quality whose insights requests are cancelled with the context
+func (qu *q) withContext(ctx context.Context) *q {
+	qc := *qu
+	qc.ctx = ctx
+	qc.client = server.WithContext(ctx, qu.client)
+
+	return &qc
+}
+
+// request returns a copy of the quality that computes the function of a campaign
+// request with the access token of the user
+func (qu *q) request(accessToken, conversionEvent string, fn qualityFunction) *q {
+	qc := *qu
+	qc.accessToken = accessToken
+	qc.conversionEvent = conversionEvent
+	qc.function = fn
+
+	return &qc
+}
+
 func (qu *q) compute(c *genetic.Chromosome) (float64, error) {
 	if qu.accessToken == "" {
 		return 0.0, errMissingAccessToken
 	}
 
 	const timeFormat = "2006-01-02"
-	var (
-		result = struct {
-			Data []struct {
-				Reach     string `json:"reach"`
-				Cpm       string `json:"cpm"`
-				UniqueCTR string `json:"unique_ctr"`
-				DateStart string `json:"date_start"`
-				DateStop  string `json:"date_stop"`
-			} `json:"data"`
-			Error *internal.FacebookError `json:"error"`
-		}{}
-	)
+	var data = []map[string]json.RawMessage{}
 
 	uV := url.Values{}
 	uV.Add("access_token", qu.accessToken)
 	uV.Add("date_preset", "lifetime")
-	uV.Add("fields", "reach,unique_ctr,cpm")
+	uV.Add("fields", strings.Join(qu.function.fields, ","))
 	u := internal.SetURL(fmt.Sprintf("%s/insights", c.ID), uV)
-
-	resp, err := qu.client.Get(u)
-	if err != nil {
-		return 0.0, &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to perform request to get quality of an adset.",
-			Err:     err,
-		}
-	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0.0, &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to read response to get quality of an adset.",
-			Err:     err,
-		}
-	}
-	err = json.Unmarshal(b, &result)
+	err := internal.List(qu.ctx, qu.client, u, &data)
 	if err != nil {
-		return 0.0, &logger.Error{
-			Level:   "Panic",
-			Message: "Unable to unamarshal response to get a quality of an adset.",
-			Err:     err,
-		}
-	}
-
-	if result.Error != nil {
-		return 0.0, &logger.Error{
-			Level:   "Error",
-			Message: "Response of an adset quality contained an error.",
-			Err:     result.Error,
-		}
+		return 0.0, internal.ListError(err,
+			"Unable to perform request to get quality of an adset.",
+			"Response of an adset quality contained an error.",
+			nil)
 	}
 
-	if len(result.Data) == 0 {
+	if len(data) == 0 {
 		return 0.0, nil
 	}
 
+	fn := qu.function
+	if fn.conversions {
+		fn.actions = conversionActions(qu.conversionEvent)
+	}
 	var q float64
 
-	for _, d := range result.Data {
-		startTime, err := time.Parse(timeFormat, d.DateStart)
+	for _, d := range data {
+		startTime, err := time.Parse(timeFormat, stringField(d, "date_start"))
 		if err != nil {
 			return 0.0, &logger.Error{
 				Level:   "Error",
@@ -110,7 +223,7 @@ func (qu *q) compute(c *genetic.Chromosome) (float64, error) {
 				Err:     err,
 			}
 		}
-		endTime, err := time.Parse(timeFormat, d.DateStop)
+		endTime, err := time.Parse(timeFormat, stringField(d, "date_stop"))
 		if err != nil {
 			return 0.0, &logger.Error{
 				Level:   "Error",
@@ -118,23 +231,7 @@ func (qu *q) compute(c *genetic.Chromosome) (float64, error) {
 				Err:     err,
 			}
 		}
-		reach, err := strconv.ParseFloat(d.Reach, 64)
-		if err != nil {
-			return 0.0, &logger.Error{
-				Level:   "Error",
-				Message: "Unable to parse quality response data as a float.",
-				Err:     err,
-			}
-		}
-		uniqueCTR, err := strconv.ParseFloat(d.UniqueCTR, 64)
-		if err != nil {
-			return 0.0, &logger.Error{
-				Level:   "Error",
-				Message: "Unable to parse quality response data as a float.",
-				Err:     err,
-			}
-		}
-		cpm, err := strconv.ParseFloat(d.Cpm, 64)
+		v, err := fn.value(fn, d, endTime.Sub(startTime).Hours()/24)
 		if err != nil {
 			return 0.0, &logger.Error{
 				Level:   "Error",
@@ -143,10 +240,10 @@ func (qu *q) compute(c *genetic.Chromosome) (float64, error) {
 			}
 		}
 
-		q += (reach * uniqueCTR / cpm) / (endTime.Sub(startTime).Hours() / 24)
+		q += v
 	}
 
-	q /= float64(len(result.Data))
+	q /= float64(len(data))
 
 	return q, nil
 }
@@ -1,11 +1,23 @@
 package campaign
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
+	"bitbucket.org/backend/core/genetic"
 	"bitbucket.org/backend/core/server"
+	"github.com/stretchr/testify/assert"
 )
 
+var errFailBodyRead = errors.New("failing body read")
+
 type qualityClient struct {
 	// fail the request
 	failRequest bool
@@ -25,11 +37,267 @@ type qualityClient struct {
 	failReachParse     bool
 	failUniqueCTRParse bool
 	failCMPParse       bool
+	// zeroCPM is used to return an adset without cost
+	zeroCPM bool
+	// missingCPM is used to return an adset without the cpm field
+	missingCPM bool
+
+	// fields requested to the insights endpoint
+	fields string
 
 	server.Client
 	t *testing.T
 }
 
+type failReader struct{}
+
+func (failReader) Read(p []byte) (int, error) {
+	return 0, errFailBodyRead
+}
+
+func (c *qualityClient) Get(u string) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	if !strings.HasSuffix(requestURL.Path, "/insights") {
+		c.t.Fatalf("Unexpected request path: %s", requestURL.Path)
+	}
+	c.fields = requestURL.Query().Get("fields")
+
+	switch {
+	case c.failRequest:
+		return nil, errFailRequest
+	case c.failBodyRead:
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(failReader{}),
+		}, nil
+	}
+
+	w := httptest.NewRecorder()
+	switch {
+	case c.failUnmarshal:
+		io.WriteString(w, `{"data":[}`)
+	case c.fail:
+		io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+	default:
+		var (
+			dateStart = "2020-10-01"
+			dateStop  = "2020-10-11"
+			reach     = "1000"
+			uniqueCTR = "2"
+			cpm       = `"cpm":"4",`
+		)
+		switch {
+		case c.zeroDays:
+			dateStop = dateStart
+		case c.failStartTimeParse:
+			dateStart = "yesterday"
+		case c.failEndTimeParse:
+			dateStop = "tomorrow"
+		case c.failReachParse:
+			reach = "many"
+		case c.failUniqueCTRParse:
+			uniqueCTR = "high"
+		case c.failCMPParse:
+			cpm = `"cpm":"cheap",`
+		case c.zeroCPM:
+			cpm = `"cpm":"0",`
+		case c.missingCPM:
+			cpm = ""
+		}
+		io.WriteString(w, fmt.Sprintf(`{"data":[{
+			"reach":"%s",
+			"unique_ctr":"%s",
+			%s
+			"spend":"50",
+			"actions":[
+				{"action_type":"offsite_conversion.fb_pixel_purchase","value":"5"},
+				{"action_type":"omni_purchase","value":"5"},
+				{"action_type":"offsite_conversion.fb_pixel_add_to_cart","value":"7"},
+				{"action_type":"like","value":"20"},
+				{"action_type":"link_click","value":"100"}
+			],
+			"action_values":[
+				{"action_type":"offsite_conversion.fb_pixel_purchase","value":"150"},
+				{"action_type":"omni_purchase","value":"150"},
+				{"action_type":"offsite_conversion.fb_pixel_add_to_cart","value":"90"}
+			],
+			"date_start":"%s",
+			"date_stop":"%s"
+		}]}`, reach, uniqueCTR, cpm, dateStart, dateStop))
+	}
+
+	return w.Result(), nil
+}
+
 func TestQuality(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Client    *qualityClient
+		Quality   string
+		Objective string
+		Event     string
+		Fields    string
+		Expected  float64
+		Error     bool
+	}{
+		{
+			Name:     "Default Quality",
+			Fields:   "reach,unique_ctr,cpm",
+			Expected: 1000 * 2 / 4 / 10,
+		},
+		{
+			Name:      "Conversions Objective",
+			Objective: "CONVERSIONS",
+			Fields:    "spend,actions",
+			Expected:  5.0 / 50,
+		},
+		{
+			Name:      "Conversion Event",
+			Objective: "CONVERSIONS",
+			Event:     "offsite_conversion.fb_pixel_add_to_cart",
+			Fields:    "spend,actions",
+			Expected:  7.0 / 50,
+		},
+		{
+			Name:      "Page Likes Objective",
+			Objective: "PAGE_LIKES",
+			Fields:    "spend,actions",
+			Expected:  20.0 / 50,
+		},
+		{
+			Name:      "Return On Ad Spend Override",
+			Quality:   "roas",
+			Objective: "CONVERSIONS",
+			Fields:    "spend,action_values",
+			Expected:  150.0 / 50,
+		},
+		{
+			Name:      "Return On Ad Spend Of Conversion Event",
+			Quality:   "roas",
+			Objective: "CONVERSIONS",
+			Event:     "offsite_conversion.fb_pixel_add_to_cart",
+			Fields:    "spend,action_values",
+			Expected:  90.0 / 50,
+		},
+		{
+			Name:     "Zero Days",
+			Client:   &qualityClient{zeroDays: true},
+			Fields:   "reach,unique_ctr,cpm",
+			Expected: 0,
+		},
+		{
+			Name:     "Zero CPM",
+			Client:   &qualityClient{zeroCPM: true},
+			Fields:   "reach,unique_ctr,cpm",
+			Expected: 0,
+		},
+		{
+			Name:   "Missing CPM",
+			Client: &qualityClient{missingCPM: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Request",
+			Client: &qualityClient{failRequest: true},
+			Error:  true,
+		},
+		{
+			Name:   "Facebook Error",
+			Client: &qualityClient{fail: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Body Read",
+			Client: &qualityClient{failBodyRead: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Unmarshal",
+			Client: &qualityClient{failUnmarshal: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Start Time Parse",
+			Client: &qualityClient{failStartTimeParse: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing End Time Parse",
+			Client: &qualityClient{failEndTimeParse: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Reach Parse",
+			Client: &qualityClient{failReachParse: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing Unique CTR Parse",
+			Client: &qualityClient{failUniqueCTRParse: true},
+			Error:  true,
+		},
+		{
+			Name:   "Failing CPM Parse",
+			Client: &qualityClient{failCMPParse: true},
+			Error:  true,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := tc.Client
+			if c == nil {
+				c = &qualityClient{}
+			}
+			c.t = t
+			fn, err := qualityByName(tc.Quality, tc.Objective, objectiveQuality)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			qu := quality(func(qu *q) {
+				qu.client = c
+				qu.accessToken = "1234"
+				qu.function = fn
+				qu.conversionEvent = tc.Event
+			})
+
+			v, err := qu.compute(&genetic.Chromosome{ID: "1234"})
+			if tc.Error {
+				assert.Error(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.Fields, c.fields)
+			assert.InDelta(tc.Expected, v, 1e-9)
+		})
+	}
+
+	t.Run("Missing Access Token", func(t *testing.T) {
+		_, err := quality().compute(&genetic.Chromosome{ID: "1234"})
+		assert.Equal(errMissingAccessToken, err)
+	})
+
+	t.Run("Unknown Quality", func(t *testing.T) {
+		_, err := qualityByName("happiness", "CONVERSIONS", objectiveQuality)
+		assert.Equal(errUnknownQuality, err)
+	})
+
+	t.Run("Request Quality", func(t *testing.T) {
+		qu := quality()
+		a := qu.request("unicorn60", "purchase", qualityFunctions["cost_per_conversion"])
+		b := qu.request("pegasus", "", qualityFunctions["cost_per_like"])
 
+		// the requests don't change the shared quality nor each other
+		assert.Equal("", qu.accessToken)
+		assert.Equal(qualityFunctions[defaultQuality].fields, qu.function.fields)
+		assert.Equal("unicorn60", a.accessToken)
+		assert.Equal("purchase", a.conversionEvent)
+		assert.Equal(qualityFunctions["cost_per_conversion"].fields, a.function.fields)
+		assert.Equal("pegasus", b.accessToken)
+		assert.Equal(qualityFunctions["cost_per_like"].fields, b.function.fields)
+	})
 }
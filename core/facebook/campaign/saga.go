@@ -0,0 +1,140 @@
+package campaign
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"bitbucket.org/backend/core/facebook/internal"
+	"bitbucket.org/backend/core/logger"
+)
+
+const (
+	campaignObject = "campaign"
+	adSetObject    = "adset"
+	creativeObject = "creative"
+	adObject       = "ad"
+)
+
+// saga records the steps of the creation or update of a campaign so
+// they can be undone in reverse order when a later step fails
+type saga struct {
+	f           *facebook
+	accessToken string
+	steps       []sagaStep
+}
+
+type sagaStep struct {
+	name string
+	undo func() error
+}
+
+// newSaga returns the saga of a creation or update, the steps are undone with
+// the campaign interface that isn't bound to the context of the operation
+func (f *facebook) newSaga(accessToken string) *saga {
+	return &saga{
+		f:           f.detached(),
+		accessToken: accessToken,
+	}
+}
+
+// created records graph objects of the kind, they are deleted on rollback and
+// the objects with a status are paused when they can't be deleted
+func (s *saga) created(kind string, ids ...string) {
+	for _, id := range ids {
+		id := id
+		s.steps = append(s.steps, sagaStep{
+			name: fmt.Sprintf("%s %s", kind, id),
+			undo: func() error {
+				err := s.f.deleteObject(id, s.accessToken)
+				if err == nil || kind == creativeObject {
+					return err
+				}
+
+				return s.f.updateObject(id, s.accessToken, map[string]string{"status": statusPaused})
+			},
+		})
+	}
+}
+
+// compensate records the operation that undoes a completed step
+func (s *saga) compensate(name string, undo func() error) {
+	s.steps = append(s.steps, sagaStep{
+		name: name,
+		undo: undo,
+	})
+}
+
+// rollback undoes every recorded step in reverse order and returns the error that
+// caused it, when a step can't be undone the returned error has the failed steps
+func (s *saga) rollback(cause error) error {
+	failed := []string{}
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		if err := s.steps[i].undo(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", s.steps[i].name, err))
+		}
+	}
+	s.steps = nil
+	if len(failed) != 0 {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to roll back the changes of a campaign.",
+			Err:     cause,
+			Context: failed,
+		}
+	}
+
+	return cause
+}
+
+// deleteObject deletes a campaign, ad set, ad or ad creative
+func (f *facebook) deleteObject(id, accessToken string) error {
+	var (
+		result = struct {
+			Success bool                    `json:"success"`
+			Error   *internal.FacebookError `json:"error"`
+		}{}
+	)
+
+	uV := url.Values{}
+	uV.Add("access_token", accessToken)
+	req, err := f.client.SetRequest("DELETE", internal.SetURL(id, uV), nil)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to set request to delete a campaign object.",
+			Err:     err,
+		}
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to perform request to delete a campaign object.",
+			Err:     err,
+		}
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to read response to delete a campaign object.",
+			Err:     err,
+		}
+	}
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to unmarshal response to delete a campaign object.",
+			Err:     err,
+		}
+	}
+	if result.Error != nil {
+		return internal.ResponseError(result.Error, "Error", "Response to delete a campaign object contained an error.", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/logger"
+	"bitbucket.org/backend/core/server"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFailStep = errors.New("failing step")
+
+type sagaClient struct {
+	// fail the deletion of the objects with the ids
+	failDelete map[string]bool
+	// fail the update of the objects with the ids
+	failUpdate map[string]bool
+
+	// operations performed on the objects
+	operations []string
+
+	server.Client
+	t *testing.T
+}
+
+func (c *sagaClient) SetRequest(method, u string, body io.Reader) (*http.Request, error) {
+	return http.NewRequest(method, u, body)
+}
+
+func (c *sagaClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != "DELETE" {
+		c.t.Fatalf("Unexpected request method: %s", req.Method)
+	}
+	if req.URL.Query().Get("access_token") == "" {
+		c.t.Fatal("Missing access token to delete campaign object")
+	}
+
+	id := strings.TrimPrefix(req.URL.Path, "/v8.0/")
+	w := httptest.NewRecorder()
+	if c.failDelete[id] {
+		io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+		return w.Result(), nil
+	}
+	c.operations = append(c.operations, fmt.Sprintf("delete %s", id))
+	io.WriteString(w, `{"success":true}`)
+
+	return w.Result(), nil
+}
+
+func (c *sagaClient) Post(u string, body io.Reader) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		c.t.Fatal("Unable to read request body: ", err)
+	}
+	fields := map[string]string{}
+	testUmarshal(c.t, b, &fields)
+
+	id := strings.TrimPrefix(requestURL.Path, "/v8.0/")
+	w := httptest.NewRecorder()
+	if c.failUpdate[id] {
+		io.WriteString(w, `{"error":{"message":"failing operation"}}`)
+		return w.Result(), nil
+	}
+	c.operations = append(c.operations, fmt.Sprintf("%s %s", strings.ToLower(fields["status"]), id))
+	io.WriteString(w, `{"success":true}`)
+
+	return w.Result(), nil
+}
+
+func (c *createClient) SetRequest(method, u string, body io.Reader) (*http.Request, error) {
+	return http.NewRequest(method, u, body)
+}
+
+func (c *createClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != "DELETE" {
+		c.t.Fatalf("Unexpected request method: %s", req.Method)
+	}
+	c.deleted = append(c.deleted, strings.TrimPrefix(req.URL.Path, "/v8.0/"))
+	w := httptest.NewRecorder()
+	io.WriteString(w, `{"success":true}`)
+
+	return w.Result(), nil
+}
+
+func TestSaga(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Client     *sagaClient
+		Compensate error
+		Expected   []string
+		Failed     []string
+	}{
+		{
+			Name:     "Reverse Order",
+			Client:   &sagaClient{},
+			Expected: []string{"segment", "delete ad1", "delete ad2", "delete cr1", "delete as1", "delete as2", "delete c1"},
+		},
+		{
+			Name:     "Pause Undeletable Objects",
+			Client:   &sagaClient{failDelete: map[string]bool{"ad2": true, "c1": true}},
+			Expected: []string{"segment", "delete ad1", "paused ad2", "delete cr1", "delete as1", "delete as2", "paused c1"},
+		},
+		{
+			Name:     "Undeletable Creative",
+			Client:   &sagaClient{failDelete: map[string]bool{"cr1": true}},
+			Expected: []string{"segment", "delete ad1", "delete ad2", "delete as1", "delete as2", "delete c1"},
+			Failed:   []string{"creative cr1"},
+		},
+		{
+			Name: "Unable To Pause",
+			Client: &sagaClient{
+				failDelete: map[string]bool{"as1": true},
+				failUpdate: map[string]bool{"as1": true},
+			},
+			Compensate: errorFailStorage,
+			Expected:   []string{"delete ad1", "delete ad2", "delete cr1", "delete as2", "delete c1"},
+			Failed:     []string{"segment", "adset as1"},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Client.t = t
+			f := &facebook{client: tc.Client}
+
+			s := f.newSaga("unicorn60")
+			s.created(campaignObject, "c1")
+			s.created(adSetObject, "as2", "as1")
+			s.created(creativeObject, "cr1")
+			s.created(adObject, "ad2", "ad1")
+			s.compensate("segment", func() error {
+				if tc.Compensate != nil {
+					return tc.Compensate
+				}
+				tc.Client.operations = append(tc.Client.operations, "segment")
+
+				return nil
+			})
+
+			err := s.rollback(errFailStep)
+			assert.Equal(tc.Expected, tc.Client.operations)
+			if tc.Failed == nil {
+				assert.Equal(errFailStep, err)
+				return
+			}
+			rollbackErr, ok := err.(*logger.Error)
+			if !assert.True(ok) {
+				return
+			}
+			assert.Equal(errFailStep, rollbackErr.Err)
+			failed := rollbackErr.Context.([]string)
+			if assert.Len(failed, len(tc.Failed)) {
+				for i, name := range tc.Failed {
+					assert.True(strings.HasPrefix(failed[i], name+": "))
+				}
+			}
+		})
+	}
+}
+
+func testSegment() []*genetic.Chromosome {
+	population := []*genetic.Chromosome{}
+	for i := 1; i <= 5; i++ {
+		population = append(population, &genetic.Chromosome{
+			ID:      fmt.Sprint(i),
+			Quality: float64(i) / 10,
+			Root: &genetic.Gene{
+				Children: []*genetic.Gene{
+					{
+						ID:    fmt.Sprintf("interest%d", i),
+						Value: 1,
+						Type:  "interests",
+					},
+				},
+			},
+		})
+	}
+
+	return population
+}
+
+func TestCreateRollback(t *testing.T) {
+	cases := []struct {
+		Name string
+		// Fail sets the client and storage failures
+		Fail func(c *createClient, s *store)
+		// Deleted is the number of deleted objects, the campaign is always the last one
+		Deleted int
+		// Restored is true when the segment population is set back after a failure
+		Restored bool
+		// Cancel cancels the context of the creation once the creative is created
+		Cancel bool
+	}{
+		{
+			Name: "Failing Batch",
+			Fail: func(c *createClient, s *store) { c.failCreateAdsetRequest = true },
+			// campaign and creative
+			Deleted: 2,
+		},
+		{
+			Name:    "Failing Ad Sets",
+			Fail:    func(c *createClient, s *store) { c.failAdset = true },
+			Deleted: 2,
+		},
+		{
+			Name: "Failing Ads",
+			Fail: func(c *createClient, s *store) { c.failAd = true },
+			// campaign, creative and adsets
+			Deleted: 1 + 1 + defaultPopulationSize,
+		},
+		{
+			Name: "Failing Segment",
+			Fail: func(c *createClient, s *store) { s.failtSetSegment = true },
+			// campaign, creative, adsets and ads
+			Deleted: 1 + defaultPopulationSize + 1 + defaultPopulationSize,
+		},
+		{
+			Name: "Canceled Context",
+			Fail: func(c *createClient, s *store) {},
+			// the ad sets aren't created after the cancellation
+			Cancel:  true,
+			Deleted: 2,
+		},
+		{
+			Name:     "Failing Campaign Storage",
+			Fail:     func(c *createClient, s *store) { s.failStoreCampaign = true },
+			Deleted:  1 + defaultPopulationSize + 1 + defaultPopulationSize,
+			Restored: true,
+		},
+	}
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := &helper{
+				campaignID: "1234",
+				expectedAuth: &entities.Facebook{
+					ID:          "1234",
+					AccessToken: "unicorn60",
+					AdAccounts:  []entities.AdAccount{{ID: "act_1234123", AccountID: "1234123", Currency: "USD"}},
+				},
+				expectedSegment: testSegment(),
+				t:               t,
+			}
+			f := New(sess, h.testConfig).(*facebook)
+			client, s := f.client.(*createClient), f.store.(*store)
+			tc.Fail(client, s)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.Cancel {
+				client.cancel = cancel
+			}
+
+			_, err := f.WithContext(ctx).Create("andres", &Request{
+				Name:              "testing C",
+				Objective:         "LINK_CLICKS",
+				Budget:            "3000",
+				SpecialAdCategory: []string{},
+				Segment:           "techUnicorn",
+				MutationRate:      0.01,
+				StartTime:         time.Now().String(),
+				EndTime:           time.Now().Add(time.Hour * 365).String(),
+				Location: geolocation{
+					Countries: []string{"CO"},
+				},
+				Gender:       [2]int{1, 1},
+				AgeMax:       45,
+				AgeMin:       25,
+				Page:         entities.Page{ID: "trinacia official"},
+				CreativeName: "first campaign",
+				MediaURL:     "https://trinacia.com",
+				ImageHash:    "123412341234123",
+				Message:      "start now!",
+				CallToAction: callToAction{
+					Type: "BUY_NOW",
+					Value: callToActionValue{
+						Link: "https://trinacia.com",
+						Page: "trinacia official",
+					},
+				},
+				AdAccount: "act_1234123",
+			})
+			assert.Error(err)
+			assert.Equal(tc.Cancel, errors.Is(err, context.Canceled))
+
+			if assert.Len(client.deleted, tc.Deleted) {
+				assert.Equal("1234", client.deleted[len(client.deleted)-1])
+			}
+			if !tc.Restored {
+				assert.Empty(s.setSegments)
+				return
+			}
+			// the elites are restored with the ids of the previous adsets
+			if assert.Len(s.setSegments, 2) {
+				ids := []string{}
+				for _, c := range s.setSegments[1] {
+					ids = append(ids, c.ID)
+				}
+				assert.Equal([]string{"1", "2", "3", "4", "5"}, ids)
+			}
+		})
+	}
+}
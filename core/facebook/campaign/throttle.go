@@ -0,0 +1,33 @@
+package campaign
+
+import (
+	"strings"
+	"time"
+
+	"bitbucket.org/backend/core/facebook/internal"
+)
+
+// Usage is the percentage of a graph api rate limit used
+type Usage = internal.Usage
+
+// RateLimit is the usage of the graph api rate limits by the app and an ad
+// account, the runs of the optimizer should be postponed for the delay
+type RateLimit struct {
+	App       Usage         `json:"app"`
+	AdAccount Usage         `json:"ad_account"`
+	Delay     time.Duration `json:"delay"`
+}
+
+// RateLimit returns the current usage of the rate limits of the ad account
+func (f *facebook) RateLimit(adAccount string) *RateLimit {
+	if f.throttle == nil {
+		return &RateLimit{}
+	}
+	usage := f.throttle.Usage()
+
+	return &RateLimit{
+		App:       usage.App,
+		AdAccount: usage.AdAccounts[strings.TrimPrefix(adAccount, "act_")],
+		Delay:     f.throttle.Delay(adAccount),
+	}
+}
@@ -0,0 +1,84 @@
+package campaign
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/facebook/internal"
+	"bitbucket.org/backend/core/logger"
+	"bitbucket.org/backend/core/server"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// throttledClient returns the rate limit headers and a throttling error of an ad account
+type throttledClient struct {
+	server.Client
+}
+
+func (c *throttledClient) Get(u string) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-App-Usage", `{"call_count":40,"total_cputime":10,"total_time":20}`)
+	w.Header().Set("X-Ad-Account-Usage", `{"acc_id_util_pct":100,"reset_time_duration":600}`)
+	io.WriteString(w, `{"error":{"message":"User request limit reached","code":17}}`)
+
+	return w.Result(), nil
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	h := &helper{
+		expectedAuth: &entities.Facebook{
+			ID:          "1234",
+			AccessToken: "unicorn60",
+			AdAccounts:  []entities.AdAccount{{ID: "act_1", AccountID: "1", Currency: "USD"}},
+		},
+		expectedSegment: testSegment(),
+		t:               t,
+	}
+	f := New(sess, h.testConfig).(*facebook)
+	assert.Equal(&RateLimit{}, f.RateLimit("act_1"))
+
+	f.throttle = internal.NewThrottle(&throttledClient{})
+	_, err = f.throttle.Get(internal.SetURL("act_1/insights", nil))
+	assert.Nil(err)
+	rateLimit := f.RateLimit("act_1")
+	assert.Equal(Usage{CallCount: 40, TotalCPUTime: 10, TotalTime: 20}, rateLimit.App)
+	assert.Equal(100.0, rateLimit.AdAccount.Max())
+	assert.InDelta(10*time.Minute, rateLimit.Delay, float64(time.Second))
+	assert.Equal(time.Duration(0), f.RateLimit("act_2").Delay)
+
+	// the creation is postponed before any object is created
+	f.client = &planClient{t: t}
+	_, err = f.Create("andres", testPlanRequest())
+	if assert.IsType(&logger.Error{}, err) {
+		assert.Equal(internal.ErrorThrottled, err.(*logger.Error).Err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f := New(sess, Client(server.Timeout(time.Second))).(*facebook)
+	assert.NotNil(f.throttle)
+	assert.Equal(f.throttle, f.client)
+	assert.Equal(f.throttle, f.quality.client)
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+
+	"bitbucket.org/backend/core/server"
+)
+
+// MaxBatchSize is the maximum number of operations of a graph api batch request
+const MaxBatchSize = 50
+
+var (
+	// ErrorBatchReference an operation references the result of an operation
+	// that isn't performed before it in the same batch request
+	ErrorBatchReference = errors.New("Batch operation references an unknown operation")
+	// ErrorMissingBatchResponse the batch response doesn't have the result of an operation
+	ErrorMissingBatchResponse = errors.New("Batch response is missing the result of an operation")
+	// ErrorBatchResponse the batch request failed as a whole
+	ErrorBatchResponse = errors.New("Batch response doesn't contain the result of the operations")
+
+	// references to other operations results such as {result=create-adset:$.id}
+	batchReference = regexp.MustCompile(`\{result=([^:}]+):`)
+	// url encoded references are restored so facebook can resolve them
+	encodedReference = regexp.MustCompile(`%7Bresult%3D([^%]+)%3A%24(\.[^%]+)%7D`)
+)
+
+// BatchOperation is a request performed inside a batch request, the operations
+// after it in the same batch request can use its result through its name with
+// a reference such as {result=<name>:$.id} in their url or body
+type BatchOperation struct {
+	Method      string `json:"method"`
+	RelativeURL string `json:"relative_url"`
+	// Body is the url encoded body of the request, see BatchBody
+	Body string `json:"body,omitempty"`
+	Name string `json:"name,omitempty"`
+	// the result of the referenced operations is always returned
+	OmitResponseOnSuccess bool `json:"omit_response_on_success"`
+}
+
+// BatchResult is the response to an operation of a batch request
+type BatchResult struct {
+	Code int    `json:"code"`
+	Body string `json:"body"`
+}
+
+// Decode unmarshals the result body into out, the error of the response is
+// returned as a *FacebookError. A nil result is an operation without response
+func (r *BatchResult) Decode(out interface{}) error {
+	if r == nil {
+		return ErrorMissingBatchResponse
+	}
+	var result = struct {
+		Error *FacebookError `json:"error"`
+	}{}
+	err := json.Unmarshal([]byte(r.Body), &result)
+	if err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(r.Body), out)
+}
+
+// BatchBody url encodes the fields of a json object as the body of a batch
+// operation, the fields that aren't strings are encoded as json
+func BatchBody(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var fields = map[string]json.RawMessage{}
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return "", err
+	}
+
+	uV := url.Values{}
+	for k, raw := range fields {
+		var s string
+		switch {
+		case string(raw) == "null":
+			continue
+		case len(raw) > 0 && raw[0] == '"':
+			err := json.Unmarshal(raw, &s)
+			if err != nil {
+				return "", err
+			}
+		default:
+			s = string(raw)
+		}
+		uV.Set(k, s)
+	}
+
+	return encodedReference.ReplaceAllString(uV.Encode(), "{result=$1:$$$2}"), nil
+}
+
+// Batch performs the operations in graph api batch requests of up to MaxBatchSize
+// operations, the results are returned in the order of the operations and an
+// operation without response has a nil result. The operations referencing another
+// operation must be in the same batch request that the referenced operation. The
+// batch requests are accounted to the rate limit of the ad account of the operations
+// and the context cancellation stops the operations before the next batch request
+func Batch(ctx context.Context, client server.Client, accessToken, adAccount string, operations []BatchOperation) ([]*BatchResult, error) {
+	var results = []*BatchResult{}
+	client = ForAdAccount(server.WithContext(ctx, client), adAccount)
+	for start := 0; start < len(operations); start += MaxBatchSize {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		end := start + MaxBatchSize
+		if end > len(operations) {
+			end = len(operations)
+		}
+		r, err := batch(client, accessToken, operations[start:end])
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r...)
+	}
+
+	return results, nil
+}
+
+func batch(client server.Client, accessToken string, operations []BatchOperation) ([]*BatchResult, error) {
+	if err := checkReferences(operations); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(struct {
+		AccessToken string           `json:"access_token"`
+		Batch       []BatchOperation `json:"batch"`
+	}{
+		AccessToken: accessToken,
+		Batch:       operations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(SetURL("", nil), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*BatchResult
+	if err := json.Unmarshal(b, &results); err != nil {
+		var result = struct {
+			Error *FacebookError `json:"error"`
+		}{}
+		if json.Unmarshal(b, &result) == nil && result.Error != nil {
+			return nil, result.Error
+		}
+
+		return nil, ErrorBatchResponse
+	}
+	if len(results) != len(operations) {
+		return nil, ErrorBatchResponse
+	}
+
+	return results, nil
+}
+
+// checkReferences checks every referenced operation is performed before the reference
+func checkReferences(operations []BatchOperation) error {
+	names := map[string]bool{}
+	for _, o := range operations {
+		for _, s := range []string{o.RelativeURL, o.Body} {
+			for _, m := range batchReference.FindAllStringSubmatch(s, -1) {
+				if !names[m[1]] {
+					return fmt.Errorf("%w: %s", ErrorBatchReference, m[1])
+				}
+			}
+		}
+		if o.Name != "" {
+			names[o.Name] = true
+		}
+	}
+
+	return nil
+}
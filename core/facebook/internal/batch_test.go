@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"bitbucket.org/backend/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFailRequest = errors.New("failing request")
+
+type batchClient struct {
+	// response returned by the batch requests, the
+	// results of the operations are returned when empty
+	response    string
+	failRequest bool
+
+	// requests are the operations of every batch request
+	requests [][]BatchOperation
+
+	server.Client
+	t *testing.T
+}
+
+func (c *batchClient) Post(u string, body io.Reader) (*http.Response, error) {
+	if c.failRequest {
+		return nil, errFailRequest
+	}
+	if u != "https://graph.facebook.com/v8.0/" {
+		c.t.Fatalf("Unexpected batch request url: %s", u)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		c.t.Fatal("Unable to read request body: ", err)
+	}
+	var batch = struct {
+		AccessToken string           `json:"access_token"`
+		Batch       []BatchOperation `json:"batch"`
+	}{}
+	if err := json.Unmarshal(b, &batch); err != nil {
+		c.t.Fatal("Unable to unmarshal batch request: ", err)
+	}
+	if batch.AccessToken == "" {
+		c.t.Fatal("Missing access token to perform batch request")
+	}
+	c.requests = append(c.requests, batch.Batch)
+
+	w := httptest.NewRecorder()
+	if c.response != "" {
+		io.WriteString(w, c.response)
+		return w.Result(), nil
+	}
+	results := []*BatchResult{}
+	for _, o := range batch.Batch {
+		results = append(results, &BatchResult{Code: 200, Body: `{"id":"` + o.Name + `"}`})
+	}
+	b, _ = json.Marshal(results)
+	w.Write(b)
+
+	return w.Result(), nil
+}
+
+func testOperations(n int) []BatchOperation {
+	operations := []BatchOperation{}
+	for i := 0; i < n; i++ {
+		operations = append(operations, BatchOperation{Method: "POST", RelativeURL: "act_1/adsets", Name: string(rune('a' + i%26))})
+	}
+
+	return operations
+}
+
+func TestBatch(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Client     *batchClient
+		Operations []BatchOperation
+		Cancel     bool
+		Requests   int
+		Results    int
+		Error      error
+	}{
+		{
+			Name:       "Single Request",
+			Client:     &batchClient{},
+			Operations: testOperations(2),
+			Requests:   1,
+			Results:    2,
+		},
+		{
+			Name:       "Split Requests",
+			Client:     &batchClient{},
+			Operations: testOperations(2*MaxBatchSize + 1),
+			Requests:   3,
+			Results:    2*MaxBatchSize + 1,
+		},
+		{
+			Name:   "Dependent Operations",
+			Client: &batchClient{},
+			Operations: []BatchOperation{
+				{Method: "POST", RelativeURL: "act_1/adsets", Name: "adset"},
+				{Method: "POST", RelativeURL: "act_1/ads", Body: "adset_id={result=adset:$.id}"},
+			},
+			Requests: 1,
+			Results:  2,
+		},
+		{
+			Name:   "Unknown Reference",
+			Client: &batchClient{},
+			Operations: []BatchOperation{
+				{Method: "POST", RelativeURL: "act_1/ads", Body: "adset_id={result=adset:$.id}"},
+				{Method: "POST", RelativeURL: "act_1/adsets", Name: "adset"},
+			},
+			Error: ErrorBatchReference,
+		},
+		{
+			Name:       "Canceled Context",
+			Client:     &batchClient{},
+			Operations: testOperations(2),
+			Cancel:     true,
+			Error:      context.Canceled,
+		},
+		{
+			Name:       "Failing Request",
+			Client:     &batchClient{failRequest: true},
+			Operations: testOperations(2),
+			Error:      errFailRequest,
+		},
+		{
+			Name:       "Failing Batch",
+			Client:     &batchClient{response: `{"error":{"message":"failing batch","code":190}}`},
+			Operations: testOperations(2),
+			Requests:   1,
+			Error:      &FacebookError{Message: "failing batch", Code: 190},
+		},
+		{
+			Name:       "Missing Results",
+			Client:     &batchClient{response: `[{"code":200,"body":"{}"}]`},
+			Operations: testOperations(2),
+			Requests:   1,
+			Error:      ErrorBatchResponse,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Client.t = t
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.Cancel {
+				cancel()
+			}
+			results, err := Batch(ctx, tc.Client, "unicorn60", "act_1", tc.Operations)
+			assert.Len(tc.Client.requests, tc.Requests)
+			if tc.Error != nil {
+				if !errors.Is(err, tc.Error) {
+					assert.Equal(tc.Error, err)
+				}
+				return
+			}
+			assert.Nil(err)
+			if !assert.Len(results, tc.Results) {
+				return
+			}
+			for i, r := range results {
+				var created = struct {
+					ID string `json:"id"`
+				}{}
+				assert.Nil(r.Decode(&created))
+				assert.Equal(tc.Operations[i].Name, created.ID)
+			}
+			for _, operations := range tc.Client.requests {
+				assert.LessOrEqual(len(operations), MaxBatchSize)
+			}
+		})
+	}
+}
+
+func TestBatchResult(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Result   *BatchResult
+		Expected string
+		Error    error
+	}{
+		{
+			Name:     "Created Object",
+			Result:   &BatchResult{Code: 200, Body: `{"id":"1234"}`},
+			Expected: "1234",
+		},
+		{
+			Name:   "Operation Error",
+			Result: &BatchResult{Code: 400, Body: `{"error":{"message":"Invalid parameter","code":100}}`},
+			Error:  &FacebookError{Message: "Invalid parameter", Code: 100},
+		},
+		{
+			Name:  "Missing Response",
+			Error: ErrorMissingBatchResponse,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var created = struct {
+				ID string `json:"id"`
+			}{}
+			err := tc.Result.Decode(&created)
+			assert.Equal(tc.Error, err)
+			assert.Equal(tc.Expected, created.ID)
+		})
+	}
+}
+
+func TestBatchBody(t *testing.T) {
+	body, err := BatchBody(struct {
+		Name      string            `json:"name"`
+		AdSetID   string            `json:"adset_id"`
+		Creative  map[string]string `json:"creative"`
+		Budget    string            `json:"daily_budget,omitempty"`
+		Targeting *struct{}         `json:"targeting"`
+	}{
+		Name:     "new ad",
+		AdSetID:  "{result=adset-0:$.id}",
+		Creative: map[string]string{"creative_id": "1234"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Contains(t, body, "adset_id={result=adset-0:$.id}")
+	uV, err := url.ParseQuery(body)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{
+		"name":     {"new ad"},
+		"adset_id": {"{result=adset-0:$.id}"},
+		"creative": {`{"creative_id":"1234"}`},
+	}, uV)
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"errors"
+
+	"bitbucket.org/backend/core/logger"
+)
+
+// classes of the facebook errors, a *FacebookError of a class
+// matches its sentinel with errors.Is
+var (
+	// ErrorInvalidToken the access token has expired or is invalid, the user
+	// has to connect the facebook account again
+	ErrorInvalidToken = errors.New("Facebook access token has expired or is invalid")
+	// ErrorPermission the access token is missing a permission of the request
+	ErrorPermission = errors.New("Facebook access token is missing a permission")
+	// ErrorThrottled the calls are throttled by a rate limit of the graph api
+	ErrorThrottled = errors.New("The graph api calls are throttled")
+	// ErrorTransient the graph api failed temporarily
+	ErrorTransient = errors.New("Temporary graph api error")
+	// ErrorInvalidParameter the request has an invalid parameter
+	ErrorInvalidParameter = errors.New("Invalid parameter in the graph api request")
+	// ErrorPolicy the request was rejected by the facebook policies
+	ErrorPolicy = errors.New("The request was rejected by the facebook policies")
+)
+
+var (
+	// throttlingCodes are the error codes of the graph api rate limits
+	throttlingCodes = map[int]bool{4: true, 17: true, 32: true, 613: true, 80004: true}
+	tokenCodes      = map[int]bool{102: true, 190: true}
+	transientCodes  = map[int]bool{1: true, 2: true}
+	policyCodes     = map[int]bool{368: true}
+)
+
+// clientMessages are the messages shown to the user for the classes of errors
+// the user can resolve or wait for
+var clientMessages = map[error]string{
+	ErrorInvalidToken: "Your facebook session has expired, connect your facebook account again",
+	ErrorPermission:   "Your facebook account is missing a permission, connect your facebook account again granting every permission",
+	ErrorThrottled:    "Facebook is limiting the requests of your account, try again later",
+	ErrorTransient:    "Facebook is temporarily unavailable, try again later",
+	ErrorPolicy:       "Facebook rejected the request because it doesn't comply with its advertising policies",
+}
+
+// Class returns the sentinel of the class of the error code,
+// nil when the code doesn't belong to a class
+func (e *FacebookError) Class() error {
+	switch {
+	case tokenCodes[e.Code]:
+		return ErrorInvalidToken
+	case e.Code == 10 || (e.Code >= 200 && e.Code <= 299):
+		return ErrorPermission
+	case throttlingCodes[e.Code]:
+		return ErrorThrottled
+	case transientCodes[e.Code] || e.Transient:
+		return ErrorTransient
+	case e.Code == 100:
+		return ErrorInvalidParameter
+	case policyCodes[e.Code]:
+		return ErrorPolicy
+	}
+
+	return nil
+}
+
+// Is reports whether the target is the sentinel of the error class
+func (e *FacebookError) Is(target error) bool {
+	class := e.Class()
+	return class != nil && class == target
+}
+
+// Retryable reports whether the request may succeed if it's sent again later
+func (e *FacebookError) Retryable() bool {
+	class := e.Class()
+	return class == ErrorThrottled || class == ErrorTransient
+}
+
+// Retryable reports whether the request that failed with the error
+// may succeed later, the delays of the throttle are retryable too
+func Retryable(err error) bool {
+	var facebookError *FacebookError
+	if errors.As(err, &facebookError) {
+		return facebookError.Retryable()
+	}
+
+	return errors.Is(err, ErrorThrottled)
+}
+
+// ClientMessage returns the message shown to the user for the class of the
+// error, empty when the user can't resolve or wait for the error
+func ClientMessage(err error) string {
+	for class, m := range clientMessages {
+		if errors.Is(err, class) {
+			return m
+		}
+	}
+
+	return ""
+}
+
+// ResponseError returns the error of a facebook response with the level of its
+// class, the errors the user can resolve are warnings with a message for the user
+func ResponseError(err *FacebookError, level, message string, context interface{}) *logger.Error {
+	e := &logger.Error{
+		Level:   level,
+		Message: message,
+		Err:     err,
+		Context: context,
+	}
+	if e.ClientMessage = ClientMessage(err); e.ClientMessage != "" && !errors.Is(err, ErrorPolicy) {
+		e.Level = "Warning"
+	}
+
+	return e
+}
+
+// ListError wraps the error of a paginated request with the message of a failed
+// request or the message of a facebook response error, both keep the context. The
+// failed requests of a class the user can wait for, like the delays of the throttle,
+// are warnings with a message for the user
+func ListError(err error, request, response string, context interface{}) error {
+	var facebookError *FacebookError
+	if errors.As(err, &facebookError) {
+		return ResponseError(facebookError, "Error", response, context)
+	}
+
+	e := &logger.Error{
+		Level:   "Panic",
+		Message: request,
+		Err:     err,
+		Context: context,
+	}
+	if e.ClientMessage = ClientMessage(err); e.ClientMessage != "" {
+		e.Level = "Warning"
+	}
+
+	return e
+}
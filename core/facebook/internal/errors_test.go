@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"bitbucket.org/backend/core/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClass(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Error     *FacebookError
+		Class     error
+		Retryable bool
+	}{
+		{
+			Name:  "Expired Token",
+			Error: &FacebookError{Code: 190, SubCode: 463},
+			Class: ErrorInvalidToken,
+		},
+		{
+			Name:  "Missing Permission",
+			Error: &FacebookError{Code: 10},
+			Class: ErrorPermission,
+		},
+		{
+			Name:  "Missing Ads Permission",
+			Error: &FacebookError{Code: 294},
+			Class: ErrorPermission,
+		},
+		{
+			Name:      "Throttled",
+			Error:     &FacebookError{Code: 17},
+			Class:     ErrorThrottled,
+			Retryable: true,
+		},
+		{
+			Name:      "Unknown Error",
+			Error:     &FacebookError{Code: 1},
+			Class:     ErrorTransient,
+			Retryable: true,
+		},
+		{
+			Name:      "Transient Flag",
+			Error:     &FacebookError{Code: 1487390, Transient: true},
+			Class:     ErrorTransient,
+			Retryable: true,
+		},
+		{
+			Name:  "Invalid Parameter",
+			Error: &FacebookError{Code: 100, SubCode: 33},
+			Class: ErrorInvalidParameter,
+		},
+		{
+			Name:  "Policy Rejection",
+			Error: &FacebookError{Code: 368},
+			Class: ErrorPolicy,
+		},
+		{
+			Name:  "Unclassified Error",
+			Error: &FacebookError{Code: 803},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(tc.Class, tc.Error.Class())
+			assert.Equal(tc.Retryable, tc.Error.Retryable())
+			// the class is found through the errors that wrap it
+			wrapped := &logger.Error{Level: "Error", Err: fmt.Errorf("request: %w", tc.Error)}
+			assert.Equal(tc.Retryable, Retryable(wrapped))
+			if tc.Class != nil {
+				assert.True(errors.Is(wrapped, tc.Class))
+			}
+			assert.False(errors.Is(wrapped, ErrorBatchResponse))
+		})
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := ResponseError(&FacebookError{Code: 190}, "Error", "Response contained an error", nil)
+	assert.Equal("Warning", err.Level)
+	assert.Equal(ClientMessage(ErrorInvalidToken), err.ClientMessage)
+	assert.NotEmpty(err.ClientMessage)
+
+	err = ResponseError(&FacebookError{Code: 368}, "Error", "Response contained an error", "1234")
+	assert.Equal("Error", err.Level)
+	assert.Equal(ClientMessage(ErrorPolicy), err.ClientMessage)
+	assert.Equal("1234", err.Context)
+
+	err = ResponseError(&FacebookError{Code: 100}, "Error", "Response contained an error", nil)
+	assert.Equal(&logger.Error{
+		Level:   "Error",
+		Message: "Response contained an error",
+		Err:     &FacebookError{Code: 100},
+	}, err)
+
+	listErr := ListError(fmt.Errorf("page 2: %w", &FacebookError{Code: 100}), "Unable to perform request", "Response contained an error", "1234")
+	assert.Equal(&logger.Error{
+		Level:   "Error",
+		Message: "Response contained an error",
+		Err:     &FacebookError{Code: 100},
+		Context: "1234",
+	}, listErr)
+	listErr = ListError(errors.New("failing request"), "Unable to perform request", "Response contained an error", "1234")
+	assert.Equal(&logger.Error{
+		Level:   "Panic",
+		Message: "Unable to perform request",
+		Err:     errors.New("failing request"),
+		Context: "1234",
+	}, listErr)
+	// the failed request keeps the wrapped error
+	delayed := fmt.Errorf("page 2: %w", ErrorThrottled)
+	listErr = ListError(delayed, "Unable to perform request", "Response contained an error", "1234")
+	assert.Equal(&logger.Error{
+		Level:         "Warning",
+		Message:       "Unable to perform request",
+		Err:           delayed,
+		Context:       "1234",
+		ClientMessage: ClientMessage(ErrorThrottled),
+	}, listErr)
+	assert.True(errors.Is(listErr, ErrorThrottled))
+	assert.True(Retryable(listErr))
+
+	assert.True(Retryable(ErrorThrottled))
+	assert.False(Retryable(errors.New("failing request")))
+}
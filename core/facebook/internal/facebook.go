@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"time"
+
+	"bitbucket.org/backend/core/server"
 )
 
 const (
 	graphAPI     = "graph.facebook.com"
 	graphVersion = "v8.0"
+	// requestTimeout is the default time limit of a graph api request
+	requestTimeout = 30 * time.Second
 )
 
 // FacebookPaging used to page requests
@@ -29,6 +34,8 @@ type FacebookError struct {
 	Type    string `json:"type,omitempty"`
 	Code    int    `json:"code,omitempty"`
 	SubCode int    `json:"error_subcode,omitempty"`
+	// Transient is true when facebook reports a temporary error
+	Transient bool `json:"is_transient,omitempty"`
 }
 
 func (e *FacebookError) Error() string {
@@ -39,6 +46,17 @@ func (e *FacebookError) Error() string {
 	return string(s)
 }
 
+// NewClient returns the throttled client of the graph api calls, the options
+// are applied after the default request timeout and retry policy
+func NewClient(config ...server.Option) *Throttle {
+	opts := append([]server.Option{
+		server.Timeout(requestTimeout),
+		server.Retry(server.DefaultRetryPolicy()),
+	}, config...)
+
+	return NewThrottle(server.New(opts...))
+}
+
 // SetURL relative url and query to request platform
 func SetURL(relative string, query url.Values) string {
 	u := url.URL{}
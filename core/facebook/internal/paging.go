@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+
+	"bitbucket.org/backend/core/server"
+)
+
+// Iterator goes through the items of a paginated graph api response
+// following the paging of every response until the last page
+type Iterator struct {
+	ctx    context.Context
+	client server.Client
+	// url of the current and next page, next is empty after the last page
+	current string
+	next    string
+	// items of the current page that haven't been returned
+	items []json.RawMessage
+	item  json.RawMessage
+	// maxItems is the maximum number of items returned, zero is unlimited
+	maxItems int
+	count    int
+	err      error
+}
+
+// NewIterator returns an iterator over the items of the url, the context
+// cancellation stops the iteration and the request of the page in flight
+func NewIterator(ctx context.Context, client server.Client, u string, config ...func(*Iterator)) *Iterator {
+	it := &Iterator{
+		ctx:    ctx,
+		client: server.WithContext(ctx, client),
+		next:   u,
+	}
+
+	for _, fn := range config {
+		fn(it)
+	}
+
+	return it
+}
+
+// MaxItems limits the number of items returned by the iterator
+func MaxItems(n int) func(*Iterator) {
+	return func(it *Iterator) {
+		it.maxItems = n
+	}
+}
+
+// Next advances the iterator to the next item, it returns false after the
+// last item or when an error stops the iteration
+func (it *Iterator) Next() bool {
+	if it.err != nil || (it.maxItems > 0 && it.count >= it.maxItems) {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	for len(it.items) == 0 {
+		if it.next == "" {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+	it.item, it.items = it.items[0], it.items[1:]
+	it.count++
+
+	return true
+}
+
+// Decode unmarshals the current item into out
+func (it *Iterator) Decode(out interface{}) error {
+	return json.Unmarshal(it.item, out)
+}
+
+// Err returns the error that stopped the iteration, the error of a
+// response is returned as a *FacebookError
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// fetch requests the next page and sets the url of the page after it
+func (it *Iterator) fetch() error {
+	if err := it.ctx.Err(); err != nil {
+		return err
+	}
+	var page = struct {
+		Data   []json.RawMessage `json:"data"`
+		Paging *FacebookPaging   `json:"paging"`
+		Error  *FacebookError    `json:"error"`
+	}{}
+
+	resp, err := it.client.Get(it.next)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, &page)
+	if err != nil {
+		return err
+	}
+	if page.Error != nil {
+		return page.Error
+	}
+
+	it.current, it.next = it.next, ""
+	it.items = page.Data
+	if page.Paging == nil || len(page.Data) == 0 {
+		return nil
+	}
+	if page.Paging.Next != "" {
+		it.next = page.Paging.Next
+		return nil
+	}
+
+	// without the url of the next page the after cursor is followed when the
+	// page is full, facebook returns the cursors of the last page too
+	u, err := url.Parse(it.current)
+	if err != nil {
+		return err
+	}
+	uV := u.Query()
+	limit, err := strconv.Atoi(uV.Get("limit"))
+	if err != nil || len(page.Data) < limit {
+		return nil
+	}
+	after := page.Paging.Cursors.After
+	if after == "" || after == uV.Get("after") {
+		return nil
+	}
+	uV.Set("after", after)
+	u.RawQuery = uV.Encode()
+	it.next = u.String()
+
+	return nil
+}
+
+// List unmarshals every item of the url into out, which must be a pointer to a slice
+func List(ctx context.Context, client server.Client, u string, out interface{}, config ...func(*Iterator)) error {
+	var items = []json.RawMessage{}
+	it := NewIterator(ctx, client, u, config...)
+	for it.Next() {
+		items = append(items, it.item)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"bitbucket.org/backend/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type pagingClient struct {
+	// items are returned in pages of size items
+	items []string
+	size  int
+	// cursors pages only have the after cursor
+	cursors bool
+	// fail the request of the page
+	failPage int
+
+	requests int
+
+	server.Client
+	t *testing.T
+}
+
+func (c *pagingClient) Get(u string) (*http.Response, error) {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		c.t.Fatal("Unable to parse request url: ", err)
+	}
+	c.requests++
+
+	var start int
+	if after := requestURL.Query().Get("after"); after != "" {
+		start, _ = strconv.Atoi(after)
+	}
+	w := httptest.NewRecorder()
+	if c.failPage != 0 && start/c.size+1 == c.failPage {
+		io.WriteString(w, `{"error":{"message":"failing operation","code":1}}`)
+		return w.Result(), nil
+	}
+	end := start + c.size
+	if end > len(c.items) {
+		end = len(c.items)
+	}
+
+	data := []map[string]string{}
+	for _, id := range c.items[start:end] {
+		data = append(data, map[string]string{"id": id})
+	}
+	paging := FacebookPaging{}
+	paging.Cursors.After = fmt.Sprint(end)
+	if end < len(c.items) && !c.cursors {
+		uV := requestURL.Query()
+		uV.Set("after", fmt.Sprint(end))
+		requestURL.RawQuery = uV.Encode()
+		paging.Next = requestURL.String()
+	}
+	b, _ := json.Marshal(map[string]interface{}{"data": data, "paging": paging})
+	w.Write(b)
+
+	return w.Result(), nil
+}
+
+func testItems(n int) []string {
+	items := []string{}
+	for i := 0; i < n; i++ {
+		items = append(items, fmt.Sprint(i))
+	}
+
+	return items
+}
+
+func TestIterator(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Client   *pagingClient
+		Limit    string
+		Config   []func(*Iterator)
+		Cancel   bool
+		Expected []string
+		Requests int
+		Error    error
+	}{
+		{
+			Name:     "Next Pages",
+			Client:   &pagingClient{items: testItems(5), size: 2},
+			Expected: testItems(5),
+			Requests: 3,
+		},
+		{
+			Name:     "After Cursors",
+			Client:   &pagingClient{items: testItems(5), size: 2, cursors: true},
+			Limit:    "2",
+			Expected: testItems(5),
+			Requests: 3,
+		},
+		{
+			Name: "Last Page Cursor",
+			// the cursor of a page smaller than the limit isn't followed
+			Client:   &pagingClient{items: testItems(3), size: 2, cursors: true},
+			Limit:    "5",
+			Expected: testItems(2),
+			Requests: 1,
+		},
+		{
+			Name:     "Cursors Without Limit",
+			Client:   &pagingClient{items: testItems(5), size: 2, cursors: true},
+			Expected: testItems(2),
+			Requests: 1,
+		},
+		{
+			Name:     "Max Items",
+			Client:   &pagingClient{items: testItems(10), size: 2},
+			Config:   []func(*Iterator){MaxItems(3)},
+			Expected: testItems(3),
+			Requests: 2,
+		},
+		{
+			Name:     "Empty Response",
+			Client:   &pagingClient{size: 2},
+			Expected: []string{},
+			Requests: 1,
+		},
+		{
+			Name:     "Failing Page",
+			Client:   &pagingClient{items: testItems(5), size: 2, failPage: 2},
+			Expected: testItems(2),
+			Requests: 2,
+			Error:    &FacebookError{Message: "failing operation", Code: 1},
+		},
+		{
+			Name:     "Canceled Context",
+			Client:   &pagingClient{items: testItems(5), size: 2},
+			Cancel:   true,
+			Expected: []string{},
+			Requests: 0,
+			Error:    context.Canceled,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Client.t = t
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.Cancel {
+				cancel()
+			}
+			uV := url.Values{}
+			uV.Add("access_token", "unicorn60")
+			if tc.Limit != "" {
+				uV.Add("limit", tc.Limit)
+			}
+
+			items := []string{}
+			it := NewIterator(ctx, tc.Client, SetURL("1234/adsets", uV), tc.Config...)
+			for it.Next() {
+				var item = struct {
+					ID string `json:"id"`
+				}{}
+				assert.Nil(it.Decode(&item))
+				items = append(items, item.ID)
+			}
+			assert.Equal(tc.Error, it.Err())
+			assert.Equal(tc.Expected, items)
+			assert.Equal(tc.Requests, tc.Client.requests)
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	assert := assert.New(t)
+	client := &pagingClient{items: testItems(5), size: 2, t: t}
+
+	var items = []struct {
+		ID string `json:"id"`
+	}{}
+	err := List(context.Background(), client, SetURL("1234/adsets", nil), &items)
+	assert.Nil(err)
+	assert.Len(items, 5)
+	assert.Equal("4", items[4].ID)
+
+	client = &pagingClient{items: testItems(5), size: 2, failPage: 1, t: t}
+	err = List(context.Background(), client, SetURL("1234/adsets", nil), &items)
+	assert.Equal(&FacebookError{Message: "failing operation", Code: 1}, err)
+}
@@ -0,0 +1,426 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"bitbucket.org/backend/core/server"
+)
+
+// rate limit headers of the graph api responses
+const (
+	appUsageHeader       = "X-App-Usage"
+	adAccountUsageHeader = "X-Ad-Account-Usage"
+	businessUsageHeader  = "X-Business-Use-Case-Usage"
+)
+
+const (
+	defaultThreshold = 90.0
+	defaultCooldown  = time.Minute
+	defaultBackoff   = 5 * time.Minute
+	defaultMaxDelay  = 30 * time.Second
+)
+
+// appCodes are the throttling codes of the application
+// rate limit, they block every call of the app
+var appCodes = map[int]bool{4: true}
+
+// Usage is the percentage of a rate limit used, Until is the time
+// the calls are blocked until because of the usage or a throttling error
+type Usage struct {
+	CallCount    float64   `json:"call_count"`
+	TotalCPUTime float64   `json:"total_cputime"`
+	TotalTime    float64   `json:"total_time"`
+	Until        time.Time `json:"until,omitempty"`
+}
+
+// Max returns the highest percentage of the rate limit used
+func (u Usage) Max() float64 {
+	max := u.CallCount
+	if u.TotalCPUTime > max {
+		max = u.TotalCPUTime
+	}
+	if u.TotalTime > max {
+		max = u.TotalTime
+	}
+
+	return max
+}
+
+// Usages is the usage of every rate limit known by a throttle
+type Usages struct {
+	App        Usage            `json:"app"`
+	AdAccounts map[string]Usage `json:"ad_accounts"`
+	Businesses map[string]Usage `json:"businesses"`
+}
+
+// Throttle is a client that keeps the usage of the app and ad account rate limits
+// from the responses of the graph api, the calls are delayed while a rate limit is
+// over the threshold or after a throttling error and fail when the delay is too long
+type Throttle struct {
+	server.Client
+	// ctx is the context of the calls, the copies bound
+	// to a context share the usage of the throttle
+	ctx context.Context
+	// adAccount is the ad account the calls are accounted to
+	// when it isn't in their url, such as the batch requests
+	adAccount string
+	*limits
+
+	// threshold is the usage percentage at which the calls are delayed for the cooldown
+	threshold float64
+	cooldown  time.Duration
+	// backoff is the delay after a throttling error without reset time
+	backoff  time.Duration
+	maxDelay time.Duration
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// limits is the usage of the rate limits
+type limits struct {
+	mu         sync.Mutex
+	app        Usage
+	adAccounts map[string]Usage
+	businesses map[string]Usage
+	// queues make the delayed calls of an ad account wait one after the other,
+	// the calls that don't belong to an ad account share the empty id queue
+	queues map[string]*sync.Mutex
+}
+
+// NewThrottle returns a throttle of the client calls
+func NewThrottle(client server.Client, config ...func(*Throttle)) *Throttle {
+	t := &Throttle{
+		Client: client,
+		ctx:    context.Background(),
+		limits: &limits{
+			adAccounts: map[string]Usage{},
+			businesses: map[string]Usage{},
+			queues:     map[string]*sync.Mutex{},
+		},
+		threshold: defaultThreshold,
+		cooldown:  defaultCooldown,
+		backoff:   defaultBackoff,
+		maxDelay:  defaultMaxDelay,
+		now:       time.Now,
+		sleep:     sleep,
+	}
+
+	for _, fn := range config {
+		fn(t)
+	}
+
+	return t
+}
+
+// Threshold sets the usage percentage at which the calls are delayed for the cooldown
+func Threshold(percentage float64, cooldown time.Duration) func(*Throttle) {
+	return func(t *Throttle) {
+		t.threshold = percentage
+		t.cooldown = cooldown
+	}
+}
+
+// Backoff sets the delay of the calls after a throttling error
+// when facebook doesn't return the time to regain access
+func Backoff(d time.Duration) func(*Throttle) {
+	return func(t *Throttle) {
+		t.backoff = d
+	}
+}
+
+// MaxDelay sets the longest delay of a call, the calls
+// throttled for longer fail with ErrorThrottled
+func MaxDelay(d time.Duration) func(*Throttle) {
+	return func(t *Throttle) {
+		t.maxDelay = d
+	}
+}
+
+// WithContext returns a copy of the throttle whose calls and delays are
+// cancelled with the context, the copy shares the usage of the rate limits
+func (t *Throttle) WithContext(ctx context.Context) server.Client {
+	tc := *t
+	tc.ctx = ctx
+	tc.Client = server.WithContext(ctx, t.Client)
+
+	return &tc
+}
+
+// ForAdAccount returns the client with its calls accounted to the ad account,
+// it's used for the calls whose url doesn't have the ad account such as the
+// batch requests. The client is returned as is when it isn't a throttle
+func ForAdAccount(c server.Client, adAccount string) server.Client {
+	t, ok := c.(*Throttle)
+	if !ok {
+		return c
+	}
+	tc := *t
+	tc.adAccount = strings.TrimPrefix(adAccount, "act_")
+
+	return &tc
+}
+
+// Get performs a get request once the rate limits allow it
+func (t *Throttle) Get(u string) (*http.Response, error) {
+	adAccount := t.account(u)
+	if err := t.wait(t.ctx, adAccount); err != nil {
+		return nil, err
+	}
+	resp, err := t.Client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.record(adAccount, resp)
+}
+
+// Post performs a post request once the rate limits allow it
+func (t *Throttle) Post(u string, body io.Reader) (*http.Response, error) {
+	adAccount := t.account(u)
+	if err := t.wait(t.ctx, adAccount); err != nil {
+		return nil, err
+	}
+	resp, err := t.Client.Post(u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.record(adAccount, resp)
+}
+
+// Do performs the request once the rate limits allow it
+func (t *Throttle) Do(req *http.Request) (*http.Response, error) {
+	adAccount := t.account(req.URL.String())
+	if err := t.wait(req.Context(), adAccount); err != nil {
+		return nil, err
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.record(adAccount, resp)
+}
+
+// Usage returns a copy of the usage of the rate limits
+func (t *Throttle) Usage() Usages {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u := Usages{
+		App:        t.app,
+		AdAccounts: map[string]Usage{},
+		Businesses: map[string]Usage{},
+	}
+	for k, v := range t.adAccounts {
+		u.AdAccounts[k] = v
+	}
+	for k, v := range t.businesses {
+		u.Businesses[k] = v
+	}
+
+	return u
+}
+
+// Delay returns how long the calls of the ad account are throttled, the calls
+// that don't belong to an ad account only depend on the app rate limit
+func (t *Throttle) Delay(adAccount string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until := t.app.Until
+	if u := t.adAccounts[strings.TrimPrefix(adAccount, "act_")]; u.Until.After(until) {
+		until = u.Until
+	}
+	if d := until.Sub(t.now()); d > 0 {
+		return d
+	}
+
+	return 0
+}
+
+// account returns the id of the ad account the call to the url is accounted to
+func (t *Throttle) account(u string) string {
+	if t.adAccount != "" {
+		return t.adAccount
+	}
+
+	return adAccountID(u)
+}
+
+// queue returns the queue of the delayed calls of the ad account
+func (t *Throttle) queue(adAccount string) *sync.Mutex {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, ok := t.queues[adAccount]
+	if !ok {
+		q = &sync.Mutex{}
+		t.queues[adAccount] = q
+	}
+
+	return q
+}
+
+// wait delays the call until the rate limits allow it, only the calls
+// of the same ad account wait for the delayed call
+func (t *Throttle) wait(ctx context.Context, adAccount string) error {
+	q := t.queue(adAccount)
+	q.Lock()
+	defer q.Unlock()
+
+	for d := t.Delay(adAccount); d > 0; d = t.Delay(adAccount) {
+		if d > t.maxDelay {
+			return ErrorThrottled
+		}
+		if err := t.sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// sleep waits for the delay unless the context is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record updates the usage from the response headers and throttling errors, the
+// response body is read to find the error and replaced with a copy
+func (t *Throttle) record(adAccount string, resp *http.Response) (*http.Response, error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var regain time.Time
+	if h := resp.Header.Get(appUsageHeader); h != "" {
+		var u Usage
+		if json.Unmarshal([]byte(h), &u) == nil {
+			t.app = t.cool(t.app, u, time.Time{}, now)
+		}
+	}
+	if h := resp.Header.Get(businessUsageHeader); h != "" {
+		var businesses = map[string][]struct {
+			Usage
+			Regain float64 `json:"estimated_time_to_regain_access"`
+		}{}
+		if json.Unmarshal([]byte(h), &businesses) == nil {
+			for id, types := range businesses {
+				var u Usage
+				var until time.Time
+				for _, business := range types {
+					if business.Max() > u.Max() {
+						u = business.Usage
+					}
+					// the time to regain access is in minutes
+					if r := now.Add(time.Duration(business.Regain * float64(time.Minute))); business.Regain > 0 && r.After(until) {
+						until = r
+					}
+				}
+				t.businesses[id] = t.cool(t.businesses[id], u, until, now)
+				if b := t.businesses[id]; b.Until.After(regain) {
+					regain = b.Until
+				}
+			}
+		}
+	}
+	if adAccount != "" {
+		u := t.adAccounts[adAccount]
+		var account = struct {
+			Utilization float64 `json:"acc_id_util_pct"`
+			// the reset time is in seconds
+			Reset float64 `json:"reset_time_duration"`
+		}{}
+		h := resp.Header.Get(adAccountUsageHeader)
+		switch {
+		case h != "" && json.Unmarshal([]byte(h), &account) == nil:
+			if r := now.Add(time.Duration(account.Reset * float64(time.Second))); account.Reset > 0 && r.After(regain) {
+				regain = r
+			}
+			u = t.cool(u, Usage{CallCount: account.Utilization}, regain, now)
+		case regain.After(u.Until):
+			// the usage is kept when the response doesn't have the header
+			u.Until = regain
+		}
+		t.adAccounts[adAccount] = u
+	}
+
+	// a throttling error blocks the calls until the time to regain
+	// access or the backoff when the response doesn't have it
+	var result = struct {
+		Error *FacebookError `json:"error"`
+	}{}
+	if json.Unmarshal(b, &result) == nil && result.Error != nil && throttlingCodes[result.Error.Code] {
+		if !regain.After(now) {
+			regain = now.Add(t.backoff)
+		}
+		switch {
+		case appCodes[result.Error.Code] || adAccount == "":
+			if regain.After(t.app.Until) {
+				t.app.Until = regain
+			}
+		default:
+			u := t.adAccounts[adAccount]
+			if regain.After(u.Until) {
+				u.Until = regain
+			}
+			t.adAccounts[adAccount] = u
+		}
+	}
+
+	return resp, nil
+}
+
+// cool returns the new usage of a rate limit, the calls are blocked until the
+// reset time and at least for the cooldown when the usage is over the threshold
+func (t *Throttle) cool(previous, u Usage, until, now time.Time) Usage {
+	u.Until = previous.Until
+	if c := now.Add(t.cooldown); u.Max() >= t.threshold && c.After(until) {
+		until = c
+	}
+	if until.After(u.Until) {
+		u.Until = until
+	}
+
+	return u
+}
+
+// adAccountID returns the id of the ad account of the url path without the act_ prefix
+func adAccountID(u string) string {
+	requestURL, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	for _, p := range strings.Split(requestURL.Path, "/") {
+		if strings.HasPrefix(p, "act_") {
+			return strings.TrimPrefix(p, "act_")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type throttleClient struct {
+	// headers and body of the responses
+	headers map[string]string
+	body    string
+
+	requests int
+
+	server.Client
+}
+
+func (c *throttleClient) response() *http.Response {
+	c.requests++
+	w := httptest.NewRecorder()
+	for k, v := range c.headers {
+		w.Header().Set(k, v)
+	}
+	if c.body == "" {
+		c.body = `{"id":"1234"}`
+	}
+	io.WriteString(w, c.body)
+
+	return w.Result()
+}
+
+func (c *throttleClient) Get(u string) (*http.Response, error) {
+	return c.response(), nil
+}
+
+func (c *throttleClient) Post(u string, body io.Reader) (*http.Response, error) {
+	return c.response(), nil
+}
+
+func (c *throttleClient) Do(req *http.Request) (*http.Response, error) {
+	return c.response(), nil
+}
+
+// testClock is the time of a throttle that moves forward when it sleeps
+type testClock struct {
+	time  time.Time
+	slept []time.Duration
+}
+
+func (c *testClock) config(t *Throttle) {
+	t.now = func() time.Time { return c.time }
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		c.slept = append(c.slept, d)
+		c.time = c.time.Add(d)
+		return ctx.Err()
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Headers map[string]string
+		Body    string
+		URL     string
+		// Slept is the delay of the call after the first one
+		Slept []time.Duration
+		Error error
+	}{
+		{
+			Name:    "Low Usage",
+			Headers: map[string]string{appUsageHeader: `{"call_count":20,"total_cputime":10,"total_time":15}`},
+			URL:     SetURL("act_1234/campaigns", nil),
+		},
+		{
+			Name:    "App Usage Over Threshold",
+			Headers: map[string]string{appUsageHeader: `{"call_count":95,"total_cputime":10,"total_time":15}`},
+			URL:     SetURL("1234", nil),
+			Slept:   []time.Duration{time.Second},
+		},
+		{
+			Name:    "Ad Account Reset Time",
+			Headers: map[string]string{adAccountUsageHeader: `{"acc_id_util_pct":50,"reset_time_duration":20}`},
+			URL:     SetURL("act_1234/adsets", nil),
+			Slept:   []time.Duration{20 * time.Second},
+		},
+		{
+			Name:    "Business Time To Regain Access",
+			Headers: map[string]string{businessUsageHeader: `{"5678":[{"type":"ads_management","call_count":100,"total_cputime":20,"total_time":20,"estimated_time_to_regain_access":1}]}`},
+			URL:     SetURL("act_1234/ads", nil),
+			Error:   ErrorThrottled,
+		},
+		{
+			Name:  "Throttling Error",
+			Body:  `{"error":{"message":"User request limit reached","code":17}}`,
+			URL:   SetURL("act_1234/ads", nil),
+			Error: ErrorThrottled,
+		},
+		{
+			Name: "Other Error",
+			Body: `{"error":{"message":"Invalid parameter","code":100}}`,
+			URL:  SetURL("act_1234/ads", nil),
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := &throttleClient{headers: tc.Headers, body: tc.Body}
+			clock := &testClock{time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)}
+			throttle := NewThrottle(client, clock.config, Threshold(90, time.Second), MaxDelay(30*time.Second))
+
+			resp, err := throttle.Get(tc.URL)
+			if !assert.Nil(err) {
+				return
+			}
+			b, _ := ioutil.ReadAll(resp.Body)
+			assert.Equal(client.body, string(b))
+
+			_, err = throttle.Post(tc.URL, strings.NewReader(""))
+			assert.Equal(tc.Error, err)
+			assert.Equal(tc.Slept, clock.slept)
+			if tc.Error != nil {
+				assert.Equal(1, client.requests)
+				return
+			}
+			assert.Equal(2, client.requests)
+		})
+	}
+}
+
+func TestThrottleUsage(t *testing.T) {
+	assert := assert.New(t)
+	client := &throttleClient{headers: map[string]string{
+		appUsageHeader:       `{"call_count":40,"total_cputime":60,"total_time":50}`,
+		adAccountUsageHeader: `{"acc_id_util_pct":30,"reset_time_duration":0}`,
+	}}
+	clock := &testClock{time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)}
+	throttle := NewThrottle(client, clock.config)
+
+	_, err := throttle.Get(SetURL("act_1234/campaigns", nil))
+	assert.Nil(err)
+	usage := throttle.Usage()
+	assert.Equal(Usage{CallCount: 40, TotalCPUTime: 60, TotalTime: 50}, usage.App)
+	assert.Equal(60.0, usage.App.Max())
+	assert.Equal(map[string]Usage{"1234": {CallCount: 30}}, usage.AdAccounts)
+	assert.Equal(time.Duration(0), throttle.Delay("act_1234"))
+
+	// an ad account throttling error doesn't delay the calls of other ad accounts
+	client.body = `{"error":{"message":"Ad account has too many API calls","code":80004}}`
+	_, err = throttle.Get(SetURL("act_1234/insights", nil))
+	assert.Nil(err)
+	assert.Equal(defaultBackoff, throttle.Delay("act_1234"))
+	assert.Equal(defaultBackoff, throttle.Delay("1234"))
+	assert.Equal(time.Duration(0), throttle.Delay("act_5678"))
+
+	// an app throttling error delays every call
+	client.body = `{"error":{"message":"Application request limit reached","code":4}}`
+	_, err = throttle.Get(SetURL("act_5678/insights", nil))
+	assert.Nil(err)
+	assert.Equal(defaultBackoff, throttle.Delay("act_5678"))
+	assert.Equal(defaultBackoff, throttle.Delay(""))
+}
+
+func TestAdAccountID(t *testing.T) {
+	assert.Equal(t, "1234", adAccountID(SetURL("act_1234/adsets", nil)))
+	assert.Equal(t, "", adAccountID(SetURL("1234/adsets", nil)))
+	assert.Equal(t, "", adAccountID(SetURL("", nil)))
+}
+
+func TestThrottleWithContext(t *testing.T) {
+	assert := assert.New(t)
+	client := &throttleClient{body: `{"error":{"message":"User request limit reached","code":17}}`}
+	clock := &testClock{time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)}
+	throttle := NewThrottle(client, clock.config, Backoff(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := throttle.WithContext(ctx)
+	_, err := tc.Get(SetURL("act_1234/ads", nil))
+	assert.Nil(err)
+	// the copy shares the usage of the rate limits
+	assert.Equal(time.Second, throttle.Delay("1234"))
+
+	cancel()
+	_, err = tc.Get(SetURL("act_1234/ads", nil))
+	assert.Equal(context.Canceled, err)
+	_, err = tc.Get(SetURL("1234", nil))
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, client.requests)
+	_, err = throttle.Get(SetURL("1234", nil))
+	assert.Nil(err)
+	assert.Equal(2, client.requests)
+}
+
+func TestThrottleQueues(t *testing.T) {
+	assert := assert.New(t)
+	client := &throttleClient{body: `{"error":{"message":"User request limit reached","code":17}}`}
+	throttle := NewThrottle(client, Backoff(time.Second))
+	sleeping, release := make(chan struct{}), make(chan struct{})
+	throttle.sleep = func(ctx context.Context, d time.Duration) error {
+		close(sleeping)
+		<-release
+		return context.Canceled
+	}
+
+	_, err := throttle.Get(SetURL("act_1234/ads", nil))
+	assert.Nil(err)
+	delayed := make(chan error)
+	go func() {
+		_, err := throttle.Get(SetURL("act_1234/ads", nil))
+		delayed <- err
+	}()
+	<-sleeping
+
+	// the delayed call of an ad account doesn't block the calls of other ad accounts
+	other := make(chan error)
+	go func() {
+		_, err := throttle.Get(SetURL("act_5678/ads", nil))
+		other <- err
+	}()
+	select {
+	case err := <-other:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Error("The call of another ad account waits for the delayed call")
+	}
+	close(release)
+	assert.Equal(context.Canceled, <-delayed)
+}
+
+func TestForAdAccount(t *testing.T) {
+	assert := assert.New(t)
+	client := &throttleClient{body: `{"error":{"message":"User request limit reached","code":17}}`}
+	clock := &testClock{time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)}
+	throttle := NewThrottle(client, clock.config)
+
+	// the batch requests are posted to the root url
+	_, err := Batch(context.Background(), throttle, "unicorn60", "act_1234", testOperations(2))
+	assert.Error(err)
+	assert.Equal(defaultBackoff, throttle.Delay("1234"))
+	assert.Equal(time.Duration(0), throttle.Delay(""))
+
+	fake := &throttleClient{}
+	assert.Equal(fake, ForAdAccount(fake, "act_1234"))
+}
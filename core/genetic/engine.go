@@ -0,0 +1,359 @@
+package genetic
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// StopMaxGenerations the engine ran the maximum number of generations
+	StopMaxGenerations = "max_generations"
+	// StopPlateau the best quality or the best value of every objective
+	// didn't improve for the plateau generations
+	StopPlateau = "plateau"
+	// StopDiversity the diversity of the population fell below the minimum
+	StopDiversity = "diversity"
+)
+
+var (
+	// ErrorEmptyPopulation the engine can't run without an initial population
+	ErrorEmptyPopulation = errors.New("The initial population can't be empty")
+)
+
+// Engine runs the genetic algorithm for several generations
+type Engine interface {
+	Run(population []*Chromosome) (*Result, error)
+}
+
+// Statistics of the quality and diversity of a generation
+type Statistics struct {
+	Generation int     `json:"generation"`
+	Best       float64 `json:"best"`
+	Mean       float64 `json:"mean"`
+	Worst      float64 `json:"worst"`
+	Diversity  float64 `json:"diversity"`
+	// Front is the size of the first Pareto front and Objectives the best value
+	// of every objective, they are only set by the multi objective optimization
+	Front      int       `json:"front,omitempty"`
+	Objectives []float64 `json:"objectives,omitempty"`
+}
+
+// Result of an engine run
+type Result struct {
+	// Population is the last evaluated generation
+	Population  []*Chromosome `json:"population"`
+	Generations []Statistics  `json:"generations"`
+	// Stop is the reason the engine stopped
+	Stop string `json:"stop"`
+}
+
+type engine struct {
+	genetic Genetic
+	random  Source
+	// population configuration
+	populationSize int
+	selectionSize  int
+	mutationRate   float64
+	crossoverRate  float64
+	// convergence configuration
+	maxGenerations     int
+	plateauGenerations int
+	tolerance          float64
+	minDiversity       float64
+	// report is called after each generation is evaluated
+	report func(s Statistics)
+}
+
+// EngineOption configures the engine created by NewEngine
+type EngineOption func(*engine)
+
+// NewEngine creates an engine that evolves a population with the genetic algorithm,
+// the quality function of the algorithm is used as the fitness evaluator
+func NewEngine(g Genetic, config ...EngineOption) Engine {
+	e := &engine{
+		genetic:            g,
+		random:             NewCryptoSource(),
+		populationSize:     30,
+		selectionSize:      5,
+		mutationRate:       0.05,
+		crossoverRate:      0.6,
+		maxGenerations:     50,
+		plateauGenerations: 10,
+		tolerance:          1e-9,
+		report:             func(s Statistics) {},
+	}
+
+	for _, fn := range config {
+		fn(e)
+	}
+
+	return e
+}
+
+// EngineSource sets the source used by the engine to decide between crossover and mutation
+func EngineSource(s Source) EngineOption {
+	return func(e *engine) {
+		e.random = s
+	}
+}
+
+// PopulationSize sets the size of each generation and the number of selected chromosomes
+func PopulationSize(population, selection int) EngineOption {
+	return func(e *engine) {
+		e.populationSize = population
+		e.selectionSize = selection
+	}
+}
+
+// Rates sets the mutation and crossover rates used to create each generation
+func Rates(mutation, crossover float64) EngineOption {
+	return func(e *engine) {
+		e.mutationRate = mutation
+		e.crossoverRate = crossover
+	}
+}
+
+// MaxGenerations sets the maximum number of generations to run
+func MaxGenerations(n int) EngineOption {
+	return func(e *engine) {
+		e.maxGenerations = n
+	}
+}
+
+// Plateau stops the engine when the best quality, or the best value of every
+// objective of a multi objective optimization, doesn't improve more than
+// tolerance during the provided number of generations
+func Plateau(generations int, tolerance float64) EngineOption {
+	return func(e *engine) {
+		e.plateauGenerations = generations
+		e.tolerance = tolerance
+	}
+}
+
+// MinDiversity stops the engine when the diversity of the population falls below min
+func MinDiversity(min float64) EngineOption {
+	return func(e *engine) {
+		e.minDiversity = min
+	}
+}
+
+// Report sets a function called with the statistics of every generation
+func Report(fn func(s Statistics)) EngineOption {
+	return func(e *engine) {
+		e.report = fn
+	}
+}
+
+func (e *engine) Run(population []*Chromosome) (*Result, error) {
+	if len(population) == 0 {
+		return nil, ErrorEmptyPopulation
+	}
+
+	var (
+		result = &Result{}
+		best   = math.Inf(-1)
+		// bestObjectives is the best value of every objective
+		bestObjectives []float64
+		stale          int
+	)
+	for generation := 0; ; generation++ {
+		err := e.genetic.Fitness(population)
+		if err != nil {
+			return nil, err
+		}
+		s := statistics(generation, population)
+		result.Generations = append(result.Generations, s)
+		result.Population = population
+		e.report(s)
+
+		improved := s.Best > best+e.tolerance
+		if improved {
+			best = s.Best
+		}
+		if s.Objectives != nil {
+			// the quality isn't computed by the multi objective optimization
+			improved = false
+			if len(bestObjectives) != len(s.Objectives) {
+				bestObjectives = make([]float64, len(s.Objectives))
+				for m := range bestObjectives {
+					bestObjectives[m] = math.Inf(-1)
+				}
+			}
+			for m, v := range s.Objectives {
+				if v > bestObjectives[m]+e.tolerance {
+					bestObjectives[m] = v
+					improved = true
+				}
+			}
+		}
+		if improved {
+			stale = 0
+		} else {
+			stale++
+		}
+
+		switch {
+		case generation+1 >= e.maxGenerations:
+			result.Stop = StopMaxGenerations
+		case e.plateauGenerations > 0 && stale >= e.plateauGenerations:
+			result.Stop = StopPlateau
+		case s.Diversity < e.minDiversity:
+			result.Stop = StopDiversity
+		}
+		if result.Stop != "" {
+			return result, nil
+		}
+
+		selected, err := e.genetic.Selection(population, e.selectionSize)
+		if err != nil {
+			return nil, err
+		}
+		population, err = Reproduce(e.genetic, selected, e.populationSize, e.mutationRate, e.crossoverRate, e.random)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Reproduce creates a population of the provided size that starts with the selected
+// chromosomes and is filled with offspring, each offspring is a mutated crossover of
+// two consecutive selected chromosomes with probability crossoverRate or a mutated
+// copy of a selected chromosome otherwise
+func Reproduce(g Genetic, selected []*Chromosome, size int, mutationRate, crossoverRate float64, d Source) ([]*Chromosome, error) {
+	if len(selected) == 0 {
+		return nil, ErrorEmptyPopulation
+	}
+
+	var population = []*Chromosome{}
+	population = append(population, selected...)
+	idx := 0
+	for len(population) < size {
+		if idx == len(selected) {
+			idx = 0
+		}
+		r, err := sample(d)
+		if err != nil {
+			return nil, err
+		}
+		if r < crossoverRate {
+			a, b, err := g.Crossover(selected[idx], selected[(idx+1)%len(selected)])
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range []*Chromosome{a, b} {
+				if len(population) == size {
+					break
+				}
+				if err := g.Mutate(c, mutationRate); err != nil {
+					return nil, err
+				}
+				population = append(population, c)
+			}
+		} else {
+			// mutants are new ad sets without performance data
+			c := offspring(selected[idx])
+			if err := g.Mutate(c, mutationRate); err != nil {
+				return nil, err
+			}
+			population = append(population, c)
+		}
+		idx++
+	}
+
+	return population, nil
+}
+
+func statistics(generation int, population []*Chromosome) Statistics {
+	s := Statistics{
+		Generation: generation,
+		Best:       math.Inf(-1),
+		Worst:      math.Inf(1),
+		Diversity:  Diversity(population),
+	}
+	for _, c := range population {
+		s.Mean += c.Quality
+		if c.Quality > s.Best {
+			s.Best = c.Quality
+		}
+		if c.Quality < s.Worst {
+			s.Worst = c.Quality
+		}
+	}
+	s.Mean /= float64(len(population))
+
+	// the chromosomes have objectives when they are ranked in Pareto fronts
+	if len(population[0].Objectives) == 0 {
+		return s
+	}
+	s.Objectives = make([]float64, len(population[0].Objectives))
+	for m := range s.Objectives {
+		s.Objectives[m] = math.Inf(-1)
+	}
+	for _, c := range population {
+		if c.Rank == 0 {
+			s.Front++
+		}
+		for m, v := range c.Objectives {
+			if v > s.Objectives[m] {
+				s.Objectives[m] = v
+			}
+		}
+	}
+
+	return s
+}
+
+// Diversity is the mean hamming distance between the leaf genes of every pair of
+// chromosomes of the population normalized by the number of leaf genes, it is zero
+// when every chromosome has the same targeting and one when every pair differs in
+// all the genes
+func Diversity(population []*Chromosome) float64 {
+	leaves := make([][]float64, len(population))
+	for i, c := range population {
+		leaves[i] = leafValues(c.Root, nil)
+	}
+
+	var (
+		total float64
+		pairs int
+	)
+	for i := 0; i < len(leaves); i++ {
+		for j := i + 1; j < len(leaves); j++ {
+			n := len(leaves[i])
+			if len(leaves[j]) > n {
+				n = len(leaves[j])
+			}
+			pairs++
+			if n == 0 {
+				continue
+			}
+			var d int
+			for k := 0; k < n; k++ {
+				if k >= len(leaves[i]) || k >= len(leaves[j]) || leaves[i][k] != leaves[j][k] {
+					d++
+				}
+			}
+			total += float64(d) / float64(n)
+		}
+	}
+	if pairs == 0 {
+		return 0.0
+	}
+
+	return total / float64(pairs)
+}
+
+// leafValues returns the value of the genes with an ID in depth first order
+func leafValues(g *Gene, values []float64) []float64 {
+	if g == nil {
+		return values
+	}
+	if g.ID != "" {
+		values = append(values, g.Value)
+	}
+	for _, child := range g.Children {
+		values = leafValues(child, values)
+	}
+
+	return values
+}
@@ -0,0 +1,228 @@
+package genetic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// onesQuality is an offline quality that counts the active leaf genes
+func onesQuality(c *Chromosome) (float64, error) {
+	var q float64 = 1
+	for _, v := range leafValues(c.Root, nil) {
+		q += v
+	}
+
+	return q, nil
+}
+
+func testEnginePopulation(n int) []*Chromosome {
+	population := []*Chromosome{}
+	for i := 0; i < n; i++ {
+		population = append(population, testChromosome("", []float64{float64(i % 2), 0, 1}, []float64{0, float64(i % 3 / 2), 0}))
+	}
+
+	return population
+}
+
+func TestEngine(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Quality     func(c *Chromosome) (float64, error)
+		Options     []Option
+		Population  []*Chromosome
+		Config      []EngineOption
+		Generations int
+		Stop        string
+		Error       error
+	}{
+		{
+			Name:        "Max Generations",
+			Quality:     onesQuality,
+			Population:  testEnginePopulation(8),
+			Config:      []EngineOption{MaxGenerations(4), Plateau(0, 0)},
+			Generations: 4,
+			Stop:        StopMaxGenerations,
+		},
+		{
+			Name: "Plateau",
+			Quality: func(c *Chromosome) (float64, error) {
+				return 1, nil
+			},
+			Population:  testEnginePopulation(8),
+			Config:      []EngineOption{Plateau(2, 0)},
+			Generations: 3,
+			Stop:        StopPlateau,
+		},
+		{
+			Name:       "Diversity",
+			Quality:    onesQuality,
+			Population: []*Chromosome{testChromosome("", []float64{1, 0}, nil), testChromosome("", []float64{1, 0}, nil)},
+			Config:     []EngineOption{MinDiversity(0.1), PopulationSize(2, 1)},
+			// a population of clones has no diversity
+			Generations: 1,
+			Stop:        StopDiversity,
+		},
+		{
+			Name:    "Multi Objective",
+			Quality: onesQuality,
+			// the objectives improve with every evaluation
+			Options: []Option{MultiObjective(func() func(c *Chromosome) ([]float64, error) {
+				var evaluations float64
+				return func(c *Chromosome) ([]float64, error) {
+					evaluations++
+					return []float64{evaluations, -evaluations}, nil
+				}
+			}())},
+			Population:  testEnginePopulation(8),
+			Config:      []EngineOption{MaxGenerations(4), Plateau(2, 0)},
+			Generations: 4,
+			Stop:        StopMaxGenerations,
+		},
+		{
+			Name:    "Multi Objective Plateau",
+			Quality: onesQuality,
+			Options: []Option{MultiObjective(func(c *Chromosome) ([]float64, error) {
+				return []float64{1, 2}, nil
+			})},
+			Population:  testEnginePopulation(8),
+			Config:      []EngineOption{Plateau(2, 0)},
+			Generations: 3,
+			Stop:        StopPlateau,
+		},
+		{
+			Name:       "Empty Population",
+			Quality:    onesQuality,
+			Population: []*Chromosome{},
+			Error:      ErrorEmptyPopulation,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reported []Statistics
+			g := New(tc.Quality, append([]Option{RandomSource(NewSeededSource(1))}, tc.Options...)...)
+			config := append([]EngineOption{
+				EngineSource(NewSeededSource(2)),
+				PopulationSize(8, 4),
+				Rates(0.1, 0.5),
+				Report(func(s Statistics) {
+					reported = append(reported, s)
+				}),
+			}, tc.Config...)
+
+			result, err := NewEngine(g, config...).Run(tc.Population)
+			if tc.Error != nil {
+				assert.Equal(tc.Error, err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.Stop, result.Stop)
+			assert.Equal(tc.Generations, len(result.Generations))
+			assert.Equal(result.Generations, reported)
+			for i, s := range result.Generations {
+				assert.Equal(i, s.Generation)
+				assert.True(s.Worst <= s.Mean && s.Mean <= s.Best)
+				if tc.Options != nil {
+					assert.Len(s.Objectives, 2)
+					assert.True(s.Front > 0)
+				}
+			}
+
+			// the result of the run can be encoded with its reproduced population
+			b, err := json.Marshal(result)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			decoded := &Result{}
+			if err := json.Unmarshal(b, decoded); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.Len(decoded.Population, len(result.Population))
+			for i, c := range decoded.Population {
+				c.Link()
+				assert.Equal(testValues(result.Population[i]), testValues(c))
+				testParentLinks(t, c.Root)
+			}
+		})
+	}
+}
+
+func TestReproduce(t *testing.T) {
+	assert := assert.New(t)
+	g := New(onesQuality, RandomSource(constant(0.9)))
+	selected := []*Chromosome{
+		testChromosome("1", []float64{1, 1}, []float64{1}),
+		testChromosome("2", []float64{0, 0}, []float64{0}),
+	}
+
+	t.Run("Mutants", func(t *testing.T) {
+		population, err := Reproduce(g, selected, 5, 0.0, 0.5, constant(0.9))
+		assert.Nil(err)
+		assert.Equal([]string{"1", "2", "", "", ""}, testIDs(population))
+		assert.Equal(testValues(selected[0]), testValues(population[2]))
+		assert.Equal(testValues(selected[1]), testValues(population[3]))
+		assert.Equal(0.0, population[2].Quality)
+	})
+
+	t.Run("Crossover", func(t *testing.T) {
+		population, err := Reproduce(g, selected, 3, 0.0, 0.5, constant(0.1))
+		assert.Nil(err)
+		assert.Equal(3, len(population))
+		testParentLinks(t, population[2].Root)
+	})
+
+	t.Run("Empty Selection", func(t *testing.T) {
+		_, err := Reproduce(g, nil, 3, 0.0, 0.5, constant(0.1))
+		assert.Equal(ErrorEmptyPopulation, err)
+	})
+}
+
+func TestDiversity(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Population []*Chromosome
+		Expected   float64
+	}{
+		{
+			Name: "Clones",
+			Population: []*Chromosome{
+				testChromosome("1", []float64{1, 0}, []float64{1, 0}),
+				testChromosome("2", []float64{1, 0}, []float64{1, 0}),
+			},
+			Expected: 0,
+		},
+		{
+			Name: "Opposites",
+			Population: []*Chromosome{
+				testChromosome("1", []float64{1, 0}, []float64{1, 0}),
+				testChromosome("2", []float64{0, 1}, []float64{0, 1}),
+			},
+			Expected: 1,
+		},
+		{
+			Name: "Mean Of Pairs",
+			// distances are 1/4, 1/4 and 0
+			Population: []*Chromosome{
+				testChromosome("1", []float64{1, 0}, []float64{1, 0}),
+				testChromosome("2", []float64{1, 0}, []float64{1, 1}),
+				testChromosome("3", []float64{1, 0}, []float64{1, 1}),
+			},
+			Expected: 1.0 / 6,
+		},
+		{
+			Name:       "Single Chromosome",
+			Population: []*Chromosome{testChromosome("1", []float64{1}, nil)},
+			Expected:   0,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.InDelta(tc.Expected, Diversity(tc.Population), 1e-9)
+		})
+	}
+}
@@ -2,15 +2,19 @@ package genetic
 
 import (
 	"errors"
+	"math"
 	"sort"
-	"sync"
 )
 
 type facebook struct {
-	distribution func() float64
-	quality      func(c *Chromosome) (float64, error)
-	fitness      func(population []*Chromosome, q ...float64) error
-	selection    func(population []*Chromosome, size int, d func() float64, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error)
+	random    Source
+	quality   func(c *Chromosome) (float64, error)
+	fitness   func(population []*Chromosome, q ...float64) error
+	selection func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error)
+	crossover func(a, b *Chromosome, d Source) (*Chromosome, *Chromosome, error)
+	// objectives replaces the quality function in
+	// the multi objective optimization
+	objectives func(c *Chromosome) ([]float64, error)
 }
 
 var (
@@ -20,15 +24,47 @@ var (
 	ErrorInvalidPopulation = errors.New("The population size can't be less than or equal to selection size")
 )
 
+// Option configures the genetic algorithm returned by New
+type Option func(*facebook)
+
 // New initialices standard fitness and standard selection genetic algorithm
-// to use with facebook data
-func New(quality func(c *Chromosome) (float64, error)) Genetic {
-	return &facebook{
-		distribution: generateRandom,
-		quality:      quality,
-		fitness:      standardFitness,
-		selection:    standardSelection,
+// to use with facebook data, the crossover strategy defaults to uniform crossover
+// and the random source defaults to crypto/rand
+func New(quality func(c *Chromosome) (float64, error), config ...Option) Genetic {
+	f := &facebook{
+		random:    NewCryptoSource(),
+		quality:   quality,
+		fitness:   standardFitness,
+		crossover: uniformCrossover,
+	}
+
+	for _, fn := range config {
+		fn(f)
+	}
+	// the multi objective optimization only works with the crowded selection
+	// since the selection strategies use the quality, which isn't set
+	switch {
+	case f.objectives != nil && f.selection != nil:
+		f.selection = failedSelection(ErrorObjectivesSelection)
+	case f.objectives != nil:
+		f.selection = crowdedSelection
+	case f.selection == nil:
+		f.selection = standardSelection
 	}
+
+	return f
+}
+
+// UniformCrossover configures the algorithm to exchange each leaf gene
+// between the parents with equal probability
+func UniformCrossover(f *facebook) {
+	f.crossover = uniformCrossover
+}
+
+// SinglePointCrossover configures the algorithm to exchange whole top level
+// targeting categories (behaviors, interests...) after a random cut point
+func SinglePointCrossover(f *facebook) {
+	f.crossover = singlePointCrossover
 }
 
 func (f *facebook) Genesis(c *Chromosome) map[string][]*Gene {
@@ -54,22 +90,24 @@ func (f *facebook) Genesis(c *Chromosome) map[string][]*Gene {
 	return result
 }
 
-func (f *facebook) Mutate(c *Chromosome, rate float64) {
-	var wg sync.WaitGroup
-	go func(wg *sync.WaitGroup) {
-		wg.Add(1)
-		binaryMutation(c.Root, f.distribution, rate, wg)
-		wg.Done()
-	}(&wg)
-
-	wg.Wait()
+func (f *facebook) Mutate(c *Chromosome, rate float64) error {
+	return binaryMutation(c.Root, f.random, rate)
 }
 
-func binaryMutation(gene *Gene, d func() float64, rate float64, wg *sync.WaitGroup) {
+// binaryMutation walks the tree sequentially so a seeded source
+// always assigns the same samples to the same genes
+func binaryMutation(gene *Gene, d Source, rate float64) error {
+	if gene == nil {
+		return nil
+	}
 	// In the facebook Graph only leaf nodes have an id and therefore
 	// the function can return after finding a leave node.
 	if gene.ID != "" {
-		if d() <= rate {
+		r, err := sample(d)
+		if err != nil {
+			return err
+		}
+		if r < rate {
 			if gene.Value == 1 {
 				gene.Value = 0
 			} else {
@@ -78,15 +116,104 @@ func binaryMutation(gene *Gene, d func() float64, rate float64, wg *sync.WaitGro
 		}
 	}
 	for i := 0; i < len(gene.Children); i++ {
-		go func(wg *sync.WaitGroup, i int) {
-			wg.Add(1)
-			binaryMutation(gene.Children[i], d, rate, wg)
-			wg.Done()
-		}(wg, i)
+		if err := binaryMutation(gene.Children[i], d, rate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *facebook) Crossover(a, b *Chromosome) (*Chromosome, *Chromosome, error) {
+	return f.crossover(a, b, f.random)
+}
+
+// uniformCrossover walks both parents' trees at the same time and swaps
+// the value of every leaf gene between the offspring with probability 0.5
+func uniformCrossover(a, b *Chromosome, d Source) (*Chromosome, *Chromosome, error) {
+	x, y := offspring(a), offspring(b)
+	if err := uniformSwap(x.Root, y.Root, d); err != nil {
+		return nil, nil, err
+	}
+
+	return x, y, nil
+}
+
+func uniformSwap(x, y *Gene, d Source) error {
+	if x == nil || y == nil {
+		return nil
+	}
+	if x.ID != "" && x.ID == y.ID {
+		r, err := sample(d)
+		if err != nil {
+			return err
+		}
+		if r < 0.5 {
+			x.Value, y.Value = y.Value, x.Value
+		}
+	}
+	for i := 0; i < len(x.Children) && i < len(y.Children); i++ {
+		if err := uniformSwap(x.Children[i], y.Children[i], d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// singlePointCrossover selects a cut point over the top level categories of the
+// targeting tree and exchanges every category after it between the offspring
+func singlePointCrossover(a, b *Chromosome, d Source) (*Chromosome, *Chromosome, error) {
+	x, y := offspring(a), offspring(b)
+	if x.Root == nil || y.Root == nil {
+		return x, y, nil
+	}
+
+	n := len(x.Root.Children)
+	if len(y.Root.Children) < n {
+		n = len(y.Root.Children)
+	}
+	if n < 2 {
+		return x, y, nil
+	}
+
+	r, err := sample(d)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the cut point is in [1, n-1] so both offspring keep at
+	// least one category from each parent
+	point := 1 + int(r*float64(n-1))
+	for i := point; i < n; i++ {
+		x.Root.Children[i], y.Root.Children[i] = y.Root.Children[i], x.Root.Children[i]
+		x.Root.Children[i].Parent = x.Root
+		y.Root.Children[i].Parent = y.Root
+	}
+
+	return x, y, nil
+}
+
+// offspring creates a new chromosome with a copy of the parent's targeting tree,
+// the offspring has no ad set associated and therefore no quality or fitness
+func offspring(c *Chromosome) *Chromosome {
+	return &Chromosome{
+		Root: c.Root.Clone(),
 	}
 }
 
 func (f *facebook) Fitness(population []*Chromosome) error {
+	if f.objectives != nil {
+		for i := 0; i < len(population); i++ {
+			o, err := f.objectives(population[i])
+			if err != nil {
+				return err
+			}
+			population[i].Objectives = o
+		}
+
+		return paretoFitness(population)
+	}
+
 	var q float64
 	for i := 0; i < len(population); i++ {
 		qi, err := f.quality(population[i])
@@ -100,23 +227,36 @@ func (f *facebook) Fitness(population []*Chromosome) error {
 	return f.fitness(population, q)
 }
 
-// standardFitness computes the fitness of a chromosome relative to the overall fitness of the population
+// standardFitness computes the fitness of a chromosome relative to the overall fitness of the population,
+// negative qualities are shifted like the sampling weights so the worst chromosome has no fitness and
+// the population gets a uniform fitness when the total can't be used
 func standardFitness(population []*Chromosome, q ...float64) error {
 	if len(q) != 1 {
 		return ErrorInvalidQualityStandardFitness
 	}
+	min := 0.0
+	for _, c := range population {
+		if c.Quality < min {
+			min = c.Quality
+		}
+	}
+	total := q[0] - min*float64(len(population))
 	for i := 0; i < len(population); i++ {
-		population[i].Fitness = population[i].Quality / q[0]
+		if total <= 0 || math.IsNaN(total) || math.IsInf(total, 0) {
+			population[i].Fitness = 1 / float64(len(population))
+		} else {
+			population[i].Fitness = (population[i].Quality - min) / total
+		}
 	}
 
 	return nil
 }
 
 func (f *facebook) Selection(population []*Chromosome, size int) ([]*Chromosome, error) {
-	return f.selection(population, size, f.distribution, standardFitness)
+	return f.selection(population, size, f.random, standardFitness)
 }
 
-func standardSelection(population []*Chromosome, size int, d func() float64, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+func standardSelection(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
 	if len(population) == size {
 		return population, nil
 	}
@@ -124,26 +264,31 @@ func standardSelection(population []*Chromosome, size int, d func() float64, fit
 		return nil, ErrorInvalidPopulation
 	}
 
+	// the selected chromosomes are removed from a copy
+	// so the caller's population isn't modified
+	population = append([]*Chromosome{}, population...)
 	selected := make([]*Chromosome, size)
 
 	for i := 0; i < len(selected); i++ {
 		f := cumulative(population)
-		idx := sort.SearchFloat64s(f, d())
+		r, err := sample(d)
+		if err != nil {
+			return nil, err
+		}
+		idx := sort.SearchFloat64s(f, r)
 		selected[i] = population[idx]
 		if idx == len(population) {
 			population = population[:idx-1]
 		} else {
 			population = append(population[:idx], population[idx+1:]...)
 		}
-		err := fitness(population, sumQuality(population))
+		err = fitness(population, sumQuality(population))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	sort.SliceStable(selected, func(i, j int) bool {
-		return selected[i].Quality > selected[j].Quality
-	})
+	sortByQuality(selected)
 
 	return selected, nil
 }
@@ -0,0 +1,431 @@
+package genetic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+)
+
+// constant is a source that always samples the same value
+type constant float64
+
+func (c constant) Float64() (float64, error) {
+	return float64(c), nil
+}
+
+// testChromosome creates a targeting tree with two top level categories
+// and a leaf gene for each of the provided values
+func testChromosome(id string, behaviors, interests []float64) *Chromosome {
+	root := &Gene{Name: "root"}
+	b := &Gene{Name: "Behaviors", Parent: root}
+	i := &Gene{Name: "Interests", Parent: root}
+	for n, v := range behaviors {
+		b.Children = append(b.Children, &Gene{ID: string(rune('a' + n)), Type: "behaviors", Value: v, Parent: b})
+	}
+	for n, v := range interests {
+		i.Children = append(i.Children, &Gene{ID: string(rune('A' + n)), Type: "interests", Value: v, Parent: i})
+	}
+	root.Children = []*Gene{b, i}
+
+	return &Chromosome{
+		ID:      id,
+		Root:    root,
+		Quality: 1,
+		Fitness: 0.5,
+	}
+}
+
+func testValues(c *Chromosome) [][]float64 {
+	values := [][]float64{}
+	for _, category := range c.Root.Children {
+		v := []float64{}
+		for _, gene := range category.Children {
+			v = append(v, gene.Value)
+		}
+		values = append(values, v)
+	}
+
+	return values
+}
+
+func testParentLinks(t *testing.T, g *Gene) {
+	t.Helper()
+
+	for _, child := range g.Children {
+		if child.Parent != g {
+			t.Fatalf("gene %s doesn't point to its parent", child.Name)
+		}
+		testParentLinks(t, child)
+	}
+}
+
+func TestCrossover(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Strategy  Option
+		Source    Source
+		ExpectedA [][]float64
+		ExpectedB [][]float64
+	}{
+		{
+			Name:      "Uniform Crossover Swap All",
+			Strategy:  UniformCrossover,
+			Source:    constant(0.0),
+			ExpectedA: [][]float64{{0, 0}, {0, 0}},
+			ExpectedB: [][]float64{{1, 1}, {1, 1}},
+		},
+		{
+			Name:      "Uniform Crossover Swap None",
+			Strategy:  UniformCrossover,
+			Source:    constant(0.9),
+			ExpectedA: [][]float64{{1, 1}, {1, 1}},
+			ExpectedB: [][]float64{{0, 0}, {0, 0}},
+		},
+		{
+			Name:      "Single Point Crossover",
+			Strategy:  SinglePointCrossover,
+			Source:    constant(0.99),
+			ExpectedA: [][]float64{{1, 1}, {0, 0}},
+			ExpectedB: [][]float64{{0, 0}, {1, 1}},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			g := New(nil, tc.Strategy, RandomSource(tc.Source))
+			a := testChromosome("1", []float64{1, 1}, []float64{1, 1})
+			b := testChromosome("2", []float64{0, 0}, []float64{0, 0})
+
+			x, y, err := g.Crossover(a, b)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.Equal(tc.ExpectedA, testValues(x))
+			assert.Equal(tc.ExpectedB, testValues(y))
+			testParentLinks(t, x.Root)
+			testParentLinks(t, y.Root)
+			// offspring can be stored with their parent links
+			testMarshal(t, x)
+			testMarshal(t, y)
+
+			// offspring are new ad sets
+			assert.Equal("", x.ID)
+			assert.Equal(0.0, x.Quality)
+
+			// parents are not modified by the crossover
+			assert.Equal([][]float64{{1, 1}, {1, 1}}, testValues(a))
+			assert.Equal([][]float64{{0, 0}, {0, 0}}, testValues(b))
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	assert := assert.New(t)
+
+	c := testChromosome("1", []float64{1, 0}, []float64{0, 1})
+	clone := c.Clone()
+
+	assert.Equal(c.ID, clone.ID)
+	assert.Equal(c.Quality, clone.Quality)
+	assert.Equal(c.Fitness, clone.Fitness)
+	assert.Equal(testValues(c), testValues(clone))
+	assert.Nil(clone.Root.Parent)
+	testParentLinks(t, clone.Root)
+
+	// the clone doesn't share genes with the original tree
+	clone.Root.Children[0].Children[0].Value = 0
+	clone.Root.Children[1].Children = nil
+	assert.Equal([][]float64{{1, 0}, {0, 1}}, testValues(c))
+
+	// cloning a subtree detaches it from the original tree
+	g := c.Root.Children[1].Clone()
+	assert.Nil(g.Parent)
+	assert.Equal("Interests", g.Name)
+	testParentLinks(t, g)
+}
+
+// testMarshal encodes the chromosome as json and as a dynamo attribute, the decoded
+// chromosomes have the same targeting and their parent links are rebuilt by Link
+func testMarshal(t *testing.T, c *Chromosome) {
+	t.Helper()
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	decoded := &Chromosome{}
+	if err := json.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	decoded.Link()
+	assert.Equal(t, testValues(c), testValues(decoded))
+	testParentLinks(t, decoded.Root)
+
+	av, err := dynamodbattribute.Marshal(c)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	decoded = &Chromosome{}
+	if err := dynamodbattribute.Unmarshal(av, decoded); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	decoded.Link()
+	assert.Equal(t, testValues(c), testValues(decoded))
+	testParentLinks(t, decoded.Root)
+}
+
+func TestMarshal(t *testing.T) {
+	c := testChromosome("1", []float64{1, 0}, []float64{0, 1})
+	testMarshal(t, c.Clone())
+	testMarshal(t, offspring(c))
+}
+
+func TestMutate(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Rate     float64
+		Expected [][]float64
+	}{
+		{
+			Name:     "Mutate All Genes",
+			Rate:     1.0,
+			Expected: [][]float64{{0, 1}, {1, 0}},
+		},
+		{
+			Name:     "Mutate No Genes",
+			Rate:     0.0,
+			Expected: [][]float64{{1, 0}, {0, 1}},
+		},
+	}
+	assert := assert.New(t)
+
+	g := New(nil, RandomSource(constant(0.5)))
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			parent := testChromosome("1", []float64{1, 0}, []float64{0, 1})
+			c := parent.Clone()
+			err := g.Mutate(c, tc.Rate)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			assert.Equal(tc.Expected, testValues(c))
+			// the parent tree stays untouched after the mutation
+			assert.Equal([][]float64{{1, 0}, {0, 1}}, testValues(parent))
+		})
+	}
+}
+
+func TestBinaryMutation(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Samples  []float64
+		Expected [][]float64
+		Error    error
+	}{
+		{
+			Name:     "Replay Samples",
+			Samples:  []float64{0.05, 0.5, 0.5, 0.09},
+			Expected: [][]float64{{0, 0}, {0, 0}},
+			Error:    nil,
+		},
+		{
+			Name:    "Exhausted Source",
+			Samples: []float64{0.05, 0.5},
+			Error:   ErrorSourceExhausted,
+		},
+		{
+			Name:    "Invalid Sample",
+			Samples: []float64{1.5, 0.5, 0.5, 0.5},
+			Error:   ErrorInvalidSample,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := testChromosome("1", []float64{1, 0}, []float64{0, 1})
+			err := binaryMutation(c.Root, NewReplaySource(tc.Samples), 0.1)
+			assert.Equal(tc.Error, err)
+			if tc.Error == nil {
+				assert.Equal(tc.Expected, testValues(c))
+			}
+		})
+	}
+}
+
+func TestStandardSelection(t *testing.T) {
+	var population = func() []*Chromosome {
+		population := []*Chromosome{}
+		for _, q := range []float64{1, 2, 3, 4} {
+			population = append(population, &Chromosome{
+				ID:      fmt.Sprint(q),
+				Quality: q,
+			})
+		}
+
+		return population
+	}
+	cases := []struct {
+		Name     string
+		Samples  []float64
+		Size     int
+		Expected []string
+		Error    error
+	}{
+		{
+			Name: "Replay Samples",
+			// the first sample selects chromosome 4 from [0.1, 0.3, 0.6, 1]
+			// and the second selects chromosome 1 from [1/6, 1/2, 1]
+			Samples:  []float64{0.7, 0.1},
+			Size:     2,
+			Expected: []string{"4", "1"},
+			Error:    nil,
+		},
+		{
+			Name:     "Whole Population",
+			Samples:  []float64{},
+			Size:     4,
+			Expected: []string{"1", "2", "3", "4"},
+			Error:    nil,
+		},
+		{
+			Name:    "Invalid Population",
+			Samples: []float64{},
+			Size:    5,
+			Error:   ErrorInvalidPopulation,
+		},
+		{
+			Name:    "Exhausted Source",
+			Samples: []float64{0.7},
+			Size:    2,
+			Error:   ErrorSourceExhausted,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p := population()
+			err := standardFitness(p, sumQuality(p))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			selected, err := standardSelection(p, tc.Size, NewReplaySource(tc.Samples), standardFitness)
+			assert.Equal(tc.Error, err)
+			if tc.Error == nil {
+				ids := []string{}
+				for _, c := range selected {
+					ids = append(ids, c.ID)
+				}
+				assert.Equal(tc.Expected, ids)
+			}
+		})
+	}
+}
+
+func TestStandardFitness(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Qualities []float64
+		Expected  []float64
+	}{
+		{
+			Name:      "Positive Qualities",
+			Qualities: []float64{1, 3},
+			Expected:  []float64{0.25, 0.75},
+		},
+		{
+			Name:      "Zero Qualities",
+			Qualities: []float64{0, 0, 0, 0},
+			Expected:  []float64{0.25, 0.25, 0.25, 0.25},
+		},
+		{
+			Name:      "Negative Qualities",
+			Qualities: []float64{-2, -1, 1},
+			Expected:  []float64{0, 0.25, 0.75},
+		},
+		{
+			Name:      "Equal Negative Qualities",
+			Qualities: []float64{-1, -1},
+			Expected:  []float64{0.5, 0.5},
+		},
+		{
+			Name:      "Infinite Quality",
+			Qualities: []float64{math.Inf(1), 1},
+			Expected:  []float64{0.5, 0.5},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p := []*Chromosome{}
+			for _, q := range tc.Qualities {
+				p = append(p, &Chromosome{Quality: q})
+			}
+			assert.Nil(standardFitness(p, sumQuality(p)))
+			for i, c := range p {
+				assert.InDelta(tc.Expected[i], c.Fitness, 1e-9)
+			}
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("Seeded Source", func(t *testing.T) {
+		a, b := NewSeededSource(42), NewSeededSource(42)
+		for i := 0; i < 100; i++ {
+			x, err := a.Float64()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			y, err := b.Float64()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.Equal(x, y)
+		}
+	})
+
+	t.Run("Crypto Source", func(t *testing.T) {
+		s := NewCryptoSource()
+		for i := 0; i < 100; i++ {
+			v, err := sample(s)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.True(v >= 0.0 && v < 1.0)
+		}
+	})
+
+	t.Run("Record And Replay", func(t *testing.T) {
+		r := NewRecordingSource(NewSeededSource(7))
+		recorded := testMutations(t, New(nil, RandomSource(r)))
+
+		replayed := testMutations(t, New(nil, RandomSource(NewReplaySource(r.Values()))))
+		assert.Equal(recorded, replayed)
+	})
+}
+
+// testMutations mutates the same chromosome a few times and returns the result
+func testMutations(t *testing.T, g Genetic) [][]float64 {
+	t.Helper()
+
+	c := testChromosome("1", []float64{1, 0, 1}, []float64{0, 1, 0})
+	for i := 0; i < 5; i++ {
+		if err := g.Mutate(c, 0.3); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	return testValues(c)
+}
@@ -2,7 +2,8 @@ package genetic
 
 // Genetic is an object in charge of performing the basic functions of a genetic algorithm
 type Genetic interface {
-	Mutate(c *Chromosome, rate float64)
+	Mutate(c *Chromosome, rate float64) error
+	Crossover(a, b *Chromosome) (*Chromosome, *Chromosome, error)
 	Fitness(population []*Chromosome) error
 	Selection(population []*Chromosome, size int) ([]*Chromosome, error)
 	Genesis(c *Chromosome) map[string][]*Gene
@@ -15,17 +16,91 @@ type Chromosome struct {
 	Root    *Gene
 	Fitness float64
 	Quality float64
+	// Objectives, Rank and Crowding are only used by the multi objective
+	// optimization, Rank is the Pareto front of the chromosome starting at
+	// zero and Crowding its crowding distance inside the front
+	Objectives []float64
+	Rank       int
+	Crowding   float64
 }
 
 // Gene is used to configure the result of targeting required
 // by the caller
 type Gene struct {
-	ID       string
-	Name     string
-	Type     string
-	Value    float64
-	Parent   *Gene
+	ID    string
+	Name  string
+	Type  string
+	Value float64
+	// Parent isn't encoded since it makes the tree a cyclic graph,
+	// Link rebuilds it after the tree is decoded
+	Parent   *Gene `json:"-" dynamodbav:"-"`
 	Children []*Gene
 }
 
+// Clone returns a deep copy of the chromosome, the targeting tree of
+// the copy doesn't share any gene with the original tree
+func (c *Chromosome) Clone() *Chromosome {
+	var objectives []float64
+	if c.Objectives != nil {
+		objectives = make([]float64, len(c.Objectives))
+		copy(objectives, c.Objectives)
+	}
+
+	return &Chromosome{
+		ID:         c.ID,
+		Root:       c.Root.Clone(),
+		Fitness:    c.Fitness,
+		Quality:    c.Quality,
+		Objectives: objectives,
+		Rank:       c.Rank,
+		Crowding:   c.Crowding,
+	}
+}
+
+// Clone returns a deep copy of the subtree rooted at the gene, the Parent
+// of every copied gene points to its copied parent and the returned gene
+// is detached from the original tree
+func (g *Gene) Clone() *Gene {
+	return g.clone(nil)
+}
+
+func (g *Gene) clone(parent *Gene) *Gene {
+	if g == nil {
+		return nil
+	}
+	n := &Gene{
+		ID:     g.ID,
+		Name:   g.Name,
+		Type:   g.Type,
+		Value:  g.Value,
+		Parent: parent,
+	}
+	if g.Children != nil {
+		n.Children = make([]*Gene, len(g.Children))
+		for i := range g.Children {
+			n.Children[i] = g.Children[i].clone(n)
+		}
+	}
+
+	return n
+}
+
+// Link sets the Parent of every gene of the targeting tree, it's used
+// to rebuild the links of a decoded chromosome
+func (c *Chromosome) Link() {
+	if c.Root == nil {
+		return
+	}
+	c.Root.link(nil)
+}
+
+func (g *Gene) link(parent *Gene) {
+	g.Parent = parent
+	for _, child := range g.Children {
+		if child != nil {
+			child.link(g)
+		}
+	}
+}
+
 // Insert, Search and Delete
@@ -0,0 +1,167 @@
+package genetic
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	// ErrorInvalidObjectives the chromosomes of the population don't have the same number of objectives
+	ErrorInvalidObjectives = errors.New("The chromosomes must have the same number of objectives")
+	// ErrorObjectivesSelection a selection strategy was configured with the multi objective optimization
+	ErrorObjectivesSelection = errors.New("The multi objective optimization can't use a selection strategy")
+)
+
+// MultiObjective configures the algorithm to optimize a vector of objectives
+// instead of a single quality, every objective is maximized so costs must be
+// negated by the caller. Fitness ranks the population in Pareto fronts and the
+// selection keeps the best fronts breaking ties with the crowding distance. The
+// selection strategies can't be combined with it, whatever the options order the
+// selection of an algorithm configured with both fails with ErrorObjectivesSelection
+func MultiObjective(objectives func(c *Chromosome) ([]float64, error)) Option {
+	return func(f *facebook) {
+		f.objectives = objectives
+	}
+}
+
+// dominates returns true when a is at least as good as b in every
+// objective and strictly better in at least one of them
+func dominates(a, b *Chromosome) bool {
+	better := false
+	for m := range a.Objectives {
+		if a.Objectives[m] < b.Objectives[m] {
+			return false
+		}
+		if a.Objectives[m] > b.Objectives[m] {
+			better = true
+		}
+	}
+
+	return better
+}
+
+// nonDominatedSort splits the population in Pareto fronts, the first front contains
+// the chromosomes that aren't dominated by any other chromosome of the population
+func nonDominatedSort(population []*Chromosome) [][]*Chromosome {
+	var (
+		fronts    = [][]*Chromosome{}
+		dominated = make([][]int, len(population))
+		count     = make([]int, len(population))
+		current   = []int{}
+	)
+
+	for p := range population {
+		for q := range population {
+			switch {
+			case p == q:
+			case dominates(population[p], population[q]):
+				dominated[p] = append(dominated[p], q)
+			case dominates(population[q], population[p]):
+				count[p]++
+			}
+		}
+		if count[p] == 0 {
+			current = append(current, p)
+		}
+	}
+
+	for rank := 0; len(current) > 0; rank++ {
+		front := []*Chromosome{}
+		next := []int{}
+		for _, p := range current {
+			population[p].Rank = rank
+			front = append(front, population[p])
+			for _, q := range dominated[p] {
+				count[q]--
+				if count[q] == 0 {
+					next = append(next, q)
+				}
+			}
+		}
+		fronts = append(fronts, front)
+		current = next
+	}
+
+	return fronts
+}
+
+// crowdingDistance sets the crowding distance of the chromosomes of a front, the
+// boundary chromosomes of every objective get the largest possible distance so
+// they are always preferred. math.MaxFloat64 is used instead of an infinite
+// distance because chromosomes are stored as json and dynamo attributes
+func crowdingDistance(front []*Chromosome) {
+	for _, c := range front {
+		c.Crowding = 0
+	}
+	if len(front) == 0 {
+		return
+	}
+
+	sorted := append([]*Chromosome{}, front...)
+	for m := range front[0].Objectives {
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Objectives[m] < sorted[j].Objectives[m]
+		})
+		min, max := sorted[0].Objectives[m], sorted[len(sorted)-1].Objectives[m]
+		sorted[0].Crowding = math.MaxFloat64
+		sorted[len(sorted)-1].Crowding = math.MaxFloat64
+		if max == min {
+			continue
+		}
+		for i := 1; i < len(sorted)-1; i++ {
+			if sorted[i].Crowding == math.MaxFloat64 {
+				continue
+			}
+			sorted[i].Crowding += (sorted[i+1].Objectives[m] - sorted[i-1].Objectives[m]) / (max - min)
+		}
+	}
+}
+
+// paretoFitness ranks the population and sets a fitness that decreases with the
+// rank of the chromosome, the fitness of the population adds up to one so it can
+// be used like the standard fitness
+func paretoFitness(population []*Chromosome) error {
+	for _, c := range population {
+		if len(c.Objectives) != len(population[0].Objectives) {
+			return ErrorInvalidObjectives
+		}
+	}
+
+	fronts := nonDominatedSort(population)
+	var total float64
+	for _, front := range fronts {
+		crowdingDistance(front)
+		total += float64(len(front) * (len(fronts) - front[0].Rank))
+	}
+	for _, c := range population {
+		c.Fitness = float64(len(fronts)-c.Rank) / total
+	}
+
+	return nil
+}
+
+// failedSelection returns a selection that always fails with the error
+func failedSelection(err error) func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+	return func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+		return nil, err
+	}
+}
+
+// crowdedSelection selects the chromosomes of the best fronts, the last front that
+// doesn't fit in the selection is sorted by crowding distance to keep diversity
+func crowdedSelection(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+	if len(population) < size {
+		return nil, ErrorInvalidPopulation
+	}
+
+	selected := append([]*Chromosome{}, population...)
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Rank != selected[j].Rank {
+			return selected[i].Rank < selected[j].Rank
+		}
+		return selected[i].Crowding > selected[j].Crowding
+	})
+
+	return selected[:size], nil
+}
@@ -0,0 +1,142 @@
+package genetic
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testObjectivesPopulation(objectives ...[]float64) []*Chromosome {
+	population := []*Chromosome{}
+	for i, o := range objectives {
+		population = append(population, &Chromosome{
+			ID:         string(rune('a' + i)),
+			Objectives: o,
+		})
+	}
+
+	return population
+}
+
+func TestNonDominatedSort(t *testing.T) {
+	assert := assert.New(t)
+
+	// reach against negated cost
+	population := testObjectivesPopulation(
+		[]float64{10, -1},
+		[]float64{20, -2},
+		[]float64{5, -3},
+		[]float64{15, -2},
+		[]float64{30, -5},
+	)
+	fronts := nonDominatedSort(population)
+
+	assert.Equal(3, len(fronts))
+	assert.Equal([]string{"a", "b", "e"}, testIDs(fronts[0]))
+	assert.Equal([]string{"d"}, testIDs(fronts[1]))
+	assert.Equal([]string{"c"}, testIDs(fronts[2]))
+	assert.Equal([]int{0, 0, 2, 1, 0}, []int{population[0].Rank, population[1].Rank, population[2].Rank, population[3].Rank, population[4].Rank})
+}
+
+func TestCrowdingDistance(t *testing.T) {
+	assert := assert.New(t)
+
+	front := testObjectivesPopulation(
+		[]float64{0, -4},
+		[]float64{1, -3},
+		[]float64{3, -1},
+		[]float64{4, 0},
+	)
+	crowdingDistance(front)
+
+	assert.Equal(math.MaxFloat64, front[0].Crowding)
+	assert.Equal(math.MaxFloat64, front[3].Crowding)
+	assert.InDelta(1.5, front[1].Crowding, 1e-9)
+	assert.InDelta(1.5, front[2].Crowding, 1e-9)
+}
+
+func TestMultiObjective(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Options func(objectives Option) []Option
+		// Error is the error of the selection
+		Error error
+	}{
+		{
+			Name: "Crowded Selection",
+			Options: func(objectives Option) []Option {
+				return []Option{objectives, SinglePointCrossover}
+			},
+		},
+		{
+			Name: "Selection Strategy After Objectives",
+			Options: func(objectives Option) []Option {
+				return []Option{objectives, TournamentSelection(2)}
+			},
+			Error: ErrorObjectivesSelection,
+		},
+		{
+			Name: "Selection Strategy Before Objectives",
+			Options: func(objectives Option) []Option {
+				return []Option{RankSelection(1.5), objectives}
+			},
+			Error: ErrorObjectivesSelection,
+		},
+		{
+			Name: "Default Selection Strategy",
+			Options: func(objectives Option) []Option {
+				return []Option{RouletteSelection, objectives}
+			},
+			Error: ErrorObjectivesSelection,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			objectives := map[string][]float64{
+				"a": {10, -1},
+				"b": {20, -2},
+				"c": {5, -3},
+				"d": {15, -2},
+				"e": {30, -5},
+				"f": {25, -4},
+			}
+			g := New(nil, tc.Options(MultiObjective(func(c *Chromosome) ([]float64, error) {
+				return objectives[c.ID], nil
+			}))...)
+
+			population := testObjectivesPopulation(make([][]float64, 6)...)
+			err := g.Fitness(population)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			var total float64
+			for _, c := range population {
+				total += c.Fitness
+			}
+			assert.InDelta(1.0, total, 1e-9)
+			assert.True(population[0].Fitness > population[2].Fitness)
+
+			// the first front is a, b, f and e, the boundaries a and e have the
+			// maximum crowding distance and b (1.5) is less crowded than f (1.25)
+			selected, err := g.Selection(population, 3)
+			if tc.Error != nil {
+				assert.Equal(tc.Error, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			assert.Equal([]string{"a", "e", "b"}, testIDs(selected))
+
+			_, err = g.Selection(population, 7)
+			assert.Equal(ErrorInvalidPopulation, err)
+
+			objectives["a"] = []float64{1}
+			err = g.Fitness(population)
+			assert.Equal(ErrorInvalidObjectives, err)
+		})
+	}
+}
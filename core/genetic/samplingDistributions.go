@@ -1,18 +1,150 @@
 package genetic
 
 import (
-	"crypto/rand"
-	"log"
+	crand "crypto/rand"
+	"errors"
 	"math/big"
+	"math/rand"
+	"sync"
 )
 
-// sample uniform from 0 - 1
-func generateRandom() float64 {
-	n, err := rand.Int(rand.Reader, big.NewInt(100))
+// resolution is the number of values that can be sampled by the crypto source,
+// 2^53 is the largest power of two for which every value is exactly representable
+// as a float64
+const resolution = 1 << 53
+
+var (
+	// ErrorSourceExhausted the replay source has no more recorded values
+	ErrorSourceExhausted = errors.New("the replay source has no more values to sample")
+	// ErrorInvalidSample the source returned a value outside of [0, 1)
+	ErrorInvalidSample = errors.New("the sampled value is outside of the [0, 1) interval")
+)
+
+// Source is a uniform distribution over [0, 1) used by the genetic
+// operators to take random decisions
+type Source interface {
+	Float64() (float64, error)
+}
+
+// RandomSource configures the source of randomness of the algorithm
+func RandomSource(s Source) Option {
+	return func(f *facebook) {
+		f.random = s
+	}
+}
+
+// sample draws a value from the source checking that it is a valid probability
+func sample(s Source) (float64, error) {
+	v, err := s.Float64()
+	if err != nil {
+		return 0.0, err
+	}
+	if v < 0.0 || v >= 1.0 {
+		return 0.0, ErrorInvalidSample
+	}
+
+	return v, nil
+}
+
+type cryptoSource struct{}
+
+// NewCryptoSource returns a non reproducible source backed by crypto/rand
+func NewCryptoSource() Source {
+	return cryptoSource{}
+}
+
+func (cryptoSource) Float64() (float64, error) {
+	n, err := crand.Int(crand.Reader, big.NewInt(resolution))
+	if err != nil {
+		return 0.0, err
+	}
+
+	return float64(n.Int64()) / resolution, nil
+}
+
+type seededSource struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewSeededSource returns a reproducible source, two sources
+// with the same seed sample the same sequence of values
+func NewSeededSource(seed int64) Source {
+	return &seededSource{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *seededSource) Float64() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rand.Float64(), nil
+}
+
+type replaySource struct {
+	mu     sync.Mutex
+	values []float64
+	idx    int
+}
+
+// NewReplaySource returns a source that samples the provided values in order,
+// it is used to replay a recorded optimization
+func NewReplaySource(values []float64) Source {
+	return &replaySource{
+		values: values,
+	}
+}
+
+func (s *replaySource) Float64() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idx == len(s.values) {
+		return 0.0, ErrorSourceExhausted
+	}
+	v := s.values[s.idx]
+	s.idx++
+
+	return v, nil
+}
+
+// RecordingSource stores every value sampled from the underlying
+// source so the run can be replayed with NewReplaySource
+type RecordingSource struct {
+	mu     sync.Mutex
+	source Source
+	values []float64
+}
+
+// NewRecordingSource records the values sampled from s
+func NewRecordingSource(s Source) *RecordingSource {
+	return &RecordingSource{
+		source: s,
+	}
+}
+
+// Float64 samples a value from the underlying source and records it
+func (r *RecordingSource) Float64() (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, err := r.source.Float64()
 	if err != nil {
-		log.Fatal("unable to sample from the distribution: ", err)
+		return 0.0, err
 	}
-	f, _ := new(big.Float).SetInt(n).Float64()
+	r.values = append(r.values, v)
+
+	return v, nil
+}
+
+// Values returns a copy of the recorded values
+func (r *RecordingSource) Values() []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make([]float64, len(r.values))
+	copy(values, r.values)
 
-	return f / 100.0
+	return values
 }
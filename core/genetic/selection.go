@@ -0,0 +1,251 @@
+package genetic
+
+import (
+	"errors"
+	"sort"
+)
+
+const (
+	defaultTournamentSize    = 3
+	defaultSelectionPressure = 1.5
+	rouletteSelectionName    = "roulette"
+	tournamentSelectionName  = "tournament"
+	rankSelectionName        = "rank"
+	susSelectionName         = "sus"
+)
+
+var (
+	// ErrorUnknownSelection the name doesn't match any selection strategy
+	ErrorUnknownSelection = errors.New("Unknown selection strategy")
+	// ErrorInvalidTournamentSize a tournament needs at least one contestant
+	ErrorInvalidTournamentSize = errors.New("The tournament size must be greater than zero")
+	// ErrorInvalidSelectionPressure the linear rank selection pressure must be in [1, 2]
+	ErrorInvalidSelectionPressure = errors.New("The selection pressure must be between 1 and 2")
+)
+
+// SelectionStrategy returns the selection option that matches the name, the valid names
+// are roulette, tournament, rank and sus. Tournament and rank selection use the default
+// tournament size and selection pressure
+func SelectionStrategy(name string) (Option, error) {
+	switch name {
+	case rouletteSelectionName:
+		return RouletteSelection, nil
+	case tournamentSelectionName:
+		return TournamentSelection(defaultTournamentSize), nil
+	case rankSelectionName:
+		return RankSelection(defaultSelectionPressure), nil
+	case susSelectionName:
+		return StochasticUniversalSampling, nil
+	default:
+		return nil, ErrorUnknownSelection
+	}
+}
+
+// RouletteSelection configures the algorithm to select chromosomes with a probability
+// proportional to their fitness, it is the default selection strategy
+func RouletteSelection(f *facebook) {
+	f.selection = standardSelection
+}
+
+// TournamentSelection configures the algorithm to select the best chromosome
+// out of k randomly sampled contestants until the selection is complete
+func TournamentSelection(k int) Option {
+	return func(f *facebook) {
+		f.selection = func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+			return tournamentSelection(population, size, k, d)
+		}
+	}
+}
+
+// RankSelection configures the algorithm to select chromosomes with a probability
+// that grows linearly with their quality rank, pressure is the expected number of
+// times the best chromosome would be selected and must be between 1 and 2
+func RankSelection(pressure float64) Option {
+	return func(f *facebook) {
+		f.selection = func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+			return rankSelection(population, size, pressure, d)
+		}
+	}
+}
+
+// StochasticUniversalSampling configures the algorithm to select chromosomes with
+// evenly spaced pointers over the cumulative weights of the population
+func StochasticUniversalSampling(f *facebook) {
+	f.selection = func(population []*Chromosome, size int, d Source, fitness func(population []*Chromosome, q ...float64) error) ([]*Chromosome, error) {
+		return stochasticUniversalSampling(population, size, d)
+	}
+}
+
+func tournamentSelection(population []*Chromosome, size, k int, d Source) ([]*Chromosome, error) {
+	if k < 1 {
+		return nil, ErrorInvalidTournamentSize
+	}
+	if len(population) == size {
+		return population, nil
+	}
+	if len(population) < size {
+		return nil, ErrorInvalidPopulation
+	}
+
+	pool := append([]*Chromosome{}, population...)
+	selected := make([]*Chromosome, size)
+	for i := 0; i < size; i++ {
+		winner := -1
+		for j := 0; j < k; j++ {
+			idx, err := sampleIndex(d, len(pool))
+			if err != nil {
+				return nil, err
+			}
+			if winner == -1 || pool[idx].Quality > pool[winner].Quality {
+				winner = idx
+			}
+		}
+		selected[i] = pool[winner]
+		pool = append(pool[:winner], pool[winner+1:]...)
+	}
+
+	sortByQuality(selected)
+
+	return selected, nil
+}
+
+func rankSelection(population []*Chromosome, size int, pressure float64, d Source) ([]*Chromosome, error) {
+	if pressure < 1.0 || pressure > 2.0 {
+		return nil, ErrorInvalidSelectionPressure
+	}
+	if len(population) == size {
+		return population, nil
+	}
+	if len(population) < size {
+		return nil, ErrorInvalidPopulation
+	}
+
+	// the pool is sorted in ascending order so the
+	// rank of a chromosome is its index
+	pool := append([]*Chromosome{}, population...)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].Quality < pool[j].Quality
+	})
+
+	selected := make([]*Chromosome, size)
+	for i := 0; i < size; i++ {
+		n := float64(len(pool))
+		weights := make([]float64, len(pool))
+		for rank := range pool {
+			if len(pool) == 1 {
+				weights[rank] = 1
+				break
+			}
+			weights[rank] = (2-pressure)/n + 2*float64(rank)*(pressure-1)/(n*(n-1))
+		}
+		r, err := sample(d)
+		if err != nil {
+			return nil, err
+		}
+		idx := searchWeights(weights, r)
+		selected[i] = pool[idx]
+		pool = append(pool[:idx], pool[idx+1:]...)
+	}
+
+	sortByQuality(selected)
+
+	return selected, nil
+}
+
+// stochasticUniversalSampling selects distinct chromosomes, when a pointer lands on an
+// already selected chromosome the remaining slots are filled with a new sampling over
+// the chromosomes that haven't been selected
+func stochasticUniversalSampling(population []*Chromosome, size int, d Source) ([]*Chromosome, error) {
+	if len(population) == size {
+		return population, nil
+	}
+	if len(population) < size {
+		return nil, ErrorInvalidPopulation
+	}
+
+	pool := append([]*Chromosome{}, population...)
+	selected := make([]*Chromosome, 0, size)
+	for len(selected) < size {
+		pointers := size - len(selected)
+		weights := samplingWeights(pool)
+		r, err := sample(d)
+		if err != nil {
+			return nil, err
+		}
+
+		picked := make(map[int]bool)
+		for i := 0; i < pointers; i++ {
+			p := (r + float64(i)) / float64(pointers)
+			picked[searchWeights(weights, p)] = true
+		}
+
+		remaining := []*Chromosome{}
+		for i, c := range pool {
+			if picked[i] {
+				selected = append(selected, c)
+			} else {
+				remaining = append(remaining, c)
+			}
+		}
+		pool = remaining
+	}
+
+	sortByQuality(selected)
+
+	return selected, nil
+}
+
+// samplingWeights normalizes the quality of the population so it can be used
+// as a probability, negative qualities are shifted and a population without
+// quality is sampled uniformly
+func samplingWeights(population []*Chromosome) []float64 {
+	min := 0.0
+	for _, c := range population {
+		if c.Quality < min {
+			min = c.Quality
+		}
+	}
+	var total float64
+	weights := make([]float64, len(population))
+	for i, c := range population {
+		weights[i] = c.Quality - min
+		total += weights[i]
+	}
+	for i := range weights {
+		if total == 0 {
+			weights[i] = 1 / float64(len(weights))
+		} else {
+			weights[i] /= total
+		}
+	}
+
+	return weights
+}
+
+// searchWeights returns the index of the weight interval that contains r
+func searchWeights(weights []float64, r float64) int {
+	var a float64
+	for i := 0; i < len(weights)-1; i++ {
+		a += weights[i]
+		if r < a {
+			return i
+		}
+	}
+	// Fix for floating point error
+	return len(weights) - 1
+}
+
+func sampleIndex(d Source, n int) (int, error) {
+	r, err := sample(d)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(r * float64(n)), nil
+}
+
+func sortByQuality(population []*Chromosome) {
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Quality > population[j].Quality
+	})
+}
@@ -0,0 +1,162 @@
+package genetic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPopulation(qualities ...float64) []*Chromosome {
+	population := []*Chromosome{}
+	for i, q := range qualities {
+		population = append(population, &Chromosome{
+			ID:      fmt.Sprint(i + 1),
+			Quality: q,
+		})
+	}
+
+	return population
+}
+
+func testIDs(population []*Chromosome) []string {
+	ids := []string{}
+	for _, c := range population {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
+
+func TestSelectionStrategies(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Strategy   Option
+		Population []*Chromosome
+		Samples    []float64
+		Size       int
+		Expected   []string
+		Error      error
+	}{
+		{
+			Name:     "Tournament Selection",
+			Strategy: TournamentSelection(2),
+			// first tournament between chromosomes 1 and 3,
+			// second between chromosomes 2 and 4
+			Population: testPopulation(1, 2, 3, 4),
+			Samples:    []float64{0.1, 0.6, 0.4, 0.9},
+			Size:       2,
+			Expected:   []string{"4", "3"},
+			Error:      nil,
+		},
+		{
+			Name:       "Tournament Selection With Zero Qualities",
+			Strategy:   TournamentSelection(1),
+			Population: testPopulation(0, 0, 0, 0),
+			Samples:    []float64{0.9, 0.1},
+			Size:       2,
+			Expected:   []string{"4", "1"},
+			Error:      nil,
+		},
+		{
+			Name:       "Invalid Tournament Size",
+			Strategy:   TournamentSelection(0),
+			Population: testPopulation(1, 2, 3, 4),
+			Size:       2,
+			Error:      ErrorInvalidTournamentSize,
+		},
+		{
+			Name:     "Rank Selection",
+			Strategy: RankSelection(2),
+			// with maximum pressure the weights are [0, 1/6, 2/6, 3/6]
+			// and the outlier has the same weight as a regular best
+			Population: testPopulation(-5, 1, 2, 1000),
+			Samples:    []float64{0.4, 0.1},
+			Size:       2,
+			Expected:   []string{"3", "2"},
+			Error:      nil,
+		},
+		{
+			Name:       "Invalid Selection Pressure",
+			Strategy:   RankSelection(3),
+			Population: testPopulation(1, 2, 3, 4),
+			Size:       2,
+			Error:      ErrorInvalidSelectionPressure,
+		},
+		{
+			Name:     "Stochastic Universal Sampling",
+			Strategy: StochasticUniversalSampling,
+			// weights [0.1, 0.2, 0.3, 0.4] and pointers at 0.25 and 0.75
+			Population: testPopulation(1, 2, 3, 4),
+			Samples:    []float64{0.5},
+			Size:       2,
+			Expected:   []string{"4", "2"},
+			Error:      nil,
+		},
+		{
+			Name:     "Stochastic Universal Sampling Repeated Pointer",
+			Strategy: StochasticUniversalSampling,
+			// both pointers land on the outlier so a second
+			// sampling selects from the remaining chromosomes
+			Population: testPopulation(1, 1, 1000),
+			Samples:    []float64{0.5, 0.9},
+			Size:       2,
+			Expected:   []string{"3", "2"},
+			Error:      nil,
+		},
+		{
+			Name:       "Stochastic Universal Sampling With Zero Qualities",
+			Strategy:   StochasticUniversalSampling,
+			Population: testPopulation(0, 0, 0, 0),
+			Samples:    []float64{0.0},
+			Size:       2,
+			Expected:   []string{"1", "3"},
+			Error:      nil,
+		},
+		{
+			Name:       "Invalid Population",
+			Strategy:   StochasticUniversalSampling,
+			Population: testPopulation(1, 2),
+			Size:       3,
+			Error:      ErrorInvalidPopulation,
+		},
+		{
+			Name:       "Exhausted Source",
+			Strategy:   RankSelection(1.5),
+			Population: testPopulation(1, 2, 3, 4),
+			Samples:    []float64{0.5},
+			Size:       2,
+			Error:      ErrorSourceExhausted,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			g := New(nil, tc.Strategy, RandomSource(NewReplaySource(tc.Samples)))
+			population := append([]*Chromosome{}, tc.Population...)
+
+			selected, err := g.Selection(population, tc.Size)
+			assert.Equal(tc.Error, err)
+			if tc.Error == nil {
+				assert.Equal(tc.Expected, testIDs(selected))
+			}
+			// the caller's population isn't modified by the selection
+			assert.Equal(testIDs(tc.Population), testIDs(population))
+		})
+	}
+}
+
+func TestSelectionStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"roulette", "tournament", "rank", "sus"} {
+		strategy, err := SelectionStrategy(name)
+		assert.Nil(err)
+		assert.NotNil(strategy)
+	}
+
+	strategy, err := SelectionStrategy("lottery")
+	assert.Nil(strategy)
+	assert.Equal(ErrorUnknownSelection, err)
+}
@@ -39,3 +39,8 @@ func (e *Error) Error() string {
 
 	return string(s)
 }
+
+// Unwrap returns the wrapped error so errors.Is and errors.As reach it
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"time"
 )
 
 // Client is a client interface that enables to write custom behaviours to servers
@@ -22,19 +24,80 @@ type flag struct {
 type client struct {
 	client *http.Client
 	*flag
+	// ctx is the context of the requests set by the client
+	ctx context.Context
+	// retry policy of the failed requests, the
+	// requests are sent once when it's nil
+	retry *RetryPolicy
 }
 
+// Option configures a client
+type Option func(*client)
+
 // New Creates a new Client
-func New(config ...*flag) Client {
-	var f *flag
-	if len(config) == 0 {
-		f = &flag{}
-	} else {
-		f = config[0]
-	}
-	return &client{
+func New(config ...Option) Client {
+	c := &client{
 		client: &http.Client{},
-		flag:   f,
+		flag:   &flag{},
+		ctx:    context.Background(),
+	}
+
+	for _, fn := range config {
+		fn(c)
+	}
+
+	return c
+}
+
+// Timeout limits the time of a request, including the
+// connection, redirects and reading the response body
+func Timeout(d time.Duration) Option {
+	return func(c *client) {
+		c.client.Timeout = d
+	}
+}
+
+// Transport sets the round tripper that sends the requests
+func Transport(t http.RoundTripper) Option {
+	return func(c *client) {
+		c.client.Transport = t
+	}
+}
+
+// Retry sends again the failed requests allowed by the policy
+func Retry(policy RetryPolicy) Option {
+	return func(c *client) {
+		c.retry = &policy
+	}
+}
+
+// contextClient is a client whose requests can be bound to a context
+type contextClient interface {
+	WithContext(ctx context.Context) Client
+}
+
+// WithContext returns the client with its requests bound to the context, the
+// clients that can't be bound to a context are returned as they are
+func WithContext(ctx context.Context, c Client) Client {
+	if cc, ok := c.(contextClient); ok {
+		return cc.WithContext(ctx)
+	}
+
+	return c
+}
+
+// WithContext returns a copy of the client whose requests are cancelled with the context
+func (c *client) WithContext(ctx context.Context) Client {
+	cc := *c
+	cc.ctx = ctx
+
+	return &cc
+}
+
+// withFlag sets the flags used to force errors in tests
+func withFlag(f *flag) Option {
+	return func(c *client) {
+		c.flag = f
 	}
 }
 
@@ -77,6 +140,9 @@ func (c *client) Do(req *http.Request) (*http.Response, error) {
 	if !c.flag.doError {
 		req.URL.Scheme = "https"
 	}
+	if c.retry != nil {
+		return c.retry.do(c.client, req)
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -89,7 +155,7 @@ func (c *client) SetRequest(method, url string, body io.Reader) (*http.Request,
 	if c.flag.requestError {
 		return nil, errorRequest
 	}
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(c.ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -11,7 +14,7 @@ import (
 func TestClient(t *testing.T) {
 	assert := assert.New(t)
 	t.Run("Error Nil URL", func(t *testing.T) {
-		client := New(&flag{})
+		client := New(withFlag(&flag{}))
 		resp, err := client.Get("")
 		assert.Equal(ErrorNilURL, err)
 		assert.Equal((*http.Response)(nil), resp)
@@ -22,9 +25,9 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("Error Set Request in methods", func(t *testing.T) {
-		client := New(&flag{
+		client := New(withFlag(&flag{
 			requestError: true,
-		})
+		}))
 		resp, err := client.Get("test.com")
 		assert.Equal((*http.Response)(nil), resp)
 		assert.Error(err)
@@ -35,7 +38,7 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("Set Request", func(t *testing.T) {
-		client := New(&flag{})
+		client := New(withFlag(&flag{}))
 		req, err := client.SetRequest("GET", "https://www.google.com", nil)
 		if err != nil {
 			t.Error("unable to set client request error: ", err)
@@ -49,7 +52,7 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("GET", func(t *testing.T) {
-		client := New(&flag{})
+		client := New(withFlag(&flag{}))
 		resp, err := client.Get("https://www.google.com")
 		if err != nil {
 			t.Error("GET request failing error: ", err)
@@ -64,7 +67,7 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("POST", func(t *testing.T) {
-		client := New(&flag{})
+		client := New(withFlag(&flag{}))
 		resp, err := client.Post("https://www.google.com", nil)
 		_ = resp.Body.Close()
 		if err != nil {
@@ -76,9 +79,9 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("Error Do", func(t *testing.T) {
-		client := New(&flag{
+		client := New(withFlag(&flag{
 			doError: true,
-		})
+		}))
 		req, err := client.SetRequest("GET", "google.com", nil)
 		if err != nil {
 			t.Fatal("Unexpected error setting client request")
@@ -88,3 +91,32 @@ func TestClient(t *testing.T) {
 		assert.Error(err)
 	})
 }
+
+func TestWithContext(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	c := New(Transport(server.Client().Transport))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cc := WithContext(ctx, c)
+	req, err := cc.SetRequest("GET", server.URL, nil)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(ctx, req.Context())
+
+	cancel()
+	_, err = cc.Get(server.URL)
+	assert.True(errors.Is(err, context.Canceled))
+	// the context isn't shared with the original client
+	resp, err := c.Get(server.URL)
+	if assert.Nil(err) {
+		resp.Body.Close()
+	}
+
+	// the clients that can't be bound are returned as they are
+	var fake Client = &struct{ Client }{}
+	assert.Equal(fake, WithContext(ctx, fake))
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode of a recorder
+type Mode int
+
+const (
+	// Replay returns the recorded responses without sending the requests
+	Replay Mode = iota
+	// Record sends the requests and records the exchanges
+	Record
+)
+
+// redacted replaces the value of the secret fields in the fixtures
+const redacted = "REDACTED"
+
+var (
+	// ErrorNoInteraction the fixture doesn't have a response for the request
+	ErrorNoInteraction = errors.New("The fixture doesn't have a recorded response for the request")
+	// ErrorReplayMode the recorder only saves the fixture in record mode
+	ErrorReplayMode = errors.New("The recorder can't save the fixture in replay mode")
+
+	// defaultSecrets are the fields of the graph api requests
+	// and responses whose values aren't recorded
+	defaultSecrets = []string{
+		"access_token",
+		"input_token",
+		"client_secret",
+		"appsecret_proof",
+	}
+)
+
+// Interaction is a recorded exchange of a request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the normalized request of an interaction, the query
+// and form bodies are sorted and the json bodies are compacted
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is a round tripper that records the exchanges with a server to a
+// fixture file or replays the fixture, the requests are matched by method, path
+// and normalized query and body, and the secret fields are redacted from both
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+	secrets   map[string]bool
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	// replayed are the interactions already returned in replay mode
+	replayed []bool
+}
+
+// NewRecorder returns a recorder of the fixture file, the fixture is loaded
+// in replay mode and it's written by Save in record mode
+func NewRecorder(path string, mode Mode, config ...func(*Recorder)) (*Recorder, error) {
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: http.DefaultTransport,
+		secrets:   map[string]bool{},
+	}
+	for _, s := range defaultSecrets {
+		r.secrets[s] = true
+	}
+
+	for _, fn := range config {
+		fn(r)
+	}
+
+	if mode == Replay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &r.interactions); err != nil {
+			return nil, err
+		}
+		r.replayed = make([]bool, len(r.interactions))
+	}
+
+	return r, nil
+}
+
+// RecordTransport sets the round tripper that sends the recorded requests
+func RecordTransport(t http.RoundTripper) func(*Recorder) {
+	return func(r *Recorder) {
+		r.transport = t
+	}
+}
+
+// Redact adds fields whose values are redacted from the fixture
+func Redact(fields ...string) func(*Recorder) {
+	return func(r *Recorder) {
+		for _, f := range fields {
+			r.secrets[f] = true
+		}
+	}
+}
+
+// RoundTrip records the exchange of the request or returns its recorded response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.request(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == Replay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	// the length changes with the normalized body and the date with every recording
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+	header.Del("Date")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, &Interaction{
+		Request: *recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       r.normalize(string(b), resp.Header.Get("Content-Type")),
+		},
+	})
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the fixture file
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return ErrorReplayMode
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := marshal(r.interactions, "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, b, os.FileMode(0644))
+}
+
+// Remaining returns the number of interactions of the fixture that weren't replayed
+func (r *Recorder) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+	for _, replayed := range r.replayed {
+		if !replayed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// replay returns the response of the first interaction of the request that wasn't replayed
+func (r *Recorder) replay(req *http.Request, recorded *RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.replayed[i] || in.Request != *recorded {
+			continue
+		}
+		r.replayed[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s?%s %s", ErrorNoInteraction, recorded.Method, recorded.Path, recorded.Query, recorded.Body)
+}
+
+// request returns the normalized request, the body of the request is restored after reading it
+func (r *Recorder) request(req *http.Request) (*RecordedRequest, error) {
+	recorded := &RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  r.redactValues(req.URL.Query()),
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return recorded, nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	recorded.Body = r.normalize(string(b), req.Header.Get("Content-Type"))
+
+	return recorded, nil
+}
+
+// normalize returns the body with the secret fields redacted, the json
+// bodies are compacted with sorted keys and form bodies are sorted
+func (r *Recorder) normalize(body, contentType string) string {
+	// the numbers are kept as they are so the long ids don't lose precision
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	if d.Decode(&v) == nil && !d.More() {
+		b, err := marshal(r.redactJSON(v), "")
+		if err == nil {
+			return string(bytes.TrimSpace(b))
+		}
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if uV, err := url.ParseQuery(body); err == nil {
+			return r.redactValues(uV)
+		}
+	}
+
+	return body
+}
+
+// redactValues returns the values encoded sorted by key with the secret values redacted
+func (r *Recorder) redactValues(uV url.Values) string {
+	for k := range uV {
+		if r.secrets[k] {
+			uV[k] = []string{redacted}
+		}
+	}
+
+	return uV.Encode()
+}
+
+// redactJSON replaces the secret fields of the decoded json value and the secret
+// query values of its urls, like the paging urls, the encoder sorts the keys
+func (r *Recorder) redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		u, err := url.Parse(t)
+		if err != nil || u.RawQuery == "" {
+			return t
+		}
+		uV := u.Query()
+		for k := range uV {
+			if r.secrets[k] {
+				u.RawQuery = r.redactValues(uV)
+				return u.String()
+			}
+		}
+	case map[string]interface{}:
+		for k, field := range t {
+			if r.secrets[k] {
+				t[k] = redacted
+				continue
+			}
+			t[k] = r.redactJSON(field)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = r.redactJSON(item)
+		}
+	}
+
+	return v
+}
+
+// marshal encodes the value without escaping the html characters of the urls
+func marshal(v interface{}, indent string) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", indent)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+	var requests int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			io.WriteString(w, `{"id":"23843012345678901","access_token":"secret"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"Invalid parameter","code":100}}`)
+		}
+	}))
+	defer server.Close()
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	recorder, err := NewRecorder(fixture, Record, RecordTransport(server.Client().Transport), Redact("client_id"))
+	if !assert.Nil(err) {
+		return
+	}
+	c := New(Transport(recorder))
+	resp, err := c.Get(server.URL + "/v8.0/me?fields=id&access_token=token&client_id=1234")
+	if !assert.Nil(err) {
+		return
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(`{"id":"23843012345678901","access_token":"secret"}`, string(b))
+	resp, err = c.Post(server.URL+"/v8.0/act_1234/campaigns", strings.NewReader(`{"name":"test","access_token":"token"}`))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	if !assert.Nil(recorder.Save()) {
+		return
+	}
+
+	b, err = ioutil.ReadFile(fixture)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.NotContains(string(b), "=token")
+	assert.NotContains(string(b), `\"token\"`)
+	assert.NotContains(string(b), "secret")
+	assert.NotContains(string(b), "client_id=1234")
+
+	replayer, err := NewRecorder(fixture, Replay, Redact("client_id"))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(ErrorReplayMode, replayer.Save())
+	assert.Equal(2, replayer.Remaining())
+	c = New(Transport(replayer))
+
+	// the requests match whatever the order of the query and the json fields and the token
+	resp, err = c.Post("https://graph.facebook.com/v8.0/act_1234/campaigns", strings.NewReader(`{"access_token":"other","name":"test"}`))
+	if !assert.Nil(err) {
+		return
+	}
+	b, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(`{"error":{"code":100,"message":"Invalid parameter"}}`, string(b))
+
+	resp, err = c.Get("https://graph.facebook.com/v8.0/me?access_token=other&client_id=5678&fields=id")
+	if !assert.Nil(err) {
+		return
+	}
+	b, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(`{"access_token":"REDACTED","id":"23843012345678901"}`, string(b))
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+	// every interaction is replayed once
+	_, err = c.Get("https://graph.facebook.com/v8.0/me?access_token=other&fields=id")
+	assert.True(errors.Is(err, ErrorNoInteraction))
+	_, err = c.Get("https://graph.facebook.com/v8.0/me?access_token=other&client_id=5678&fields=id")
+	assert.True(errors.Is(err, ErrorNoInteraction))
+	assert.Equal(0, replayer.Remaining())
+	assert.Equal(2, requests)
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Body        string
+		ContentType string
+		Expected    string
+	}{
+		{
+			Name:     "JSON",
+			Body:     `{"name": "test", "id": 23843012345678901, "access_token": "token", "data": [{"input_token": "token"}]}`,
+			Expected: `{"access_token":"REDACTED","data":[{"input_token":"REDACTED"}],"id":23843012345678901,"name":"test"}`,
+		},
+		{
+			Name:     "Paging URL",
+			Body:     `{"paging":{"next":"https://graph.facebook.com/v8.0/c1/ads?limit=100&access_token=token&after=MQ"},"message":"why?"}`,
+			Expected: `{"message":"why?","paging":{"next":"https://graph.facebook.com/v8.0/c1/ads?access_token=REDACTED&after=MQ&limit=100"}}`,
+		},
+		{
+			Name:        "Form",
+			Body:        "name=test&client_secret=secret&access_token=token",
+			ContentType: "application/x-www-form-urlencoded",
+			Expected:    "access_token=REDACTED&client_secret=REDACTED&name=test",
+		},
+		{
+			Name:     "Text",
+			Body:     "access_token=token",
+			Expected: "access_token=token",
+		},
+	}
+	assert := assert.New(t)
+	r, err := NewRecorder("", Record)
+	if !assert.Nil(err) {
+		return
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(tc.Expected, r.normalize(tc.Body, tc.ContentType))
+		})
+	}
+}
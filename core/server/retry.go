@@ -0,0 +1,156 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader marks a request that is safe to send again whatever its method
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy configures how the failed requests are sent again, the delay
+// between attempts grows exponentially from the base delay up to the maximum
+// delay and a random jitter spreads the retries of concurrent requests
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// StatusCodes are the response status codes that are retried,
+	// the network errors are always retried
+	StatusCodes []int
+	// Idempotent reports whether a request is safe to send again
+	Idempotent func(*http.Request) bool
+
+	// sleep waits between attempts, it returns
+	// early when the request context is done
+	sleep func(*http.Request, time.Duration) error
+}
+
+// DefaultRetryPolicy returns the policy that retries the idempotent requests
+// three times on network errors, rate limits and server errors
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		Idempotent: IdempotentRequest,
+	}
+}
+
+// IdempotentRequest reports whether the request method is idempotent or the
+// request has an idempotency key, the requests with a body that can't be read
+// again aren't idempotent
+func IdempotentRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+// do sends the request until it succeeds, it fails with an error that isn't
+// retried or the request runs out of attempts
+func (p *RetryPolicy) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if p.MaxAttempts <= 1 || (p.Idempotent != nil && !p.Idempotent(req)) {
+		return client.Do(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		if attempt >= p.MaxAttempts || !p.retryable(req, resp, err) {
+			return resp, err
+		}
+		delay := p.delay(attempt, resp)
+		if resp != nil {
+			// the connection is reused once the body is read to the end
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := p.wait(req, delay); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether the result of the request is retried
+func (p *RetryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	for _, code := range p.StatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns the wait before the next attempt, a random duration up to the
+// exponential backoff or the time of the Retry-After header of the response
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			d := time.Duration(seconds) * time.Second
+			if p.MaxDelay > 0 && d > p.MaxDelay {
+				d = p.MaxDelay
+			}
+			return d
+		}
+	}
+
+	backoff := p.BaseDelay << uint(attempt-1)
+	if backoff <= 0 || (p.MaxDelay > 0 && backoff > p.MaxDelay) {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// wait sleeps for the delay unless the request context is done first
+func (p *RetryPolicy) wait(req *http.Request, d time.Duration) error {
+	if p.sleep != nil {
+		return p.sleep(req, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
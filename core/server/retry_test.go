@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRetryPolicy is the default policy without waits between attempts
+func testRetryPolicy(delays *[]time.Duration) RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.sleep = func(req *http.Request, d time.Duration) error {
+		*delays = append(*delays, d)
+		return req.Context().Err()
+	}
+
+	return p
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		Name string
+		// Statuses are the response status codes of the attempts,
+		// the last one is repeated
+		Statuses []int
+		Method   string
+		Header   http.Header
+		Attempts int
+		Status   int
+	}{
+		{
+			Name:     "Success",
+			Statuses: []int{http.StatusOK},
+			Method:   "GET",
+			Attempts: 1,
+			Status:   http.StatusOK,
+		},
+		{
+			Name:     "Retried Server Error",
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			Method:   "GET",
+			Attempts: 2,
+			Status:   http.StatusOK,
+		},
+		{
+			Name:     "Max Attempts",
+			Statuses: []int{http.StatusTooManyRequests},
+			Method:   "DELETE",
+			Attempts: 3,
+			Status:   http.StatusTooManyRequests,
+		},
+		{
+			Name:     "Client Error",
+			Statuses: []int{http.StatusBadRequest, http.StatusOK},
+			Method:   "GET",
+			Attempts: 1,
+			Status:   http.StatusBadRequest,
+		},
+		{
+			Name:     "Not Idempotent",
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			Method:   "POST",
+			Attempts: 1,
+			Status:   http.StatusServiceUnavailable,
+		},
+		{
+			Name:     "Idempotency Key",
+			Statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			Method:   "POST",
+			Header:   http.Header{IdempotencyKeyHeader: {"1234"}},
+			Attempts: 2,
+			Status:   http.StatusOK,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attempts int
+			var bodies []string
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				status := tc.Statuses[len(tc.Statuses)-1]
+				if attempts < len(tc.Statuses) {
+					status = tc.Statuses[attempts]
+				}
+				attempts++
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			var delays []time.Duration
+			c := New(Transport(server.Client().Transport), Retry(testRetryPolicy(&delays)))
+			req, err := c.SetRequest(tc.Method, server.URL, strings.NewReader("body"))
+			if !assert.Nil(err) {
+				return
+			}
+			for k, v := range tc.Header {
+				req.Header[k] = v
+			}
+			resp, err := c.Do(req)
+			if !assert.Nil(err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(tc.Status, resp.StatusCode)
+			assert.Equal(tc.Attempts, attempts)
+			assert.Len(delays, tc.Attempts-1)
+			for _, b := range bodies {
+				assert.Equal("body", b)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	assert := assert.New(t)
+	var attempts int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := DefaultRetryPolicy()
+	p.sleep = func(req *http.Request, d time.Duration) error {
+		cancel()
+		return req.Context().Err()
+	}
+	c := New(Transport(server.Client().Transport), Retry(p))
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = c.Do(req)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, attempts)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+	p := DefaultRetryPolicy()
+
+	for attempt := 1; attempt < 10; attempt++ {
+		d := p.delay(attempt, nil)
+		assert.GreaterOrEqual(int64(d), int64(0))
+		assert.LessOrEqual(int64(d), int64(p.MaxDelay))
+		assert.LessOrEqual(int64(d), int64(p.BaseDelay<<uint(attempt-1)))
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"2"}}}
+	assert.Equal(2*time.Second, p.delay(1, resp))
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(p.MaxDelay, p.delay(1, resp))
+}
+
+func TestTimeout(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	c := New(Transport(server.Client().Transport), Timeout(10*time.Millisecond))
+	_, err := c.Get(server.URL)
+	assert.Error(err)
+}
@@ -0,0 +1,333 @@
+package simulator
+
+import (
+	"errors"
+	"math"
+
+	"bitbucket.org/backend/core/genetic"
+)
+
+const (
+	// hidden response rates are sampled in these ranges
+	minAudience = 1e4
+	maxAudience = 2e6
+	minCTR      = 0.5
+	maxCTR      = 3.0
+	minCPM      = 2.0
+	maxCPM      = 12.0
+	// interactions change the ctr of an ad set that targets both genes
+	maxInteraction = 0.5
+
+	// an ad set without targeting reaches the whole location
+	broadAudience = 2e7
+	broadCTR      = 0.8
+	broadCPM      = 4.0
+)
+
+var (
+	// ErrorEmptyChromosome the chromosome doesn't have a targeting tree
+	ErrorEmptyChromosome = errors.New("The chromosome doesn't have a targeting tree")
+	// ErrorUnknownGene the chromosome has an active gene that isn't part of the market
+	ErrorUnknownGene = errors.New("The gene isn't part of the simulated market")
+	// ErrorInvalidNoise the noise amplitude isn't in [0, 1) so it could make the cpm zero or negative
+	ErrorInvalidNoise = errors.New("The noise amplitude must be at least zero and less than one")
+)
+
+// Simulator is an offline market that answers with synthetic insights
+// for the targeting of a chromosome
+type Simulator interface {
+	Insights(c *genetic.Chromosome) (*Insights, error)
+	Quality(c *genetic.Chromosome) (float64, error)
+	Objectives(c *genetic.Chromosome) ([]float64, error)
+	Population(size int, active float64) ([]*genetic.Chromosome, error)
+}
+
+// Insights are the synthetic lifetime insights of an ad set, unique CTR is
+// a percentage like the one returned by the graph api
+type Insights struct {
+	Reach       float64 `json:"reach"`
+	Impressions float64 `json:"impressions"`
+	UniqueCTR   float64 `json:"unique_ctr"`
+	CPM         float64 `json:"cpm"`
+	Spend       float64 `json:"spend"`
+	Days        float64 `json:"days"`
+}
+
+// Response is the hidden response of the people targeted by a leaf gene
+type Response struct {
+	Audience float64
+	CTR      float64
+	CPM      float64
+}
+
+type pair [2]string
+
+type simulator struct {
+	tree   *genetic.Chromosome
+	random genetic.Source
+	// responses of the leaf genes by ID
+	responses map[string]Response
+	// interactions between pairs of leaf genes, the ctr of
+	// an ad set targeting both genes is multiplied by 1 + effect
+	interactions map[pair]float64
+	// number of random interactions
+	interactionCount int
+	// overrides of the sampled responses and interactions
+	fixedResponses    map[string]Response
+	fixedInteractions map[pair]float64
+
+	budget float64
+	days   float64
+	noise  float64
+}
+
+// New creates a market for the leaf genes of the tree, the responses of the genes
+// and their interactions are sampled from the random source of the simulator so
+// two simulators with the same seeded source are the same market
+func New(tree *genetic.Chromosome, config ...func(*simulator)) (Simulator, error) {
+	if tree == nil || tree.Root == nil {
+		return nil, ErrorEmptyChromosome
+	}
+	s := &simulator{
+		tree:              tree,
+		random:            genetic.NewCryptoSource(),
+		responses:         make(map[string]Response),
+		interactions:      make(map[pair]float64),
+		interactionCount:  -1,
+		fixedResponses:    make(map[string]Response),
+		fixedInteractions: make(map[pair]float64),
+		budget:            100,
+		days:              10,
+	}
+
+	for _, fn := range config {
+		fn(s)
+	}
+	if s.noise < 0 || s.noise >= 1 || math.IsNaN(s.noise) {
+		return nil, ErrorInvalidNoise
+	}
+
+	genes := leaves(tree.Root, nil)
+	for _, g := range genes {
+		var (
+			r   Response
+			err error
+		)
+		if r.Audience, err = s.uniform(math.Log(minAudience), math.Log(maxAudience)); err != nil {
+			return nil, err
+		}
+		r.Audience = math.Exp(r.Audience)
+		if r.CTR, err = s.uniform(minCTR, maxCTR); err != nil {
+			return nil, err
+		}
+		if r.CPM, err = s.uniform(minCPM, maxCPM); err != nil {
+			return nil, err
+		}
+		s.responses[g.ID] = r
+	}
+
+	if s.interactionCount < 0 {
+		s.interactionCount = len(genes) / 2
+	}
+	for i := 0; i < s.interactionCount && len(genes) > 1; i++ {
+		a, err := s.uniform(0, float64(len(genes)))
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.uniform(0, float64(len(genes)))
+		if err != nil {
+			return nil, err
+		}
+		effect, err := s.uniform(-maxInteraction, maxInteraction)
+		if err != nil {
+			return nil, err
+		}
+		if int(a) == int(b) {
+			continue
+		}
+		s.interactions[newPair(genes[int(a)].ID, genes[int(b)].ID)] = effect
+	}
+
+	for id, r := range s.fixedResponses {
+		s.responses[id] = r
+	}
+	for p, effect := range s.fixedInteractions {
+		s.interactions[p] = effect
+	}
+
+	return s, nil
+}
+
+// RandomSource sets the source used to sample the market and the noise of the insights
+func RandomSource(r genetic.Source) func(*simulator) {
+	return func(s *simulator) {
+		s.random = r
+	}
+}
+
+// Budget sets the daily budget and the number of days of the simulated ad sets
+func Budget(daily, days float64) func(*simulator) {
+	return func(s *simulator) {
+		s.budget = daily
+		s.days = days
+	}
+}
+
+// Noise sets the relative amplitude of the uniform noise applied to the ctr
+// and cpm of every insights request, it must be at least zero and less than one
+func Noise(amplitude float64) func(*simulator) {
+	return func(s *simulator) {
+		s.noise = amplitude
+	}
+}
+
+// Interactions sets the number of random interactions between leaf genes,
+// by default there is an interaction for every two leaf genes
+func Interactions(n int) func(*simulator) {
+	return func(s *simulator) {
+		s.interactionCount = n
+	}
+}
+
+// GeneResponse fixes the hidden response of a leaf gene
+func GeneResponse(id string, r Response) func(*simulator) {
+	return func(s *simulator) {
+		s.fixedResponses[id] = r
+	}
+}
+
+// Interaction fixes the effect on the ctr of targeting both genes
+func Interaction(a, b string, effect float64) func(*simulator) {
+	return func(s *simulator) {
+		s.fixedInteractions[newPair(a, b)] = effect
+	}
+}
+
+func newPair(a, b string) pair {
+	if b < a {
+		a, b = b, a
+	}
+
+	return pair{a, b}
+}
+
+func (s *simulator) uniform(min, max float64) (float64, error) {
+	r, err := s.random.Float64()
+	if err != nil {
+		return 0.0, err
+	}
+
+	return min + r*(max-min), nil
+}
+
+// Insights returns the synthetic insights of the chromosome, the audience is the sum of
+// the audiences of the active genes and the ctr and cpm are their audience weighted mean.
+// The reach saturates with the impressions the budget can buy
+func (s *simulator) Insights(c *genetic.Chromosome) (*Insights, error) {
+	if c == nil || c.Root == nil {
+		return nil, ErrorEmptyChromosome
+	}
+
+	var (
+		active   = make(map[string]bool)
+		audience float64
+		ctr      float64
+		cpm      float64
+	)
+	for _, g := range leaves(c.Root, nil) {
+		if g.Value <= 0 {
+			continue
+		}
+		r, ok := s.responses[g.ID]
+		if !ok {
+			return nil, ErrorUnknownGene
+		}
+		active[g.ID] = true
+		audience += r.Audience
+		ctr += r.CTR * r.Audience
+		cpm += r.CPM * r.Audience
+	}
+	if len(active) == 0 {
+		audience, ctr, cpm = broadAudience, broadCTR, broadCPM
+	} else {
+		ctr /= audience
+		cpm /= audience
+	}
+
+	for p, effect := range s.interactions {
+		if active[p[0]] && active[p[1]] {
+			ctr *= 1 + effect
+		}
+	}
+
+	if s.noise > 0 {
+		n, err := s.uniform(1-s.noise, 1+s.noise)
+		if err != nil {
+			return nil, err
+		}
+		ctr *= n
+		n, err = s.uniform(1-s.noise, 1+s.noise)
+		if err != nil {
+			return nil, err
+		}
+		cpm *= n
+	}
+	ctr = math.Min(math.Max(ctr, 0), 100)
+
+	spend := s.budget * s.days
+	impressions := spend / cpm * 1000
+
+	return &Insights{
+		Reach:       audience * (1 - math.Exp(-impressions/audience)),
+		Impressions: impressions,
+		UniqueCTR:   ctr,
+		CPM:         cpm,
+		Spend:       spend,
+		Days:        s.days,
+	}, nil
+}
+
+// Quality is the default quality of the campaigns, reach * unique ctr / cpm / days,
+// computed with synthetic insights so it can be used by genetic.New
+func (s *simulator) Quality(c *genetic.Chromosome) (float64, error) {
+	i, err := s.Insights(c)
+	if err != nil {
+		return 0.0, err
+	}
+
+	return i.Reach * i.UniqueCTR / i.CPM / i.Days, nil
+}
+
+// Objectives returns the reach, unique ctr and negated cpm of the
+// chromosome so it can be used by genetic.MultiObjective
+func (s *simulator) Objectives(c *genetic.Chromosome) ([]float64, error) {
+	i, err := s.Insights(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return []float64{i.Reach, i.UniqueCTR, -i.CPM}, nil
+}
+
+// Population creates chromosomes of the market tree activating
+// each leaf gene with the provided probability
+func (s *simulator) Population(size int, active float64) ([]*genetic.Chromosome, error) {
+	population := make([]*genetic.Chromosome, size)
+	for i := range population {
+		c := s.tree.Clone()
+		c.ID, c.Quality, c.Fitness = "", 0.0, 0.0
+		for _, g := range leaves(c.Root, nil) {
+			r, err := s.random.Float64()
+			if err != nil {
+				return nil, err
+			}
+			g.Value = 0
+			if r < active {
+				g.Value = 1
+			}
+		}
+		population[i] = c
+	}
+
+	return population, nil
+}
@@ -0,0 +1,219 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/backend/core/genetic"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTree = `{"data":[
+	{"raw_name":"__ROOT__","name":"__ROOT__","id":"","type":"","path":null},
+	{"raw_name":"Behaviors","name":"Behaviors","id":"","type":"","path":null},
+	{"raw_name":"Travel","name":"Travel","id":"","type":"","path":["Behaviors"]},
+	{"raw_name":"Frequent Travelers","name":"Frequent Travelers","id":"a","type":"behaviors","path":["Behaviors","Travel"]},
+	{"raw_name":"Interests","name":"Interests","id":"","type":"","path":null},
+	{"raw_name":"Hiking","name":"Hiking","id":"b","type":"interests","path":["Interests"]},
+	{"raw_name":"Surfing","name":"Surfing","id":"c","type":"interests","path":["Interests"]}
+]}`
+
+// testTargeting returns a copy of the tree with the provided genes active
+func testTargeting(t *testing.T, ids ...string) *genetic.Chromosome {
+	tree, err := ParseTree([]byte(testTree))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, g := range leaves(tree.Root, nil) {
+		for _, id := range ids {
+			if g.ID == id {
+				g.Value = 1
+			}
+		}
+	}
+
+	return tree
+}
+
+func TestParseTree(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("Test Tree", func(t *testing.T) {
+		tree, err := ParseTree([]byte(testTree))
+		assert.Nil(err)
+		ids := []string{}
+		for _, g := range leaves(tree.Root, nil) {
+			ids = append(ids, g.ID)
+		}
+		assert.Equal([]string{"a", "b", "c"}, ids)
+		assert.Equal("Travel", tree.Root.Children[0].Children[0].Name)
+		assert.Equal(tree.Root.Children[0].Children[0], tree.Root.Children[0].Children[0].Children[0].Parent)
+	})
+
+	t.Run("Fixture", func(t *testing.T) {
+		tree, err := LoadTree("../facebook/campaign/tests-fixtures/targetingTree.json")
+		assert.Nil(err)
+		assert.NotEmpty(leaves(tree.Root, nil))
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := LoadTree("missing.json")
+		assert.Error(err)
+	})
+
+	t.Run("Empty Tree", func(t *testing.T) {
+		_, err := ParseTree([]byte(`{"data":[]}`))
+		assert.Equal(ErrorEmptyTree, err)
+	})
+
+	t.Run("Missing Parent", func(t *testing.T) {
+		_, err := ParseTree([]byte(`{"data":[{"name":"__ROOT__"},{"name":"Hiking","id":"b","path":["Interests"]}]}`))
+		assert.Equal(ErrorMissingParent, err)
+	})
+
+	t.Run("Failing Unmarshal", func(t *testing.T) {
+		_, err := ParseTree([]byte(`{"data":[}`))
+		assert.Error(err)
+	})
+}
+
+func TestInsights(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Active   []string
+		Expected *Insights
+		Error    error
+	}{
+		{
+			Name:   "Single Gene",
+			Active: []string{"a"},
+			// 20 spend at 5 cpm buys 4000 impressions
+			Expected: &Insights{
+				Reach:       1000 * (1 - math.Exp(-4)),
+				Impressions: 4000,
+				UniqueCTR:   2,
+				CPM:         5,
+				Spend:       20,
+				Days:        2,
+			},
+		},
+		{
+			Name:   "Interacting Genes",
+			Active: []string{"a", "b"},
+			// audience weighted means, the ctr is increased by the interaction
+			Expected: &Insights{
+				Reach:       4000 * (1 - math.Exp(-20000/8.75/4000)),
+				Impressions: 20000 / 8.75,
+				UniqueCTR:   (2*1000 + 1*3000) / 4000.0 * 1.5,
+				CPM:         (5*1000 + 10*3000) / 4000.0,
+				Spend:       20,
+				Days:        2,
+			},
+		},
+		{
+			Name: "Broad Targeting",
+			Expected: &Insights{
+				Reach:       broadAudience * (1 - math.Exp(-5000/broadAudience)),
+				Impressions: 5000,
+				UniqueCTR:   broadCTR,
+				CPM:         broadCPM,
+				Spend:       20,
+				Days:        2,
+			},
+		},
+		{
+			Name:   "Unknown Gene",
+			Active: []string{"c"},
+			Error:  ErrorUnknownGene,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tree := testTargeting(t)
+			if tc.Error != nil {
+				// the market doesn't know the interests
+				tree.Root.Children = tree.Root.Children[:1]
+			}
+			s, err := New(tree,
+				RandomSource(genetic.NewSeededSource(1)),
+				Interactions(0),
+				Budget(10, 2),
+				GeneResponse("a", Response{Audience: 1000, CTR: 2, CPM: 5}),
+				GeneResponse("b", Response{Audience: 3000, CTR: 1, CPM: 10}),
+				Interaction("b", "a", 0.5),
+			)
+			assert.Nil(err)
+
+			i, err := s.Insights(testTargeting(t, tc.Active...))
+			if tc.Error != nil {
+				assert.Equal(tc.Error, err)
+				return
+			}
+			assert.Nil(err)
+			assert.InDelta(tc.Expected.Reach, i.Reach, 1e-6)
+			assert.InDelta(tc.Expected.Impressions, i.Impressions, 1e-6)
+			assert.InDelta(tc.Expected.UniqueCTR, i.UniqueCTR, 1e-9)
+			assert.InDelta(tc.Expected.CPM, i.CPM, 1e-9)
+			assert.Equal(tc.Expected.Spend, i.Spend)
+			assert.Equal(tc.Expected.Days, i.Days)
+
+			q, err := s.Quality(testTargeting(t, tc.Active...))
+			assert.Nil(err)
+			assert.InDelta(i.Reach*i.UniqueCTR/i.CPM/i.Days, q, 1e-6)
+
+			o, err := s.Objectives(testTargeting(t, tc.Active...))
+			assert.Nil(err)
+			assert.InDeltaSlice([]float64{i.Reach, i.UniqueCTR, -i.CPM}, o, 1e-6)
+		})
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("Seeded Market", func(t *testing.T) {
+		a, err := New(testTargeting(t), RandomSource(genetic.NewSeededSource(7)))
+		assert.Nil(err)
+		b, err := New(testTargeting(t), RandomSource(genetic.NewSeededSource(7)))
+		assert.Nil(err)
+		qa, err := a.Quality(testTargeting(t, "a", "c"))
+		assert.Nil(err)
+		qb, err := b.Quality(testTargeting(t, "a", "c"))
+		assert.Nil(err)
+		assert.Equal(qa, qb)
+	})
+
+	t.Run("Empty Chromosome", func(t *testing.T) {
+		_, err := New(&genetic.Chromosome{})
+		assert.Equal(ErrorEmptyChromosome, err)
+	})
+
+	t.Run("Invalid Noise", func(t *testing.T) {
+		for _, amplitude := range []float64{-0.1, 1, 1.5, math.NaN()} {
+			_, err := New(testTargeting(t), Noise(amplitude))
+			assert.Equal(ErrorInvalidNoise, err)
+		}
+	})
+
+	t.Run("Optimization", func(t *testing.T) {
+		s, err := New(testTargeting(t), RandomSource(genetic.NewSeededSource(3)), Noise(0.1))
+		assert.Nil(err)
+		population, err := s.Population(6, 0.5)
+		assert.Nil(err)
+		assert.Equal(6, len(population))
+
+		g := genetic.New(s.Quality, genetic.RandomSource(genetic.NewSeededSource(4)))
+		result, err := genetic.NewEngine(g,
+			genetic.EngineSource(genetic.NewSeededSource(5)),
+			genetic.PopulationSize(6, 3),
+			genetic.MaxGenerations(5),
+		).Run(population)
+		assert.Nil(err)
+		assert.True(len(result.Generations) <= 5)
+		for _, c := range result.Population {
+			assert.True(c.Quality > 0)
+		}
+	})
+}
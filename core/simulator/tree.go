@@ -0,0 +1,101 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/logger"
+)
+
+var (
+	// ErrorEmptyTree the targeting tree doesn't have a root
+	ErrorEmptyTree = errors.New("The targeting tree is empty")
+	// ErrorMissingParent a node of the targeting tree references a category that doesn't exist
+	ErrorMissingParent = errors.New("The parent of a targeting node doesn't exist")
+)
+
+type targetingNode struct {
+	RawName string   `json:"raw_name"`
+	Name    string   `json:"name"`
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Path    []string `json:"path"`
+}
+
+// LoadTree reads a targeting tree file with the targetingTree.json format
+// and creates a chromosome without any active gene
+func LoadTree(file string) (*genetic.Chromosome, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to load data from targeting tree.",
+			Err:     err,
+			Context: file,
+		}
+	}
+
+	return ParseTree(b)
+}
+
+// ParseTree creates a chromosome from the content of a targeting tree, the first node
+// is the root, nodes without an ID are categories and nodes with an ID are leaf genes
+func ParseTree(b []byte) (*genetic.Chromosome, error) {
+	var tree = struct {
+		Data []targetingNode `json:"data"`
+	}{}
+	err := json.Unmarshal(b, &tree)
+	if err != nil {
+		return nil, &logger.Error{
+			Level:   "Panic",
+			Message: "Unable to unmarshal data from targeting tree.",
+			Err:     err,
+		}
+	}
+	if len(tree.Data) == 0 {
+		return nil, ErrorEmptyTree
+	}
+
+	root := &genetic.Gene{
+		Name: tree.Data[0].Name,
+	}
+	path := map[string]*genetic.Gene{root.Name: root}
+	for _, node := range tree.Data[1:] {
+		parent := root
+		if len(node.Path) > 0 {
+			parent = path[node.Path[len(node.Path)-1]]
+		}
+		if parent == nil {
+			return nil, ErrorMissingParent
+		}
+		g := &genetic.Gene{
+			ID:     node.ID,
+			Name:   node.Name,
+			Type:   node.Type,
+			Parent: parent,
+		}
+		if node.ID == "" {
+			path[node.Name] = g
+		}
+		parent.Children = append(parent.Children, g)
+	}
+
+	return &genetic.Chromosome{Root: root}, nil
+}
+
+// leaves returns the genes with an ID in depth first order
+func leaves(g *genetic.Gene, genes []*genetic.Gene) []*genetic.Gene {
+	if g == nil {
+		return genes
+	}
+	if g.ID != "" {
+		genes = append(genes, g)
+	}
+	for _, child := range g.Children {
+		genes = leaves(child, genes)
+	}
+
+	return genes
+}
@@ -1,8 +1,12 @@
 package campaigns
 
 import (
+	"context"
+	"time"
+
 	"bitbucket.org/backend/core/entities"
 	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/storage/schema"
 )
 
 // Storage interface to get campaign information from database
@@ -10,9 +14,17 @@ type Storage interface {
 	// Campaign storage
 	StoreCampaign(userID, platform, adAccount, segment string, c *entities.Campaign) error
 	GetCampaign(campaignID string) (*entities.Campaign, error)
+	// UpdateCampaign updates the status, budget and schedule of a stored campaign
+	UpdateCampaign(c *entities.Campaign) error
 	GetUserCampaigns(userID string) (map[string][]string, error)
-	// GetActiveCampaigns returns a maping from userID to active campaigns' ID
-	GetActiveCampaigns(platform string) (map[string][]string, error)
+	// GetActiveCampaigns returns a maping from userID to the IDs of the campaigns of the filter
+	GetActiveCampaigns(f ActiveFilter) (map[string][]string, error)
+	// ListUserCampaigns returns a page of at most limit campaigns of a user in no particular
+	// order, next is the token of the previous page or empty for the first page
+	ListUserCampaigns(userID string, limit int, next string) (*Page, error)
+	// ListActiveCampaigns returns a page of at most limit campaigns
+	// of the filter in increasing order by end time
+	ListActiveCampaigns(f ActiveFilter, limit int, next string) (*Page, error)
 
 	// Segment Storage
 	// SetSegment initialices a segment to the provided initial targeting population
@@ -24,4 +36,43 @@ type Storage interface {
 	// GetSegmentCampaigns returns all campaigns created by a
 	// user initialized segment sorted in decesing order by end time
 	GetSegmentCampaigns(userID, segment string) ([]string, error)
+	// ListSegmentCampaigns returns a page of at most limit campaigns of
+	// a segment in decreasing order by end time
+	ListSegmentCampaigns(userID, segment string, limit int, next string) (*Page, error)
+	// SetEvolution stores the settings of the genetic algorithm used to optimize the segment,
+	// the elite count must be between one and the population size
+	SetEvolution(userID, segment string, e *entities.Evolution) error
+	// GetEvolution returns the evolution settings of the segment,
+	// nil is returned when the segment uses the default settings
+	GetEvolution(userID, segment string) (*entities.Evolution, error)
+
+	// WithContext returns the storage with its requests bound to the context
+	WithContext(ctx context.Context) Storage
+}
+
+// ActiveFilter selects the campaigns of a platform that haven't ended at a time
+type ActiveFilter struct {
+	Platform string
+	// Status selects the campaigns with the status, any status is selected when it's empty
+	Status string
+	// AsOf is the time when the campaigns haven't ended, it's the current time when it's zero
+	AsOf time.Time
+}
+
+// asOf returns the time of the filter in the layout of the end times
+func (f ActiveFilter) asOf() string {
+	if f.AsOf.IsZero() {
+		return schema.FormatTime(time.Now())
+	}
+
+	return schema.FormatTime(f.AsOf)
+}
+
+// link rebuilds the parent links of the targeting trees of a decoded population
+func link(population []*genetic.Chromosome) {
+	for _, c := range population {
+		if c != nil {
+			c.Link()
+		}
+	}
 }
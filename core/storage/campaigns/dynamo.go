@@ -1,14 +1,16 @@
 package campaigns
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
-	"time"
 
 	"bitbucket.org/backend/core/entities"
 	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/storage/schema"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -16,14 +18,58 @@ import (
 
 type dynamo struct {
 	svc *dynamodb.DynamoDB
+	// ctx is the context of the requests to dynamo
+	ctx context.Context
+	// table is the name of the table of the items
+	table string
+	// endpoint overrides the endpoint of the session when it isn't empty
+	endpoint string
 }
 
+// Option configures a dynamo storage
+type Option func(*dynamo)
+
 // New isntanciates a session dynamo session to query information
 // about users' campaigns
-func New(sess *session.Session) Storage {
-	return &dynamo{
-		svc: dynamodb.New(sess),
+func New(sess *session.Session, config ...Option) Storage {
+	d := &dynamo{
+		ctx:   context.Background(),
+		table: TableName,
+	}
+
+	for _, fn := range config {
+		fn(d)
+	}
+
+	cfg := aws.NewConfig()
+	if d.endpoint != "" {
+		cfg = cfg.WithEndpoint(d.endpoint)
 	}
+	d.svc = dynamodb.New(sess, cfg)
+
+	return d
+}
+
+// Endpoint sets the endpoint of the requests, like the url of a local stand-in of dynamo
+func Endpoint(url string) Option {
+	return func(d *dynamo) {
+		d.endpoint = url
+	}
+}
+
+// Table sets the name of the table of the items
+func Table(name string) Option {
+	return func(d *dynamo) {
+		d.table = name
+	}
+}
+
+// WithContext returns a copy of the storage whose requests are cancelled with the context
+func (d *dynamo) WithContext(ctx context.Context) Storage {
+	dc := *d
+	dc.ctx = ctx
+
+	return &dc
 }
 
 type sortKeys struct {
@@ -56,6 +102,12 @@ var (
 	ErrorMissingCampaignID = errors.New("Missing campaign id")
 	// ErrorUnableToFindCampaign get campaign found no result
 	ErrorUnableToFindCampaign = errors.New("Unable to find the campaign")
+	// ErrorMissingEvolution missing evolution settings
+	ErrorMissingEvolution = errors.New("Missing evolution settings")
+	// ErrorInvalidEvolution the population of the evolution settings can't keep its elites
+	ErrorInvalidEvolution = errors.New("The evolution elite count must be between one and the population size")
+	// ErrorInvalidEndTime the end time of the campaign isn't an ISO-8601 time
+	ErrorInvalidEndTime = errors.New("The campaign end time must be an ISO-8601 time")
 )
 
 func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *entities.Campaign) error {
@@ -74,6 +126,11 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 	if c == nil || c.ID == "" || c.StartTime == "" || c.EndTime == "" || c.Budget == "" || len(c.Targeting) == 0 || len(c.Media) == 0 {
 		return ErrorInvalidCampaign
 	}
+	// the end times are stored in UTC RFC3339 so they sort lexically
+	endTime, err := schema.NormalizeTime(c.EndTime)
+	if err != nil {
+		return ErrorInvalidEndTime
+	}
 
 	targeting, err := dynamodbattribute.Marshal(c.Targeting)
 	if err != nil {
@@ -85,7 +142,7 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 	}
 
 	in := &dynamodb.UpdateItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String("campaigns"),
@@ -110,7 +167,7 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 				S: aws.String(userID),
 			},
 			":secondSort": {
-				S: aws.String(c.EndTime),
+				S: aws.String(endTime),
 			},
 			":thirdSort": {
 				S: aws.String(fmt.Sprintf("%s:%s", userID, segment)),
@@ -118,6 +175,9 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 			":fourthSort": {
 				S: aws.String(platform),
 			},
+			":fifthSort": {
+				S: aws.String(segmentEnd(fmt.Sprintf("%s:%s", userID, segment), endTime)),
+			},
 			":platform": {
 				S: aws.String(platform),
 			},
@@ -134,7 +194,7 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 				S: aws.String(c.StartTime),
 			},
 			":endTime": {
-				S: aws.String(c.EndTime),
+				S: aws.String(endTime),
 			},
 			":budget": {
 				S: aws.String(c.Budget),
@@ -142,9 +202,16 @@ func (d *dynamo) StoreCampaign(userID, platform, adAccount, segment string, c *e
 			":targeting": targeting,
 			":media":     media,
 		},
-		UpdateExpression: aws.String("set sort=:sort, secondSort=:secondSort, thirdSort=:thirdSort, fourthSort=:fourthSort, #platform=:platform, #segment=:segment, #adAccount=:adAccount, #campaign=:campaign, #startTime=:startTime, #endTime=:endTime, #budget=:budget, #targeting=:targeting, #media=:media"),
+		UpdateExpression: aws.String("set sort=:sort, secondSort=:secondSort, thirdSort=:thirdSort, fourthSort=:fourthSort, fifthSort=:fifthSort, #platform=:platform, #segment=:segment, #adAccount=:adAccount, #campaign=:campaign, #startTime=:startTime, #endTime=:endTime, #budget=:budget, #targeting=:targeting, #media=:media"),
+	}
+	if c.Status != "" {
+		in.ExpressionAttributeNames["#status"] = aws.String("status")
+		in.ExpressionAttributeValues[":status"] = &dynamodb.AttributeValue{
+			S: aws.String(c.Status),
+		}
+		in.UpdateExpression = aws.String(fmt.Sprintf("%s, #status=:status", *in.UpdateExpression))
 	}
-	_, err = d.svc.UpdateItem(in)
+	_, err = d.svc.UpdateItemWithContext(d.ctx, in)
 	if err != nil {
 		return err
 	}
@@ -159,7 +226,7 @@ func (d *dynamo) GetCampaign(campaignID string) (*entities.Campaign, error) {
 	c := &entities.Campaign{}
 
 	in := &dynamodb.GetItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String("campaigns"),
@@ -169,7 +236,7 @@ func (d *dynamo) GetCampaign(campaignID string) (*entities.Campaign, error) {
 			},
 		},
 	}
-	out, err := d.svc.GetItem(in)
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
 	if err != nil {
 		return nil, err
 	}
@@ -181,18 +248,141 @@ func (d *dynamo) GetCampaign(campaignID string) (*entities.Campaign, error) {
 	if c.ID == "" {
 		return nil, ErrorUnableToFindCampaign
 	}
+	link(c.Targeting)
 
 	return c, nil
 }
 
+func (d *dynamo) UpdateCampaign(c *entities.Campaign) error {
+	if c == nil || c.ID == "" {
+		return ErrorMissingCampaignID
+	}
+	if c.Status == "" || c.StartTime == "" || c.EndTime == "" || c.Budget == "" {
+		return ErrorInvalidCampaign
+	}
+	endTime, err := schema.NormalizeTime(c.EndTime)
+	if err != nil {
+		return ErrorInvalidEndTime
+	}
+	keys, err := d.campaignKeys(c.ID)
+	if err != nil {
+		return err
+	}
+
+	in := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"partition": {
+				S: aws.String("campaigns"),
+			},
+			"key": {
+				S: aws.String(c.ID),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#campaign":  aws.String("id"),
+			"#status":    aws.String("status"),
+			"#startTime": aws.String("start_time"),
+			"#endTime":   aws.String("end_time"),
+			"#budget":    aws.String("budget"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":secondSort": {
+				S: aws.String(endTime),
+			},
+			":fifthSort": {
+				S: aws.String(segmentEnd(keys.ThirdSort, endTime)),
+			},
+			":status": {
+				S: aws.String(c.Status),
+			},
+			":startTime": {
+				S: aws.String(c.StartTime),
+			},
+			":endTime": {
+				S: aws.String(endTime),
+			},
+			":budget": {
+				S: aws.String(c.Budget),
+			},
+		},
+		// only stored campaigns are updated
+		ConditionExpression: aws.String("attribute_exists(#campaign)"),
+		UpdateExpression:    aws.String("set secondSort=:secondSort, fifthSort=:fifthSort, #status=:status, #startTime=:startTime, #endTime=:endTime, #budget=:budget"),
+	}
+	_, err = d.svc.UpdateItemWithContext(d.ctx, in)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrorUnableToFindCampaign
+	}
+
+	return err
+}
+
+// campaignKeys returns the sort keys of a stored campaign
+func (d *dynamo) campaignKeys(campaignID string) (*sortKeys, error) {
+	in := &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"partition": {
+				S: aws.String("campaigns"),
+			},
+			"key": {
+				S: aws.String(campaignID),
+			},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	keys := &sortKeys{}
+	err = dynamodbattribute.UnmarshalMap(out.Item, keys)
+	if err != nil {
+		return nil, err
+	}
+	if keys.Key == "" {
+		return nil, ErrorUnableToFindCampaign
+	}
+
+	return keys, nil
+}
+
+// segmentEnd is the sort key of the campaigns of a segment sorted by end time
+func segmentEnd(thirdSort, endTime string) string {
+	return fmt.Sprintf("%s:%s", thirdSort, endTime)
+}
+
 func (d *dynamo) GetUserCampaigns(userID string) (map[string][]string, error) {
 	if userID == "" {
 		return nil, ErrorMissingUserID
 	}
-	cIDs := []sortKeys{}
 
-	in := &dynamodb.QueryInput{
-		TableName: aws.String(TableName),
+	cIDs, err := d.queryAll(d.userCampaignsQuery(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(map[string][]string)
+	for _, keys := range cIDs {
+		c[keys.FourthSort] = append(c[keys.FourthSort], keys.Key)
+	}
+
+	return c, nil
+}
+
+func (d *dynamo) ListUserCampaigns(userID string, limit int, next string) (*Page, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+
+	return d.queryPage(d.userCampaignsQuery(userID), limit, next)
+}
+
+// userCampaignsQuery returns the query of the campaigns of the user
+func (d *dynamo) userCampaignsQuery(userID string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName: aws.String(d.table),
 		ExpressionAttributeNames: map[string]*string{
 			"#p": aws.String("partition"),
 			"#s": aws.String("sort"),
@@ -208,32 +398,38 @@ func (d *dynamo) GetUserCampaigns(userID string) (map[string][]string, error) {
 		IndexName:              aws.String("partition-sort-index"),
 		KeyConditionExpression: aws.String("#p = :partition AND #s = :sort"),
 	}
-	out, err := d.svc.Query(in)
-	if err != nil {
-		return nil, err
+}
+
+func (d *dynamo) GetActiveCampaigns(f ActiveFilter) (map[string][]string, error) {
+	if f.Platform == "" {
+		return nil, ErrorMissingPlatform
 	}
 
-	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &cIDs)
+	cIDs, err := d.queryAll(d.activeCampaignsQuery(f))
 	if err != nil {
 		return nil, err
 	}
 
 	c := make(map[string][]string)
 	for _, keys := range cIDs {
-		c[keys.FourthSort] = append(c[keys.FourthSort], keys.Key)
+		c[keys.Sort] = append(c[keys.Sort], keys.Key)
 	}
 
 	return c, nil
 }
 
-func (d *dynamo) GetActiveCampaigns(platform string) (map[string][]string, error) {
-	if platform == "" {
+func (d *dynamo) ListActiveCampaigns(f ActiveFilter, limit int, next string) (*Page, error) {
+	if f.Platform == "" {
 		return nil, ErrorMissingPlatform
 	}
-	cIDs := []sortKeys{}
 
+	return d.queryPage(d.activeCampaignsQuery(f), limit, next)
+}
+
+// activeCampaignsQuery returns the query of the campaigns of the filter
+func (d *dynamo) activeCampaignsQuery(f ActiveFilter) *dynamodb.QueryInput {
 	in := &dynamodb.QueryInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		ExpressionAttributeNames: map[string]*string{
 			"#p":  aws.String("partition"),
 			"#ss": aws.String("secondSort"),
@@ -244,32 +440,25 @@ func (d *dynamo) GetActiveCampaigns(platform string) (map[string][]string, error
 				S: aws.String("campaigns"),
 			},
 			":secondSort": {
-				S: aws.String(time.Now().String()),
+				S: aws.String(f.asOf()),
 			},
 			":4s": {
-				S: aws.String(platform),
+				S: aws.String(f.Platform),
 			},
 		},
 		IndexName:              aws.String("partition-secondSort-index"),
 		KeyConditionExpression: aws.String("#p = :partition AND #ss > :secondSort"),
 		FilterExpression:       aws.String("#4s = :4s"),
 	}
-	out, err := d.svc.Query(in)
-	if err != nil {
-		return nil, err
-	}
-
-	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &cIDs)
-	if err != nil {
-		return nil, err
+	if f.Status != "" {
+		in.ExpressionAttributeNames["#status"] = aws.String("status")
+		in.ExpressionAttributeValues[":status"] = &dynamodb.AttributeValue{
+			S: aws.String(f.Status),
+		}
+		in.FilterExpression = aws.String("#4s = :4s AND #status = :status")
 	}
 
-	c := make(map[string][]string)
-	for _, keys := range cIDs {
-		c[keys.Sort] = append(c[keys.Sort], keys.Key)
-	}
-
-	return c, nil
+	return in
 }
 
 func (d *dynamo) SetSegment(userID, segment string, initialPopulation []*genetic.Chromosome) error {
@@ -286,7 +475,7 @@ func (d *dynamo) SetSegment(userID, segment string, initialPopulation []*genetic
 	}
 
 	in := &dynamodb.UpdateItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String(userID),
@@ -310,7 +499,7 @@ func (d *dynamo) SetSegment(userID, segment string, initialPopulation []*genetic
 		},
 		UpdateExpression: aws.String("set #segment=:segment, #all=list_append(if_not_exists(#all, :empty), :name)"),
 	}
-	_, err = d.svc.UpdateItem(in)
+	_, err = d.svc.UpdateItemWithContext(d.ctx, in)
 
 	return err
 }
@@ -326,7 +515,7 @@ func (d *dynamo) GetSegment(userID, segment string) ([]*genetic.Chromosome, erro
 	population := []*genetic.Chromosome{}
 
 	in := &dynamodb.GetItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String(userID),
@@ -340,14 +529,15 @@ func (d *dynamo) GetSegment(userID, segment string) ([]*genetic.Chromosome, erro
 		},
 		ProjectionExpression: aws.String("#segment"),
 	}
-	out, err := d.svc.GetItem(in)
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
 	if err != nil {
 		return nil, err
 	}
-	err = dynamodbattribute.Unmarshal(out.Item[segment], population)
+	err = dynamodbattribute.Unmarshal(out.Item[segment], &population)
 	if err != nil {
 		return nil, err
 	}
+	link(population)
 
 	return population, nil
 }
@@ -357,7 +547,7 @@ func (d *dynamo) GetSegments(userID string) ([]string, error) {
 		return nil, ErrorMissingUserID
 	}
 	in := &dynamodb.GetItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String(userID),
@@ -371,7 +561,7 @@ func (d *dynamo) GetSegments(userID string) ([]string, error) {
 		},
 		ProjectionExpression: aws.String("#names"),
 	}
-	out, err := d.svc.GetItem(in)
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
 	if err != nil {
 		return nil, err
 	}
@@ -392,10 +582,9 @@ func (d *dynamo) GetSegmentCampaigns(userID, segment string) ([]string, error) {
 	if segment == "" {
 		return nil, ErrorMissingSegment
 	}
-	cIDs := []sortKeys{}
 
 	in := &dynamodb.QueryInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		ExpressionAttributeNames: map[string]*string{
 			"#p": aws.String("partition"),
 			"#s": aws.String("thirdSort"),
@@ -411,12 +600,7 @@ func (d *dynamo) GetSegmentCampaigns(userID, segment string) ([]string, error) {
 		IndexName:              aws.String("partition-thirdSort-index"),
 		KeyConditionExpression: aws.String("#p = :partition AND #s = :thirdSort"),
 	}
-	out, err := d.svc.Query(in)
-	if err != nil {
-		return nil, err
-	}
-
-	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &cIDs)
+	cIDs, err := d.queryAll(in)
 	if err != nil {
 		return nil, err
 	}
@@ -432,3 +616,201 @@ func (d *dynamo) GetSegmentCampaigns(userID, segment string) ([]string, error) {
 
 	return c, nil
 }
+
+func (d *dynamo) ListSegmentCampaigns(userID, segment string, limit int, next string) (*Page, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+	thirdSort := fmt.Sprintf("%s:%s", userID, segment)
+
+	in := &dynamodb.QueryInput{
+		TableName: aws.String(d.table),
+		ExpressionAttributeNames: map[string]*string{
+			"#p":  aws.String("partition"),
+			"#3s": aws.String("thirdSort"),
+			"#5s": aws.String("fifthSort"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":partition": {
+				S: aws.String("campaigns"),
+			},
+			":thirdSort": {
+				S: aws.String(thirdSort),
+			},
+			":fifthSort": {
+				S: aws.String(segmentEnd(thirdSort, "")),
+			},
+		},
+		IndexName:              aws.String("partition-fifthSort-index"),
+		KeyConditionExpression: aws.String("#p = :partition AND begins_with(#5s, :fifthSort)"),
+		// the prefix also matches the segments that start with the segment and a colon
+		FilterExpression: aws.String("#3s = :thirdSort"),
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	return d.queryPage(in, limit, next)
+}
+
+// queryAll returns the keys of the items of every page of the query
+func (d *dynamo) queryAll(in *dynamodb.QueryInput) ([]sortKeys, error) {
+	cIDs := []sortKeys{}
+	var pageErr error
+	err := d.svc.QueryPagesWithContext(d.ctx, in, func(out *dynamodb.QueryOutput, last bool) bool {
+		page := []sortKeys{}
+		if pageErr = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page); pageErr != nil {
+			return false
+		}
+		cIDs = append(cIDs, page...)
+		return true
+	})
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cIDs, nil
+}
+
+// queryPage returns a page of at most limit items of the query that starts at the key
+// of the token, the query continues until the page is full because dynamo applies
+// the filters after the limit
+func (d *dynamo) queryPage(in *dynamodb.QueryInput, limit int, next string) (*Page, error) {
+	if limit <= 0 {
+		return nil, ErrorInvalidLimit
+	}
+	start, err := decodeToken(next)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range start {
+		if in.ExclusiveStartKey == nil {
+			in.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{}
+		}
+		in.ExclusiveStartKey[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+
+	cIDs := []sortKeys{}
+	for {
+		in.Limit = aws.Int64(int64(limit - len(cIDs)))
+		out, err := d.svc.QueryWithContext(d.ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		page := []sortKeys{}
+		err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page)
+		if err != nil {
+			return nil, err
+		}
+		cIDs = append(cIDs, page...)
+
+		if len(out.LastEvaluatedKey) == 0 {
+			return newPage(cIDs, ""), nil
+		}
+		if len(cIDs) == limit {
+			last := map[string]string{}
+			for name, value := range out.LastEvaluatedKey {
+				last[name] = aws.StringValue(value.S)
+			}
+			token, err := encodeToken(last)
+			if err != nil {
+				return nil, err
+			}
+			return newPage(cIDs, token), nil
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// evolutionAttribute is the attribute of the segments item
+// that contains the evolution settings of a segment
+func evolutionAttribute(segment string) string {
+	return fmt.Sprintf("evolution:%s", segment)
+}
+
+func (d *dynamo) SetEvolution(userID, segment string, e *entities.Evolution) error {
+	if userID == "" {
+		return ErrorMissingUserID
+	}
+	if segment == "" {
+		return ErrorMissingSegment
+	}
+	if e == nil {
+		return ErrorMissingEvolution
+	}
+	if e.EliteCount < 1 || e.PopulationSize < e.EliteCount {
+		return ErrorInvalidEvolution
+	}
+
+	evolution, err := dynamodbattribute.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	in := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"partition": {
+				S: aws.String(userID),
+			},
+			"key": {
+				S: aws.String("segments"),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#evolution": aws.String(evolutionAttribute(segment)),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":evolution": evolution,
+		},
+		UpdateExpression: aws.String("set #evolution=:evolution"),
+	}
+	_, err = d.svc.UpdateItemWithContext(d.ctx, in)
+
+	return err
+}
+
+func (d *dynamo) GetEvolution(userID, segment string) (*entities.Evolution, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+
+	in := &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"partition": {
+				S: aws.String(userID),
+			},
+			"key": {
+				S: aws.String("segments"),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#evolution": aws.String(evolutionAttribute(segment)),
+		},
+		ProjectionExpression: aws.String("#evolution"),
+	}
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	av, ok := out.Item[evolutionAttribute(segment)]
+	if !ok {
+		return nil, nil
+	}
+
+	e := &entities.Evolution{}
+	err = dynamodbattribute.Unmarshal(av, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
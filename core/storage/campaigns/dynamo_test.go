@@ -6,53 +6,30 @@ import (
 
 	"bitbucket.org/backend/core/entities"
 	"bitbucket.org/backend/core/genetic"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"bitbucket.org/backend/core/storage/schema"
+	"bitbucket.org/backend/core/storage/schema/schematest"
 	"github.com/stretchr/testify/assert"
 )
 
-func testCreateCampaign(t *testing.T, userID, platform, adAccount, segment string, c *entities.Campaign) {
-	t.Helper()
+// testDynamo returns a storage of a new table of the local stand-in of dynamo
+// set in schematest.EndpointEnv, the table is deleted at the end of the test
+func testDynamo(t *testing.T) Storage {
+	sess, table := schematest.LocalTable(t, schema.Trinacia)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+	return New(sess, Table(table))
+}
 
-	storage := New(sess)
-	err = storage.StoreCampaign(userID, platform, adAccount, segment, c)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+func TestDynamo(t *testing.T) {
+	testStorage(t, testDynamo)
 }
 
-func testDeleteItem(t *testing.T, partition, key string) {
+func testCreateCampaign(t *testing.T, storage Storage, userID, platform, adAccount, segment string, c *entities.Campaign) {
 	t.Helper()
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
+	err := storage.StoreCampaign(userID, platform, adAccount, segment, c)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	svc := dynamodb.New(sess)
-	in := &dynamodb.DeleteItemInput{
-		TableName: aws.String(TableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"partition": {
-				S: aws.String(partition),
-			},
-			"key": {
-				S: aws.String(key),
-			},
-		},
-	}
-	if _, err := svc.DeleteItem(in); err != nil {
-		t.Fatalf("err: %s", err)
-	}
 }
 
 func TestStoreCampaign(t *testing.T) {
@@ -70,8 +47,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -90,8 +67,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -110,8 +87,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -130,8 +107,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -150,8 +127,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -179,8 +156,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -200,7 +177,7 @@ func TestStoreCampaign(t *testing.T) {
 				ID:        "campaign1234",
 				Budget:    "1bn",
 				StartTime: "",
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -219,7 +196,7 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
 				EndTime:   "",
 				Targeting: []*genetic.Chromosome{
 					{},
@@ -239,8 +216,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -259,8 +236,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: nil,
 				Media: []entities.Media{
 					{},
@@ -277,8 +254,8 @@ func TestStoreCampaign(t *testing.T) {
 			Campaign: &entities.Campaign{
 				ID:        "campaign1234",
 				Budget:    "1 bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{},
 				},
@@ -288,20 +265,9 @@ func TestStoreCampaign(t *testing.T) {
 		},
 	}
 	assert := assert.New(t)
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-
-	storage := New(sess)
+	storage := testDynamo(t)
 
 	for _, tc := range cases {
-		if tc.Error == nil {
-			defer testDeleteItem(t, "campaigns", tc.Campaign.ID)
-		}
 		t.Run(tc.Name, func(t *testing.T) {
 			err := storage.StoreCampaign(tc.UserID, tc.Platform, tc.AdAccount, tc.Segment, tc.Campaign)
 			assert.Equal(tc.Error, err)
@@ -322,8 +288,8 @@ func TestGetCampaign(t *testing.T) {
 			Expected: &entities.Campaign{
 				ID:        "1234",
 				Budget:    "1bn",
-				StartTime: time.Now().String(),
-				EndTime:   time.Now().Add(time.Hour * 365).String(),
+				StartTime: time.Now().UTC().Format(time.RFC3339),
+				EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 				Targeting: []*genetic.Chromosome{
 					{
 						ID: "test",
@@ -347,30 +313,91 @@ func TestGetCampaign(t *testing.T) {
 			Error:    ErrorUnableToFindCampaign,
 		},
 	}
+	storage := testDynamo(t)
+
 	// create test campaigns
 	for _, tc := range cases {
 		if tc.Error == nil {
-			testCreateCampaign(t, "testUser", "testPlatform", "testAdAccount", "testSegment", tc.Expected)
-			defer testDeleteItem(t, "campaigns", tc.Expected.ID)
+			testCreateCampaign(t, storage, "testUser", "testPlatform", "testAdAccount", "testSegment", tc.Expected)
 		}
 	}
 
 	assert := assert.New(t)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, err := storage.GetCampaign(tc.ID)
+			assert.Equal(tc.Expected, c)
+			assert.Equal(tc.Error, err)
+		})
 	}
+}
 
-	storage := New(sess)
+func TestUpdateCampaign(t *testing.T) {
+	stored := &entities.Campaign{
+		ID:        "1234",
+		Status:    "ACTIVE",
+		Budget:    "1bn",
+		StartTime: time.Now().UTC().Format(time.RFC3339),
+		EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
+		Targeting: []*genetic.Chromosome{
+			{
+				ID: "test",
+			},
+		},
+		Media: []entities.Media{
+			{},
+		},
+	}
+	cases := []struct {
+		Name     string
+		Campaign *entities.Campaign
+		Error    error
+	}{
+		{
+			Name: "Paused Campaign",
+			Campaign: &entities.Campaign{
+				ID:        "1234",
+				Status:    "PAUSED",
+				Budget:    "2bn",
+				StartTime: stored.StartTime,
+				EndTime:   time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+				Targeting: stored.Targeting,
+				Media:     stored.Media,
+			},
+			Error: nil,
+		},
+		{
+			Name:     "Missing ID",
+			Campaign: &entities.Campaign{},
+			Error:    ErrorMissingCampaignID,
+		},
+		{
+			Name:     "Missing Status",
+			Campaign: &entities.Campaign{ID: "1234", Budget: "1bn", StartTime: "now", EndTime: "later"},
+			Error:    ErrorInvalidCampaign,
+		},
+		{
+			Name:     "Unable To Find Campaign",
+			Campaign: &entities.Campaign{ID: "12345", Status: "PAUSED", Budget: "1bn", StartTime: "now", EndTime: "2020-08-01T10:30:00Z"},
+			Error:    ErrorUnableToFindCampaign,
+		},
+	}
+	storage := testDynamo(t)
+	testCreateCampaign(t, storage, "testUser", "testPlatform", "testAdAccount", "testSegment", stored)
+
+	assert := assert.New(t)
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			c, err := storage.GetCampaign(tc.ID)
-			assert.Equal(tc.Expected, c)
+			err := storage.UpdateCampaign(tc.Campaign)
 			assert.Equal(tc.Error, err)
+			if tc.Error != nil {
+				return
+			}
+			c, err := storage.GetCampaign(tc.Campaign.ID)
+			assert.Nil(err)
+			assert.Equal(tc.Campaign, c)
 		})
 	}
 }
@@ -407,8 +434,8 @@ func TestGetUserCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "1234",
 						Budget:    "1bn",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -434,8 +461,8 @@ func TestGetUserCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "12345",
 						Budget:    "1bn",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -450,8 +477,8 @@ func TestGetUserCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "123456",
 						Budget:    "1bn",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -475,11 +502,12 @@ func TestGetUserCampaigns(t *testing.T) {
 			Error:    ErrorMissingUserID,
 		},
 	}
+	storage := testDynamo(t)
+
 	for _, tc := range cases {
 		if tc.Error == nil {
 			for _, campaign := range tc.Campaign {
-				testCreateCampaign(t, tc.UserID, tc.Platform, campaign.AdAccount, campaign.Segment, campaign.Campaign)
-				defer testDeleteItem(t, "campaigns", campaign.Campaign.ID)
+				testCreateCampaign(t, storage, tc.UserID, tc.Platform, campaign.AdAccount, campaign.Segment, campaign.Campaign)
 			}
 
 		}
@@ -487,15 +515,6 @@ func TestGetUserCampaigns(t *testing.T) {
 
 	assert := assert.New(t)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-
-	storage := New(sess)
-
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			c, err := storage.GetUserCampaigns(tc.UserID)
@@ -528,8 +547,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "123",
 						Budget:    "1234",
-						StartTime: time.Now().Add(-time.Hour * 365).String(),
-						EndTime:   time.Now().Add(-time.Hour * 48).String(),
+						StartTime: time.Now().Add(-time.Hour * 365).UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(-time.Hour * 48).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -553,8 +572,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "1234",
 						Budget:    "1234",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -580,8 +599,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "12345",
 						Budget:    "1234",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -597,8 +616,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "123456",
 						Budget:    "1234",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -624,8 +643,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "1234567",
 						Budget:    "1234",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -641,8 +660,8 @@ func TestGetActiveCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "12345678",
 						Budget:    "1234",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -666,28 +685,21 @@ func TestGetActiveCampaigns(t *testing.T) {
 			Error:     ErrorMissingPlatform,
 		},
 	}
+	storage := testDynamo(t)
+
 	for _, tc := range cases {
 		if tc.Error == nil {
 			for _, campaign := range tc.Campaigns {
-				testCreateCampaign(t, campaign.UserID, tc.Platform, campaign.AdAccount, campaign.Segment, campaign.Campaign)
-				defer testDeleteItem(t, "campaigns", campaign.Campaign.ID)
+				testCreateCampaign(t, storage, campaign.UserID, tc.Platform, campaign.AdAccount, campaign.Segment, campaign.Campaign)
 			}
 		}
 	}
 
 	assert := assert.New(t)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	storage := New(sess)
-
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			c, err := storage.GetActiveCampaigns(tc.Platform)
+			c, err := storage.GetActiveCampaigns(ActiveFilter{Platform: tc.Platform})
 			assert.Equal(tc.Expected, c)
 			if tc.Error != err {
 				t.Fatal(err)
@@ -728,8 +740,8 @@ func TestGetSegmentCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "11234",
 						Budget:    "1bn",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -753,8 +765,8 @@ func TestGetSegmentCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "1234",
 						Budget:    "500m",
-						StartTime: time.Now().String(),
-						EndTime:   time.Now().Add(time.Hour * 365).String(),
+						StartTime: time.Now().UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 365).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -769,8 +781,8 @@ func TestGetSegmentCampaigns(t *testing.T) {
 					Campaign: &entities.Campaign{
 						ID:        "12345",
 						Budget:    "500m",
-						StartTime: time.Now().Add(-time.Hour * 24).String(),
-						EndTime:   time.Now().Add(time.Hour * 367).String(),
+						StartTime: time.Now().Add(-time.Hour * 24).UTC().Format(time.RFC3339),
+						EndTime:   time.Now().Add(time.Hour * 367).UTC().Format(time.RFC3339),
 						Targeting: []*genetic.Chromosome{
 							{},
 						},
@@ -800,11 +812,12 @@ func TestGetSegmentCampaigns(t *testing.T) {
 			Error:     ErrorMissingSegment,
 		},
 	}
+	storage := testDynamo(t)
+
 	for _, tc := range cases {
 		if tc.Error == nil {
 			for _, campaign := range tc.Campaigns {
-				testCreateCampaign(t, tc.UserID, campaign.Platform, campaign.AdAccount, tc.Segment, campaign.Campaign)
-				defer testDeleteItem(t, "campaigns", campaign.Campaign.ID)
+				testCreateCampaign(t, storage, tc.UserID, campaign.Platform, campaign.AdAccount, tc.Segment, campaign.Campaign)
 			}
 
 		}
@@ -812,20 +825,87 @@ func TestGetSegmentCampaigns(t *testing.T) {
 
 	assert := assert.New(t)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, err := storage.GetSegmentCampaigns(tc.UserID, tc.Segment)
+			assert.Equal(tc.Expected, c)
+			assert.Equal(tc.Error, err)
+		})
+	}
+}
+
+func TestEvolution(t *testing.T) {
+	cases := []struct {
+		Name      string
+		UserID    string
+		Segment   string
+		Evolution *entities.Evolution
+		Error     error
+	}{
+		{
+			Name:    "Segment Evolution",
+			UserID:  "1234",
+			Segment: "Evolution",
+			Evolution: &entities.Evolution{
+				PopulationSize:  10,
+				EliteCount:      3,
+				MinMutationRate: 0.01,
+				MaxMutationRate: 0.1,
+				CrossoverRate:   0.5,
+			},
+			Error: nil,
+		},
+		{
+			Name:      "Missing Evolution",
+			UserID:    "1234",
+			Segment:   "Evolution",
+			Evolution: nil,
+			Error:     ErrorMissingEvolution,
+		},
+		{
+			Name:    "Population Smaller Than Elites",
+			UserID:  "1234",
+			Segment: "Evolution",
+			Evolution: &entities.Evolution{
+				PopulationSize: 2,
+				EliteCount:     3,
+			},
+			Error: ErrorInvalidEvolution,
+		},
+		{
+			Name:      "Missing User ID",
+			UserID:    "",
+			Segment:   "Evolution",
+			Evolution: &entities.Evolution{},
+			Error:     ErrorMissingUserID,
+		},
+		{
+			Name:      "Missing Segment",
+			UserID:    "1234",
+			Segment:   "",
+			Evolution: &entities.Evolution{},
+			Error:     ErrorMissingSegment,
+		},
 	}
+	assert := assert.New(t)
+	storage := testDynamo(t)
 
-	storage := New(sess)
+	t.Run("Default Evolution", func(t *testing.T) {
+		e, err := storage.GetEvolution("1234", "Default")
+		assert.Nil(err)
+		assert.Nil(e)
+	})
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			c, err := storage.GetSegmentCampaigns(tc.UserID, tc.Segment)
-			assert.Equal(tc.Expected, c)
+			err := storage.SetEvolution(tc.UserID, tc.Segment, tc.Evolution)
 			assert.Equal(tc.Error, err)
+			if tc.Error != nil {
+				return
+			}
+			e, err := storage.GetEvolution(tc.UserID, tc.Segment)
+			assert.Nil(err)
+			assert.Equal(tc.Evolution, e)
 		})
 	}
 }
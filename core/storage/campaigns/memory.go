@@ -0,0 +1,498 @@
+package campaigns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+	"bitbucket.org/backend/core/storage/schema"
+)
+
+type memory struct {
+	*tables
+	// ctx is the context of the operations, the storage fails once it's done
+	ctx context.Context
+}
+
+// tables are the items of a memory storage, they are shared by its copies
+type tables struct {
+	mu        sync.RWMutex
+	campaigns map[string]*campaignItem
+	segments  map[string]*segmentsItem
+}
+
+// campaignItem is a stored campaign with the keys of the indexes
+type campaignItem struct {
+	sortKeys
+	Campaign entities.Campaign
+}
+
+// segmentsItem are the segments of a user
+type segmentsItem struct {
+	// names keep every set segment in order, with repetitions
+	names      []string
+	population map[string][]*genetic.Chromosome
+	evolution  map[string]*entities.Evolution
+}
+
+// NewMemory returns a storage that keeps the campaigns and segments in memory, it's
+// safe for concurrent use and behaves as the dynamo storage, so it can replace it in
+// tests and local development
+func NewMemory() Storage {
+	return &memory{
+		tables: &tables{
+			campaigns: map[string]*campaignItem{},
+			segments:  map[string]*segmentsItem{},
+		},
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a copy of the storage that fails once the context is
+// done, the copy shares the stored items
+func (m *memory) WithContext(ctx context.Context) Storage {
+	mc := *m
+	mc.ctx = ctx
+
+	return &mc
+}
+
+func (m *memory) StoreCampaign(userID, platform, adAccount, segment string, c *entities.Campaign) error {
+	if userID == "" {
+		return ErrorMissingUserID
+	}
+	if platform == "" {
+		return ErrorMissingPlatform
+	}
+	if adAccount == "" {
+		return ErrorMissingAdAccount
+	}
+	if segment == "" {
+		return ErrorMissingSegment
+	}
+	if c == nil || c.ID == "" || c.StartTime == "" || c.EndTime == "" || c.Budget == "" || len(c.Targeting) == 0 || len(c.Media) == 0 {
+		return ErrorInvalidCampaign
+	}
+	endTime, err := schema.NormalizeTime(c.EndTime)
+	if err != nil {
+		return ErrorInvalidEndTime
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	stored := entities.Campaign{}
+	if err := copyValue(c, &stored); err != nil {
+		return err
+	}
+	stored.EndTime = endTime
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the status is kept when the campaign is stored again without it
+	if item, ok := m.campaigns[c.ID]; ok && stored.Status == "" {
+		stored.Status = item.Campaign.Status
+	}
+	thirdSort := fmt.Sprintf("%s:%s", userID, segment)
+	m.campaigns[c.ID] = &campaignItem{
+		sortKeys: sortKeys{
+			Partition:  "campaigns",
+			Key:        c.ID,
+			Sort:       userID,
+			SecondSort: endTime,
+			ThirdSort:  thirdSort,
+			FourthSort: platform,
+			FifthSort:  segmentEnd(thirdSort, endTime),
+		},
+		Campaign: stored,
+	}
+
+	return nil
+}
+
+func (m *memory) GetCampaign(campaignID string) (*entities.Campaign, error) {
+	if campaignID == "" {
+		return nil, ErrorMissingCampaignID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.campaigns[campaignID]
+	if !ok {
+		return nil, ErrorUnableToFindCampaign
+	}
+	c := &entities.Campaign{}
+	if err := copyValue(&item.Campaign, c); err != nil {
+		return nil, err
+	}
+	link(c.Targeting)
+
+	return c, nil
+}
+
+func (m *memory) UpdateCampaign(c *entities.Campaign) error {
+	if c == nil || c.ID == "" {
+		return ErrorMissingCampaignID
+	}
+	if c.Status == "" || c.StartTime == "" || c.EndTime == "" || c.Budget == "" {
+		return ErrorInvalidCampaign
+	}
+	endTime, err := schema.NormalizeTime(c.EndTime)
+	if err != nil {
+		return ErrorInvalidEndTime
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.campaigns[c.ID]
+	if !ok {
+		return ErrorUnableToFindCampaign
+	}
+	item.SecondSort = endTime
+	item.FifthSort = segmentEnd(item.ThirdSort, endTime)
+	item.Campaign.Status = c.Status
+	item.Campaign.StartTime = c.StartTime
+	item.Campaign.EndTime = endTime
+	item.Campaign.Budget = c.Budget
+
+	return nil
+}
+
+func (m *memory) GetUserCampaigns(userID string) (map[string][]string, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := make(map[string][]string)
+	for _, keys := range m.query(func(item *campaignItem) bool {
+		return item.Sort == userID
+	}) {
+		c[keys.FourthSort] = append(c[keys.FourthSort], keys.Key)
+	}
+
+	return c, nil
+}
+
+func (m *memory) GetActiveCampaigns(f ActiveFilter) (map[string][]string, error) {
+	if f.Platform == "" {
+		return nil, ErrorMissingPlatform
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := make(map[string][]string)
+	for _, keys := range m.query(f.match()) {
+		c[keys.Sort] = append(c[keys.Sort], keys.Key)
+	}
+
+	return c, nil
+}
+
+func (m *memory) ListUserCampaigns(userID string, limit int, next string) (*Page, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.page(func(item *campaignItem) bool {
+		return item.Sort == userID
+	}, func(a, b sortKeys) bool {
+		return a.Key < b.Key
+	}, limit, next)
+}
+
+func (m *memory) ListActiveCampaigns(f ActiveFilter, limit int, next string) (*Page, error) {
+	if f.Platform == "" {
+		return nil, ErrorMissingPlatform
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.page(f.match(), func(a, b sortKeys) bool {
+		if a.SecondSort != b.SecondSort {
+			return a.SecondSort < b.SecondSort
+		}
+		return a.Key < b.Key
+	}, limit, next)
+}
+
+func (m *memory) SetSegment(userID, segment string, initialPopulation []*genetic.Chromosome) error {
+	if userID == "" {
+		return ErrorMissingUserID
+	}
+	if segment == "" {
+		return ErrorMissingSegment
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	population := []*genetic.Chromosome{}
+	if err := copyValue(initialPopulation, &population); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.userSegments(userID)
+	s.population[segment] = population
+	s.names = append(s.names, segment)
+
+	return nil
+}
+
+func (m *memory) GetSegment(userID, segment string) ([]*genetic.Chromosome, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	population := []*genetic.Chromosome{}
+	if s, ok := m.segments[userID]; ok && s.population[segment] != nil {
+		if err := copyValue(s.population[segment], &population); err != nil {
+			return nil, err
+		}
+		link(population)
+	}
+
+	return population, nil
+}
+
+func (m *memory) GetSegments(userID string) ([]string, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	segments := []string{}
+	if s, ok := m.segments[userID]; ok {
+		segments = append(segments, s.names...)
+	}
+
+	return segments, nil
+}
+
+func (m *memory) GetSegmentCampaigns(userID, segment string) ([]string, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	thirdSort := fmt.Sprintf("%s:%s", userID, segment)
+	cIDs := m.query(func(item *campaignItem) bool {
+		return item.ThirdSort == thirdSort
+	})
+	sort.SliceStable(cIDs, func(i, j int) bool {
+		return cIDs[i].SecondSort > cIDs[j].SecondSort
+	})
+
+	c := make([]string, len(cIDs))
+	for i, keys := range cIDs {
+		c[i] = keys.Key
+	}
+
+	return c, nil
+}
+
+func (m *memory) ListSegmentCampaigns(userID, segment string, limit int, next string) (*Page, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	thirdSort := fmt.Sprintf("%s:%s", userID, segment)
+	return m.page(func(item *campaignItem) bool {
+		return item.ThirdSort == thirdSort
+	}, func(a, b sortKeys) bool {
+		if a.FifthSort != b.FifthSort {
+			return a.FifthSort > b.FifthSort
+		}
+		return a.Key < b.Key
+	}, limit, next)
+}
+
+func (m *memory) SetEvolution(userID, segment string, e *entities.Evolution) error {
+	if userID == "" {
+		return ErrorMissingUserID
+	}
+	if segment == "" {
+		return ErrorMissingSegment
+	}
+	if e == nil {
+		return ErrorMissingEvolution
+	}
+	if e.EliteCount < 1 || e.PopulationSize < e.EliteCount {
+		return ErrorInvalidEvolution
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	evolution := *e
+	m.userSegments(userID).evolution[segment] = &evolution
+
+	return nil
+}
+
+func (m *memory) GetEvolution(userID, segment string) (*entities.Evolution, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if segment == "" {
+		return nil, ErrorMissingSegment
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.segments[userID]
+	if !ok || s.evolution[segment] == nil {
+		return nil, nil
+	}
+	e := *s.evolution[segment]
+
+	return &e, nil
+}
+
+// query returns the keys of the campaigns that match the condition sorted
+// by campaign id, so the results of an index key have a stable order
+func (m *memory) query(match func(*campaignItem) bool) []sortKeys {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cIDs := []sortKeys{}
+	for _, item := range m.campaigns {
+		if match(item) {
+			cIDs = append(cIDs, item.sortKeys)
+		}
+	}
+	sort.Slice(cIDs, func(i, j int) bool {
+		return cIDs[i].Key < cIDs[j].Key
+	})
+
+	return cIDs
+}
+
+// match returns the condition of the campaigns of the filter
+func (f ActiveFilter) match() func(*campaignItem) bool {
+	asOf := f.asOf()
+	return func(item *campaignItem) bool {
+		return item.SecondSort > asOf && item.FourthSort == f.Platform && (f.Status == "" || item.Campaign.Status == f.Status)
+	}
+}
+
+// page returns a page of at most limit keys of the campaigns that match the condition
+// sorted by less, the token of the next page has the keys of the last campaign
+func (m *memory) page(match func(*campaignItem) bool, less func(a, b sortKeys) bool, limit int, next string) (*Page, error) {
+	if limit <= 0 {
+		return nil, ErrorInvalidLimit
+	}
+	start, err := decodeToken(next)
+	if err != nil {
+		return nil, err
+	}
+
+	cIDs := m.query(match)
+	sort.SliceStable(cIDs, func(i, j int) bool {
+		return less(cIDs[i], cIDs[j])
+	})
+	if start != nil {
+		last := sortKeys{}
+		if err := copyValue(start, &last); err != nil {
+			return nil, ErrorInvalidNextToken
+		}
+		cIDs = cIDs[sort.Search(len(cIDs), func(i int) bool {
+			return less(last, cIDs[i])
+		}):]
+	}
+	if len(cIDs) <= limit {
+		return newPage(cIDs, ""), nil
+	}
+
+	cIDs = cIDs[:limit]
+	last := map[string]string{}
+	if err := copyValue(cIDs[limit-1], &last); err != nil {
+		return nil, err
+	}
+	token, err := encodeToken(last)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(cIDs, token), nil
+}
+
+// userSegments returns the segments item of the user, it's created when the
+// user doesn't have one, the caller must hold the write lock
+func (m *memory) userSegments(userID string) *segmentsItem {
+	s, ok := m.segments[userID]
+	if !ok {
+		s = &segmentsItem{
+			population: map[string][]*genetic.Chromosome{},
+			evolution:  map[string]*entities.Evolution{},
+		}
+		m.segments[userID] = s
+	}
+
+	return s
+}
+
+// copyValue copies the value through its json encoding so the stored items
+// don't share the pointers and slices of the callers, the parent links of
+// the copied targeting trees must be rebuilt with link
+func copyValue(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dst)
+}
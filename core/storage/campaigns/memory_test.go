@@ -0,0 +1,9 @@
+package campaigns
+
+import "testing"
+
+func TestMemory(t *testing.T) {
+	testStorage(t, func(t *testing.T) Storage {
+		return NewMemory()
+	})
+}
@@ -0,0 +1,72 @@
+package campaigns
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrorInvalidLimit the limit of a page isn't positive
+	ErrorInvalidLimit = errors.New("The page limit must be positive")
+	// ErrorInvalidNextToken the next token wasn't returned by a page
+	ErrorInvalidNextToken = errors.New("Invalid next page token")
+)
+
+// Page is a page of the campaigns of a paginated query
+type Page struct {
+	Campaigns []CampaignRef `json:"campaigns"`
+	// Next is the token of the next page, it's empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// CampaignRef is the id of a campaign with the user and platform it belongs to
+type CampaignRef struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	Platform string `json:"platform"`
+}
+
+// newPage returns the page of the keys of the campaigns
+func newPage(cIDs []sortKeys, next string) *Page {
+	p := &Page{
+		Campaigns: make([]CampaignRef, len(cIDs)),
+		Next:      next,
+	}
+	for i, keys := range cIDs {
+		p.Campaigns[i] = CampaignRef{
+			ID:       keys.Key,
+			UserID:   keys.Sort,
+			Platform: keys.FourthSort,
+		}
+	}
+
+	return p
+}
+
+// encodeToken returns the opaque token of the key where the next page starts
+func encodeToken(key map[string]string) (string, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeToken returns the key of the token, it's nil for the empty token of the first page
+func decodeToken(token string) (map[string]string, error) {
+	if token == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrorInvalidNextToken
+	}
+	key := map[string]string{}
+	if err := json.Unmarshal(b, &key); err != nil || len(key) == 0 {
+		return nil, ErrorInvalidNextToken
+	}
+
+	return key, nil
+}
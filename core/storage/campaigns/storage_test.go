@@ -0,0 +1,591 @@
+package campaigns
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/entities"
+	"bitbucket.org/backend/core/genetic"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCampaign returns a campaign with every required field that ends after the duration
+func testCampaign(id string, end time.Duration) *entities.Campaign {
+	root := &genetic.Gene{Name: "root"}
+	root.Children = []*genetic.Gene{{ID: "b1", Type: "behaviors", Value: 1, Parent: root}}
+	c := &genetic.Chromosome{ID: "test", Root: root}
+
+	return &entities.Campaign{
+		ID:        id,
+		Budget:    "1bn",
+		StartTime: time.Now().Add(-time.Hour * 24).UTC().Format(time.RFC3339),
+		EndTime:   time.Now().Add(end).UTC().Format(time.RFC3339),
+		// the clone of the chromosome points to its parent genes
+		Targeting: []*genetic.Chromosome{
+			c, c.Clone(),
+		},
+		Media: []entities.Media{
+			{Title: "test"},
+		},
+	}
+}
+
+// testPopulation returns a hand-built chromosome and the chromosomes that the genetic
+// algorithm creates from it, a clone and the offspring of a crossover, whose genes
+// point to their parents
+func testPopulation(t *testing.T) []*genetic.Chromosome {
+	t.Helper()
+
+	root := &genetic.Gene{Name: "root"}
+	behaviors := &genetic.Gene{Name: "Behaviors", Parent: root}
+	behaviors.Children = []*genetic.Gene{{ID: "b1", Type: "behaviors", Value: 1, Parent: behaviors}}
+	interests := &genetic.Gene{Name: "Interests", Parent: root}
+	interests.Children = []*genetic.Gene{{ID: "i1", Type: "interests", Parent: interests}}
+	root.Children = []*genetic.Gene{behaviors, interests}
+	c := &genetic.Chromosome{ID: "c1", Root: root, Fitness: 0.5, Quality: 1}
+
+	g := genetic.New(nil, genetic.SinglePointCrossover, genetic.RandomSource(genetic.NewSeededSource(1)))
+	x, y, err := g.Crossover(c, c.Clone())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return []*genetic.Chromosome{c, c.Clone(), x, y}
+}
+
+// testStorage is the conformance suite of the storage implementations,
+// newStorage returns an empty storage for every test
+func testStorage(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("Store Campaign", func(t *testing.T) {
+		cases := []struct {
+			Name, UserID, Platform, AdAccount, Segment string
+			Campaign                                   *entities.Campaign
+			Error                                      error
+		}{
+			{
+				Name:      "Correct",
+				UserID:    "1234",
+				Platform:  "facebook",
+				AdAccount: "ac_1234",
+				Segment:   "Unicorn",
+				Campaign:  testCampaign("1234", time.Hour),
+			},
+			{
+				Name:      "Missing User ID",
+				Platform:  "facebook",
+				AdAccount: "ac_1234",
+				Segment:   "Unicorn",
+				Campaign:  testCampaign("1234", time.Hour),
+				Error:     ErrorMissingUserID,
+			},
+			{
+				Name:      "Missing Platform",
+				UserID:    "1234",
+				AdAccount: "ac_1234",
+				Segment:   "Unicorn",
+				Campaign:  testCampaign("1234", time.Hour),
+				Error:     ErrorMissingPlatform,
+			},
+			{
+				Name:     "Missing Ad Account",
+				UserID:   "1234",
+				Platform: "facebook",
+				Segment:  "Unicorn",
+				Campaign: testCampaign("1234", time.Hour),
+				Error:    ErrorMissingAdAccount,
+			},
+			{
+				Name:      "Missing Segment",
+				UserID:    "1234",
+				Platform:  "facebook",
+				AdAccount: "ac_1234",
+				Campaign:  testCampaign("1234", time.Hour),
+				Error:     ErrorMissingSegment,
+			},
+			{
+				Name:      "Nil Pointer Campaign reference",
+				UserID:    "1234",
+				Platform:  "facebook",
+				AdAccount: "ac_1234",
+				Segment:   "Unicorn",
+				Error:     ErrorInvalidCampaign,
+			},
+			{
+				Name:      "Missing Targeting",
+				UserID:    "1234",
+				Platform:  "facebook",
+				AdAccount: "ac_1234",
+				Segment:   "Unicorn",
+				Campaign:  &entities.Campaign{ID: "1234", Budget: "1bn", StartTime: "now", EndTime: "later", Media: []entities.Media{{}}},
+				Error:     ErrorInvalidCampaign,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := storage.StoreCampaign(tc.UserID, tc.Platform, tc.AdAccount, tc.Segment, tc.Campaign)
+				assert.Equal(tc.Error, err)
+				if tc.Error != nil {
+					return
+				}
+				c, err := storage.GetCampaign(tc.Campaign.ID)
+				assert.Nil(err)
+				assert.Equal(tc.Campaign, c)
+			})
+		}
+	})
+
+	t.Run("Get Campaign", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		stored := testCampaign("1234", time.Hour)
+		stored.Status = "ACTIVE"
+		if !assert.Nil(storage.StoreCampaign("testUser", "testPlatform", "testAdAccount", "testSegment", stored)) {
+			return
+		}
+
+		c, err := storage.GetCampaign("1234")
+		assert.Nil(err)
+		assert.Equal(stored, c)
+		// the stored campaign doesn't share the values of the caller
+		c.Targeting[0].ID = "changed"
+		c, _ = storage.GetCampaign("1234")
+		assert.Equal("test", c.Targeting[0].ID)
+
+		// the status is kept when the campaign is stored without it
+		stored.Status = ""
+		stored.Budget = "2bn"
+		assert.Nil(storage.StoreCampaign("testUser", "testPlatform", "testAdAccount", "testSegment", stored))
+		c, err = storage.GetCampaign("1234")
+		assert.Nil(err)
+		assert.Equal("ACTIVE", c.Status)
+		assert.Equal("2bn", c.Budget)
+
+		c, err = storage.GetCampaign("12345")
+		assert.Nil(c)
+		assert.Equal(ErrorUnableToFindCampaign, err)
+		c, err = storage.GetCampaign("")
+		assert.Nil(c)
+		assert.Equal(ErrorMissingCampaignID, err)
+	})
+
+	t.Run("End Time", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		c := testCampaign("1234", time.Hour)
+		c.EndTime = "2020-08-01T03:30:00-0700"
+		if !assert.Nil(storage.StoreCampaign("1234", "facebook", "ac_1234", "Unicorn", c)) {
+			return
+		}
+
+		// the end times are stored in UTC RFC3339
+		stored, err := storage.GetCampaign("1234")
+		assert.Nil(err)
+		assert.Equal("2020-08-01T10:30:00Z", stored.EndTime)
+		stored.Status = "ACTIVE"
+		stored.EndTime = "2020-08-02 12:30:00 +0200 CEST"
+		assert.Nil(storage.UpdateCampaign(stored))
+		stored, err = storage.GetCampaign("1234")
+		assert.Nil(err)
+		assert.Equal("2020-08-02T10:30:00Z", stored.EndTime)
+		active, err := storage.GetActiveCampaigns(ActiveFilter{Platform: "facebook", AsOf: time.Date(2020, 8, 2, 10, 29, 0, 0, time.UTC)})
+		assert.Nil(err)
+		assert.Equal(map[string][]string{"1234": {"1234"}}, active)
+
+		stored.EndTime = "next week"
+		assert.Equal(ErrorInvalidEndTime, storage.UpdateCampaign(stored))
+		assert.Equal(ErrorInvalidEndTime, storage.StoreCampaign("1234", "facebook", "ac_1234", "Unicorn", stored))
+	})
+
+	t.Run("Update Campaign", func(t *testing.T) {
+		stored := testCampaign("1234", time.Hour)
+		stored.Status = "ACTIVE"
+		cases := []struct {
+			Name     string
+			Campaign *entities.Campaign
+			Error    error
+		}{
+			{
+				Name: "Paused Campaign",
+				Campaign: &entities.Campaign{
+					ID:        "1234",
+					Status:    "PAUSED",
+					Budget:    "2bn",
+					StartTime: stored.StartTime,
+					EndTime:   time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+					Targeting: stored.Targeting,
+					Media:     stored.Media,
+				},
+			},
+			{
+				Name:     "Missing ID",
+				Campaign: &entities.Campaign{},
+				Error:    ErrorMissingCampaignID,
+			},
+			{
+				Name:     "Missing Status",
+				Campaign: &entities.Campaign{ID: "1234", Budget: "1bn", StartTime: "now", EndTime: "later"},
+				Error:    ErrorInvalidCampaign,
+			},
+			{
+				Name:     "Unable To Find Campaign",
+				Campaign: &entities.Campaign{ID: "12345", Status: "PAUSED", Budget: "1bn", StartTime: "now", EndTime: "2020-08-01T10:30:00Z"},
+				Error:    ErrorUnableToFindCampaign,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+		if !assert.Nil(storage.StoreCampaign("testUser", "testPlatform", "testAdAccount", "testSegment", stored)) {
+			return
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := storage.UpdateCampaign(tc.Campaign)
+				assert.Equal(tc.Error, err)
+				if tc.Error != nil {
+					return
+				}
+				c, err := storage.GetCampaign(tc.Campaign.ID)
+				assert.Nil(err)
+				assert.Equal(tc.Campaign, c)
+			})
+		}
+	})
+
+	t.Run("User Campaigns", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		for _, c := range []struct {
+			UserID, Platform, ID string
+		}{
+			{"1234", "facebook", "c2"},
+			{"1234", "facebook", "c1"},
+			{"1234", "google", "c3"},
+			{"5678", "facebook", "c4"},
+		} {
+			if !assert.Nil(storage.StoreCampaign(c.UserID, c.Platform, "testAdAccount", "testSegment", testCampaign(c.ID, time.Hour))) {
+				return
+			}
+		}
+
+		// the campaigns of a user aren't sorted
+		c, err := storage.GetUserCampaigns("1234")
+		assert.Nil(err)
+		assert.Len(c, 2)
+		assert.ElementsMatch([]string{"c1", "c2"}, c["facebook"])
+		assert.Equal([]string{"c3"}, c["google"])
+		c, err = storage.GetUserCampaigns("123")
+		assert.Nil(err)
+		assert.Equal(map[string][]string{}, c)
+		c, err = storage.GetUserCampaigns("")
+		assert.Nil(c)
+		assert.Equal(ErrorMissingUserID, err)
+	})
+
+	t.Run("Active Campaigns", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		for _, c := range []struct {
+			UserID, Platform, ID string
+			End                  time.Duration
+		}{
+			{"1234", "facebook", "active1", time.Hour},
+			{"1234", "facebook", "active2", time.Hour * 365},
+			{"1234", "facebook", "ended", -time.Hour * 48},
+			{"5678", "facebook", "active3", time.Hour},
+			{"5678", "google", "active4", time.Hour},
+		} {
+			if !assert.Nil(storage.StoreCampaign(c.UserID, c.Platform, "testAdAccount", "testSegment", testCampaign(c.ID, c.End))) {
+				return
+			}
+		}
+		// the updated end time is used to find the active campaigns
+		paused := testCampaign("active2", -time.Hour)
+		paused.Status = "PAUSED"
+		if !assert.Nil(storage.UpdateCampaign(paused)) {
+			return
+		}
+
+		c, err := storage.GetActiveCampaigns(ActiveFilter{Platform: "facebook"})
+		assert.Nil(err)
+		assert.Equal(map[string][]string{"1234": {"active1"}, "5678": {"active3"}}, c)
+		c, err = storage.GetActiveCampaigns(ActiveFilter{Platform: "test"})
+		assert.Nil(err)
+		assert.Equal(map[string][]string{}, c)
+		c, err = storage.GetActiveCampaigns(ActiveFilter{})
+		assert.Nil(c)
+		assert.Equal(ErrorMissingPlatform, err)
+
+		// the campaigns that hadn't ended before
+		c, err = storage.GetActiveCampaigns(ActiveFilter{Platform: "facebook", AsOf: time.Now().Add(-time.Hour * 72)})
+		assert.Nil(err)
+		assert.Len(c, 2)
+		assert.ElementsMatch([]string{"active1", "active2", "ended"}, c["1234"])
+		assert.Equal([]string{"active3"}, c["5678"])
+		c, err = storage.GetActiveCampaigns(ActiveFilter{Platform: "facebook", Status: "PAUSED", AsOf: time.Now().Add(-time.Hour * 2)})
+		assert.Nil(err)
+		assert.Equal(map[string][]string{"1234": {"active2"}}, c)
+		c, err = storage.GetActiveCampaigns(ActiveFilter{Platform: "facebook", AsOf: time.Now().Add(time.Hour * 2)})
+		assert.Nil(err)
+		assert.Equal(map[string][]string{}, c)
+	})
+
+	t.Run("Segment Campaigns", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		for _, c := range []struct {
+			UserID, Segment, ID string
+			End                 time.Duration
+		}{
+			{"1234", "Trinacia", "1234", time.Hour * 365},
+			{"1234", "Trinacia", "12345", time.Hour * 367},
+			{"1234", "Trinacia", "123456", -time.Hour},
+			{"1234", "Unicorn", "11234", time.Hour},
+			{"5678", "Trinacia", "5678", time.Hour},
+		} {
+			if !assert.Nil(storage.StoreCampaign(c.UserID, "growth", "trinacia", c.Segment, testCampaign(c.ID, c.End))) {
+				return
+			}
+		}
+
+		c, err := storage.GetSegmentCampaigns("1234", "Trinacia")
+		assert.Nil(err)
+		assert.Equal([]string{"12345", "1234", "123456"}, c)
+		c, err = storage.GetSegmentCampaigns("1234", "Test")
+		assert.Nil(err)
+		assert.Equal([]string{}, c)
+		_, err = storage.GetSegmentCampaigns("", "Trinacia")
+		assert.Equal(ErrorMissingUserID, err)
+		_, err = storage.GetSegmentCampaigns("1234", "")
+		assert.Equal(ErrorMissingSegment, err)
+	})
+
+	t.Run("Paginated Campaigns", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		for _, c := range []struct {
+			UserID, Platform, Segment, ID string
+			End                           time.Duration
+		}{
+			{"1234", "facebook", "Unicorn", "c2", time.Hour * 3},
+			{"1234", "facebook", "Unicorn", "c0", time.Hour},
+			{"1234", "facebook", "Unicorn", "c4", time.Hour * 5},
+			{"1234", "facebook", "Unicorn", "c1", time.Hour * 2},
+			{"1234", "facebook", "Unicorn:Test", "c3", time.Hour * 4},
+			{"1234", "facebook", "Unicorn", "ended", -time.Hour},
+			{"5678", "google", "Unicorn", "other", time.Hour},
+		} {
+			if !assert.Nil(storage.StoreCampaign(c.UserID, c.Platform, "testAdAccount", c.Segment, testCampaign(c.ID, c.End))) {
+				return
+			}
+		}
+		// listAll returns the ids of the campaigns of every page of two campaigns
+		listAll := func(list func(limit int, next string) (*Page, error)) []string {
+			ids := []string{}
+			next := ""
+			for i := 0; i < 10; i++ {
+				p, err := list(2, next)
+				if !assert.Nil(err) {
+					return ids
+				}
+				assert.LessOrEqual(len(p.Campaigns), 2)
+				for _, c := range p.Campaigns {
+					ids = append(ids, c.ID)
+				}
+				if p.Next == "" {
+					return ids
+				}
+				next = p.Next
+			}
+			assert.Fail("the pages didn't end")
+			return ids
+		}
+
+		assert.ElementsMatch([]string{"c0", "c1", "c2", "c3", "c4", "ended"}, listAll(func(limit int, next string) (*Page, error) {
+			return storage.ListUserCampaigns("1234", limit, next)
+		}))
+		assert.Equal([]string{"c0", "c1", "c2", "c3", "c4"}, listAll(func(limit int, next string) (*Page, error) {
+			return storage.ListActiveCampaigns(ActiveFilter{Platform: "facebook"}, limit, next)
+		}))
+		segment := listAll(func(limit int, next string) (*Page, error) {
+			return storage.ListSegmentCampaigns("1234", "Unicorn", limit, next)
+		})
+		assert.Equal([]string{"c4", "c2", "c1", "c0", "ended"}, segment)
+		c, err := storage.GetSegmentCampaigns("1234", "Unicorn")
+		assert.Nil(err)
+		assert.Equal(c, segment)
+
+		p, err := storage.ListUserCampaigns("5678", 10, "")
+		assert.Nil(err)
+		assert.Equal(&Page{Campaigns: []CampaignRef{{ID: "other", UserID: "5678", Platform: "google"}}}, p)
+		p, err = storage.ListSegmentCampaigns("1234", "Test", 10, "")
+		assert.Nil(err)
+		assert.Empty(p.Campaigns)
+		assert.Empty(p.Next)
+
+		_, err = storage.ListUserCampaigns("1234", 0, "")
+		assert.Equal(ErrorInvalidLimit, err)
+		_, err = storage.ListActiveCampaigns(ActiveFilter{Platform: "facebook"}, 2, "invalid token")
+		assert.Equal(ErrorInvalidNextToken, err)
+		_, err = storage.ListUserCampaigns("", 2, "")
+		assert.Equal(ErrorMissingUserID, err)
+		_, err = storage.ListActiveCampaigns(ActiveFilter{}, 2, "")
+		assert.Equal(ErrorMissingPlatform, err)
+		_, err = storage.ListSegmentCampaigns("1234", "", 2, "")
+		assert.Equal(ErrorMissingSegment, err)
+	})
+
+	t.Run("Segments", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		population := testPopulation(t)
+		segments, err := storage.GetSegments("1234")
+		assert.Nil(err)
+		assert.Empty(segments)
+
+		assert.Nil(storage.SetSegment("1234", "Unicorn", population))
+		assert.Nil(storage.SetSegment("1234", "Trinacia", population[:1]))
+		segments, err = storage.GetSegments("1234")
+		assert.Nil(err)
+		assert.Equal([]string{"Unicorn", "Trinacia"}, segments)
+		p, err := storage.GetSegment("1234", "Unicorn")
+		assert.Nil(err)
+		assert.Equal(population, p)
+		p, err = storage.GetSegment("1234", "Test")
+		assert.Nil(err)
+		assert.Empty(p)
+
+		assert.Equal(ErrorMissingUserID, storage.SetSegment("", "Unicorn", population))
+		assert.Equal(ErrorMissingSegment, storage.SetSegment("1234", "", population))
+		_, err = storage.GetSegment("1234", "")
+		assert.Equal(ErrorMissingSegment, err)
+		_, err = storage.GetSegments("")
+		assert.Equal(ErrorMissingUserID, err)
+	})
+
+	t.Run("Evolution", func(t *testing.T) {
+		cases := []struct {
+			Name      string
+			UserID    string
+			Segment   string
+			Evolution *entities.Evolution
+			Error     error
+		}{
+			{
+				Name:    "Segment Evolution",
+				UserID:  "1234",
+				Segment: "Evolution",
+				Evolution: &entities.Evolution{
+					PopulationSize:  10,
+					EliteCount:      3,
+					MinMutationRate: 0.01,
+					MaxMutationRate: 0.1,
+					CrossoverRate:   0.5,
+				},
+			},
+			{
+				Name:    "Missing Evolution",
+				UserID:  "1234",
+				Segment: "Evolution",
+				Error:   ErrorMissingEvolution,
+			},
+			{
+				Name:    "Population Smaller Than Elites",
+				UserID:  "1234",
+				Segment: "Evolution",
+				Evolution: &entities.Evolution{
+					PopulationSize: 2,
+					EliteCount:     3,
+				},
+				Error: ErrorInvalidEvolution,
+			},
+			{
+				Name:      "Missing Elites",
+				UserID:    "1234",
+				Segment:   "Evolution",
+				Evolution: &entities.Evolution{PopulationSize: 2},
+				Error:     ErrorInvalidEvolution,
+			},
+			{
+				Name:      "Missing User ID",
+				Segment:   "Evolution",
+				Evolution: &entities.Evolution{},
+				Error:     ErrorMissingUserID,
+			},
+			{
+				Name:      "Missing Segment",
+				UserID:    "1234",
+				Evolution: &entities.Evolution{},
+				Error:     ErrorMissingSegment,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+
+		e, err := storage.GetEvolution("1234", "Default")
+		assert.Nil(err)
+		assert.Nil(e)
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := storage.SetEvolution(tc.UserID, tc.Segment, tc.Evolution)
+				assert.Equal(tc.Error, err)
+				if tc.Error != nil {
+					return
+				}
+				e, err := storage.GetEvolution(tc.UserID, tc.Segment)
+				assert.Nil(err)
+				assert.Equal(tc.Evolution, e)
+			})
+		}
+	})
+
+	t.Run("Canceled Context", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		if !assert.Nil(storage.StoreCampaign("1234", "facebook", "ac_1234", "Unicorn", testCampaign("1234", time.Hour))) {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := storage.WithContext(ctx).GetCampaign("1234")
+		assert.Error(err)
+		_, err = storage.GetCampaign("1234")
+		assert.Nil(err)
+	})
+
+	t.Run("Concurrent Operations", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		errs := make(chan error)
+		for i := 0; i < 10; i++ {
+			go func(i int) {
+				c := testCampaign(fmt.Sprint(i), time.Hour)
+				if err := storage.StoreCampaign("1234", "facebook", "ac_1234", "Unicorn", c); err != nil {
+					errs <- err
+					return
+				}
+				c.Status = "PAUSED"
+				if err := storage.UpdateCampaign(c); err != nil {
+					errs <- err
+					return
+				}
+				_, err := storage.GetSegmentCampaigns("1234", "Unicorn")
+				errs <- err
+			}(i)
+		}
+		for i := 0; i < 10; i++ {
+			assert.Nil(<-errs)
+		}
+
+		c, err := storage.GetUserCampaigns("1234")
+		assert.Nil(err)
+		assert.Len(c["facebook"], 10)
+	})
+}
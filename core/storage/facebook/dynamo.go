@@ -1,6 +1,7 @@
 package facebook
 
 import (
+	"context"
 	"errors"
 
 	"bitbucket.org/backend/core/entities"
@@ -12,14 +13,58 @@ import (
 
 type dynamo struct {
 	svc *dynamodb.DynamoDB
+	// ctx is the context of the requests to dynamo
+	ctx context.Context
+	// table is the name of the table of the items
+	table string
+	// endpoint overrides the endpoint of the session when it isn't empty
+	endpoint string
 }
 
+// Option configures a dynamo storage
+type Option func(*dynamo)
+
 // NewFacebook isntanciates a session dynamo session to query information
 // about users' Facebook accounts
-func NewFacebook(sess *session.Session) Storage {
-	return &dynamo{
-		svc: dynamodb.New(sess),
+func NewFacebook(sess *session.Session, config ...Option) Storage {
+	d := &dynamo{
+		ctx:   context.Background(),
+		table: TableName,
+	}
+
+	for _, fn := range config {
+		fn(d)
+	}
+
+	cfg := aws.NewConfig()
+	if d.endpoint != "" {
+		cfg = cfg.WithEndpoint(d.endpoint)
 	}
+	d.svc = dynamodb.New(sess, cfg)
+
+	return d
+}
+
+// Endpoint sets the endpoint of the requests, like the url of a local stand-in of dynamo
+func Endpoint(url string) Option {
+	return func(d *dynamo) {
+		d.endpoint = url
+	}
+}
+
+// Table sets the name of the table of the items
+func Table(name string) Option {
+	return func(d *dynamo) {
+		d.table = name
+	}
+}
+
+// WithContext returns a copy of the storage whose requests are cancelled with the context
+func (d *dynamo) WithContext(ctx context.Context) Storage {
+	dc := *d
+	dc.ctx = ctx
+
+	return &dc
 }
 
 const (
@@ -57,7 +102,7 @@ func (d *dynamo) StoreFacebook(userID string, f *entities.Facebook) error {
 	}
 
 	in := &dynamodb.UpdateItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String(userID),
@@ -80,7 +125,7 @@ func (d *dynamo) StoreFacebook(userID string, f *entities.Facebook) error {
 		},
 		UpdateExpression: aws.String("set #accessToken=:accessToken, #pages=:pages, #adAccounts=:adAccounts"),
 	}
-	_, err = d.svc.UpdateItem(in)
+	_, err = d.svc.UpdateItemWithContext(d.ctx, in)
 	if err != nil {
 		return err
 	}
@@ -95,7 +140,7 @@ func (d *dynamo) GetFacebook(userID string) (*entities.Facebook, error) {
 	f := &entities.Facebook{}
 
 	in := &dynamodb.GetItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String(userID),
@@ -105,7 +150,7 @@ func (d *dynamo) GetFacebook(userID string) (*entities.Facebook, error) {
 			},
 		},
 	}
-	out, err := d.svc.GetItem(in)
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
 	if err != nil {
 		return nil, err
 	}
@@ -4,53 +4,30 @@ import (
 	"testing"
 
 	"bitbucket.org/backend/core/entities"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"bitbucket.org/backend/core/storage/schema"
+	"bitbucket.org/backend/core/storage/schema/schematest"
 	"github.com/stretchr/testify/assert"
 )
 
-func testCreateFacebook(t *testing.T, userID string, f *entities.Facebook) {
-	t.Helper()
+// testDynamo returns a storage of a new table of the local stand-in of dynamo
+// set in schematest.EndpointEnv, the table is deleted at the end of the test
+func testDynamo(t *testing.T) Storage {
+	sess, table := schematest.LocalTable(t, schema.Trinacia)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	storage := NewFacebook(sess)
+	return NewFacebook(sess, Table(table))
+}
 
-	err = storage.StoreFacebook(userID, f)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+func TestDynamo(t *testing.T) {
+	testStorage(t, testDynamo)
 }
 
-func testDeleteItem(t *testing.T, partition, key string) {
+func testCreateFacebook(t *testing.T, storage Storage, userID string, f *entities.Facebook) {
 	t.Helper()
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
+	err := storage.StoreFacebook(userID, f)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	svc := dynamodb.New(sess)
-	in := &dynamodb.DeleteItemInput{
-		TableName: aws.String(TableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"partition": {
-				S: aws.String(partition),
-			},
-			"key": {
-				S: aws.String(key),
-			},
-		},
-	}
-	if _, err := svc.DeleteItem(in); err != nil {
-		t.Fatalf("err: %s", err)
-	}
 }
 
 func TestStoreFacebook(t *testing.T) {
@@ -154,20 +131,9 @@ func TestStoreFacebook(t *testing.T) {
 		},
 	}
 	assert := assert.New(t)
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-
-	storage := NewFacebook(sess)
+	storage := testDynamo(t)
 
 	for _, tc := range cases {
-		if tc.Error == nil {
-			defer testDeleteItem(t, tc.UserID, "facebook")
-		}
 		t.Run(tc.Name, func(t *testing.T) {
 			err := storage.StoreFacebook(tc.UserID, tc.Facebook)
 			assert.Equal(tc.Error, err)
@@ -227,25 +193,17 @@ func TestGetFacebook(t *testing.T) {
 		},
 	}
 
+	storage := testDynamo(t)
+
 	// create test facebook data
 	for _, tc := range cases {
 		if tc.Create {
-			testCreateFacebook(t, tc.UserID, tc.Expected)
-			defer testDeleteItem(t, tc.UserID, "facebook")
+			testCreateFacebook(t, storage, tc.UserID, tc.Expected)
 		}
 	}
 
 	assert := assert.New(t)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-
-	storage := NewFacebook(sess)
-
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			f, err := storage.GetFacebook(tc.UserID)
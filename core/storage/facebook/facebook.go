@@ -1,9 +1,15 @@
 package facebook
 
-import "bitbucket.org/backend/core/entities"
+import (
+	"context"
+
+	"bitbucket.org/backend/core/entities"
+)
 
 // Storage interface to get information from database
 type Storage interface {
 	StoreFacebook(userID string, f *entities.Facebook) error
 	GetFacebook(userID string) (*entities.Facebook, error)
+	// WithContext returns the storage with its requests bound to the context
+	WithContext(ctx context.Context) Storage
 }
@@ -0,0 +1,96 @@
+package facebook
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"bitbucket.org/backend/core/entities"
+)
+
+type memory struct {
+	*accounts
+	// ctx is the context of the operations, the storage fails once it's done
+	ctx context.Context
+}
+
+// accounts are the items of a memory storage, they are shared by its copies
+type accounts struct {
+	mu sync.RWMutex
+	// items are the json encoded accounts, so the stored
+	// accounts don't share the slices of the callers
+	items map[string][]byte
+}
+
+// NewMemory returns a storage that keeps the facebook accounts in memory, it's
+// safe for concurrent use and behaves as the dynamo storage, so it can replace
+// it in tests and local development
+func NewMemory() Storage {
+	return &memory{
+		accounts: &accounts{items: map[string][]byte{}},
+		ctx:      context.Background(),
+	}
+}
+
+// WithContext returns a copy of the storage that fails once the context is
+// done, the copy shares the stored accounts
+func (m *memory) WithContext(ctx context.Context) Storage {
+	mc := *m
+	mc.ctx = ctx
+
+	return &mc
+}
+
+func (m *memory) StoreFacebook(userID string, f *entities.Facebook) error {
+	if userID == "" {
+		return ErrorMissingUserID
+	}
+	if f == nil {
+		return ErrorMissingFacebook
+	}
+	if f.AccessToken == "" {
+		return ErrorMissingFacebookAccessToken
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	// the dynamo item only has the access token, pages and ad accounts
+	b, err := json.Marshal(&entities.Facebook{
+		Pages:       f.Pages,
+		AdAccounts:  f.AdAccounts,
+		AccessToken: f.AccessToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[userID] = b
+
+	return nil
+}
+
+func (m *memory) GetFacebook(userID string) (*entities.Facebook, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f := &entities.Facebook{}
+	b, ok := m.items[userID]
+	if !ok {
+		return f, nil
+	}
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
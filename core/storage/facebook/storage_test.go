@@ -0,0 +1,167 @@
+package facebook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"bitbucket.org/backend/core/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFacebook returns the facebook account of a user with the access token
+func testFacebook(accessToken string) *entities.Facebook {
+	return &entities.Facebook{
+		Pages: []entities.Page{
+			{
+				Category: "Unicorn",
+				Name:     "Trinacia",
+				ID:       "1234",
+				Instagram: []entities.Instagram{
+					{
+						ID:   "1234",
+						Name: "Trinacia",
+					},
+				},
+				AccessToken: "1234",
+			},
+		},
+		AdAccounts: []entities.AdAccount{
+			{
+				AccountID: "act_1234",
+				ID:        "1234",
+				Name:      "Trinacia",
+			},
+		},
+		AccessToken: accessToken,
+	}
+}
+
+// testStorage is the conformance suite of the storage implementations,
+// newStorage returns an empty storage for every test
+func testStorage(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("Store Facebook", func(t *testing.T) {
+		cases := []struct {
+			Name     string
+			UserID   string
+			Facebook *entities.Facebook
+			Error    error
+		}{
+			{
+				Name:     "Correct",
+				UserID:   "1234",
+				Facebook: testFacebook("1234"),
+			},
+			{
+				Name:     "Updated Access Token",
+				UserID:   "1234",
+				Facebook: testFacebook("5678"),
+			},
+			{
+				Name:     "Missing User ID",
+				Facebook: testFacebook("1234"),
+				Error:    ErrorMissingUserID,
+			},
+			{
+				Name:     "Missing Access Token",
+				UserID:   "1234",
+				Facebook: testFacebook(""),
+				Error:    ErrorMissingFacebookAccessToken,
+			},
+			{
+				Name:   "Nil Pointer Reference",
+				UserID: "1234",
+				Error:  ErrorMissingFacebook,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := storage.StoreFacebook(tc.UserID, tc.Facebook)
+				assert.Equal(tc.Error, err)
+				if tc.Error != nil {
+					return
+				}
+				f, err := storage.GetFacebook(tc.UserID)
+				assert.Nil(err)
+				assert.Equal(tc.Facebook, f)
+			})
+		}
+	})
+
+	t.Run("Get Facebook", func(t *testing.T) {
+		cases := []struct {
+			Name     string
+			UserID   string
+			Expected *entities.Facebook
+			Error    error
+		}{
+			{
+				Name:     "Correct",
+				UserID:   "1234",
+				Expected: testFacebook("1234"),
+			},
+			{
+				Name:  "Missing User ID",
+				Error: ErrorMissingUserID,
+			},
+			{
+				Name:     "No Data Found",
+				UserID:   "123412341234123",
+				Expected: &entities.Facebook{},
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+		stored := testFacebook("1234")
+		// the id isn't stored
+		stored.ID = "1234"
+		if !assert.Nil(storage.StoreFacebook("1234", stored)) {
+			return
+		}
+		// the stored account doesn't share the values of the caller
+		stored.Pages[0].Name = "changed"
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				f, err := storage.GetFacebook(tc.UserID)
+				assert.Equal(tc.Expected, f)
+				assert.Equal(tc.Error, err)
+			})
+		}
+	})
+
+	t.Run("Canceled Context", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(storage.WithContext(ctx).StoreFacebook("1234", testFacebook("1234")))
+		f, err := storage.GetFacebook("1234")
+		assert.Nil(err)
+		assert.Equal(&entities.Facebook{}, f)
+	})
+
+	t.Run("Concurrent Operations", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		errs := make(chan error)
+		for i := 0; i < 10; i++ {
+			go func(i int) {
+				id := fmt.Sprint(i)
+				if err := storage.StoreFacebook(id, testFacebook(id)); err != nil {
+					errs <- err
+					return
+				}
+				_, err := storage.GetFacebook(id)
+				errs <- err
+			}(i)
+		}
+		for i := 0; i < 10; i++ {
+			assert.Nil(<-errs)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package schema
+
+// the migration tests are in the schema_test package so they can
+// use schematest, which imports schema, these are their internals
+
+// PollInterval is the time between the checks of the status of the indexes
+var PollInterval = &pollInterval
+
+// ItemKey returns the key of an item of the table
+var ItemKey = itemKey
@@ -0,0 +1,84 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/storage/schema"
+	"bitbucket.org/backend/core/storage/schema/schematest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMigrate runs against the local stand-in of dynamo set in schematest.EndpointEnv
+func TestMigrate(t *testing.T) {
+	assert := assert.New(t)
+	// the table starts with the previous indexes
+	previous := schema.Trinacia
+	previous.Indexes = schema.Trinacia.Indexes[:3]
+	sess, name := schematest.LocalTable(t, previous)
+	svc := dynamodb.New(sess)
+	ctx := context.Background()
+	table := schema.Trinacia
+	table.Name = name
+
+	*schema.PollInterval = 100 * time.Millisecond
+	if !assert.Nil(table.Apply(ctx, svc)) {
+		return
+	}
+	out, err := svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table.Name)})
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Len(out.Table.GlobalSecondaryIndexes, len(schema.Trinacia.Indexes))
+
+	for _, item := range []map[string]*dynamodb.AttributeValue{
+		{
+			"partition":  {S: aws.String("campaigns")},
+			"key":        {S: aws.String("old")},
+			"end_time":   {S: aws.String("2020-08-01T03:30:00-0700")},
+			"secondSort": {S: aws.String("2020-08-01T03:30:00-0700")},
+			"thirdSort":  {S: aws.String("1234:Unicorn")},
+		},
+		{"partition": {S: aws.String("campaigns")}, "key": {S: aws.String("paused")}, "status": {S: aws.String("PAUSED")}},
+	} {
+		_, err := svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: aws.String(table.Name), Item: item})
+		if !assert.Nil(err) {
+			return
+		}
+	}
+
+	version, err := schema.Migrate(ctx, svc, table.Name, schema.Migrations)
+	assert.Nil(err)
+	assert.Equal(len(schema.Migrations), version)
+	version, err = schema.Version(ctx, svc, table.Name)
+	assert.Nil(err)
+	assert.Equal(len(schema.Migrations), version)
+	for key, status := range map[string]string{"old": "ACTIVE", "paused": "PAUSED"} {
+		out, err := svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(table.Name),
+			Key:       schema.ItemKey("campaigns", key),
+		})
+		assert.Nil(err)
+		assert.Equal(status, aws.StringValue(out.Item["status"].S))
+	}
+	item, err := svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table.Name),
+		Key:       schema.ItemKey("campaigns", "old"),
+	})
+	assert.Nil(err)
+	assert.Equal("2020-08-01T10:30:00Z", aws.StringValue(item.Item["end_time"].S))
+	assert.Equal("2020-08-01T10:30:00Z", aws.StringValue(item.Item["secondSort"].S))
+	assert.Equal("1234:Unicorn:2020-08-01T10:30:00Z", aws.StringValue(item.Item["fifthSort"].S))
+
+	// the applied migrations don't run again
+	applied := []schema.Migration{{
+		Version: 1,
+		Migrate: func(context.Context, *dynamodb.DynamoDB, string) error { return schema.ErrorConcurrentMigration },
+	}}
+	version, err = schema.Migrate(ctx, svc, table.Name, applied)
+	assert.Nil(err)
+	assert.Equal(len(schema.Migrations), version)
+}
@@ -0,0 +1,363 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// keys and attribute of the item that records the version of the table
+const (
+	versionPartition = "schema"
+	versionKey       = "version"
+	versionAttribute = "version"
+)
+
+var (
+	// ErrorMigrationOrder the versions of the migrations aren't increasing
+	ErrorMigrationOrder = errors.New("The migrations must have increasing versions starting at one")
+	// ErrorConcurrentMigration the version of the table changed during the migration
+	ErrorConcurrentMigration = errors.New("The table version changed during the migration")
+)
+
+// Migration changes the items of a table from the previous version of the schema,
+// a migration must be safe to run again in case the version isn't recorded
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(ctx context.Context, svc *dynamodb.DynamoDB, table string) error
+}
+
+// Migrations are the data migrations of the trinacia table in order
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Backfill the status of the campaigns stored before it was recorded",
+		Migrate:     backfillStatus,
+	},
+	{
+		Version:     2,
+		Description: "Backfill the sort key of the campaigns of a segment by end time",
+		Migrate:     backfillSegmentEnd,
+	},
+	{
+		Version:     3,
+		Description: "Normalize the end time of the campaigns to UTC RFC3339",
+		Migrate:     normalizeEndTime,
+	},
+}
+
+// Version returns the last migration applied to the table, zero when it doesn't have any
+func Version(ctx context.Context, svc *dynamodb.DynamoDB, table string) (int, error) {
+	in := &dynamodb.GetItemInput{
+		TableName:      aws.String(table),
+		Key:            itemKey(versionPartition, versionKey),
+		ConsistentRead: aws.Bool(true),
+	}
+	out, err := svc.GetItemWithContext(ctx, in)
+	if err != nil {
+		return 0, err
+	}
+	av, ok := out.Item[versionAttribute]
+	if !ok || av.N == nil {
+		return 0, nil
+	}
+
+	return strconv.Atoi(*av.N)
+}
+
+// Migrate applies the migrations newer than the version of the table in order and
+// returns the new version, the version is recorded after every migration so a
+// failed run continues from the last applied one
+func Migrate(ctx context.Context, svc *dynamodb.DynamoDB, table string, migrations []Migration) (int, error) {
+	if err := validate(migrations); err != nil {
+		return 0, err
+	}
+	version, err := Version(ctx, svc, table)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range pending(migrations, version) {
+		if err := m.Migrate(ctx, svc, table); err != nil {
+			return version, err
+		}
+		if err := recordVersion(ctx, svc, table, version, m.Version); err != nil {
+			return version, err
+		}
+		version = m.Version
+	}
+
+	return version, nil
+}
+
+// validate checks that the migrations have increasing versions starting at one
+func validate(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Migrate == nil || m.Version <= 0 || (i > 0 && m.Version <= migrations[i-1].Version) {
+			return ErrorMigrationOrder
+		}
+	}
+
+	return nil
+}
+
+// pending returns the migrations newer than the version
+func pending(migrations []Migration, version int) []Migration {
+	p := []Migration{}
+	for _, m := range migrations {
+		if m.Version > version {
+			p = append(p, m)
+		}
+	}
+
+	return p
+}
+
+// recordVersion updates the version of the table, it fails when
+// the version isn't the previous one because of another migration
+func recordVersion(ctx context.Context, svc *dynamodb.DynamoDB, table string, previous, version int) error {
+	in := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key:       itemKey(versionPartition, versionKey),
+		ExpressionAttributeNames: map[string]*string{
+			"#version":   aws.String(versionAttribute),
+			"#updatedAt": aws.String("updated_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":version": {
+				N: aws.String(strconv.Itoa(version)),
+			},
+			":previous": {
+				N: aws.String(strconv.Itoa(previous)),
+			},
+			":updatedAt": {
+				S: aws.String(time.Now().UTC().Format(time.RFC3339)),
+			},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#version) OR #version = :previous"),
+		UpdateExpression:    aws.String("set #version=:version, #updatedAt=:updatedAt"),
+	}
+	_, err := svc.UpdateItemWithContext(ctx, in)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrorConcurrentMigration
+	}
+
+	return err
+}
+
+// scanItems calls the function with every item of the scan following its pages
+func scanItems(ctx context.Context, svc *dynamodb.DynamoDB, in *dynamodb.ScanInput, fn func(map[string]*dynamodb.AttributeValue) error) error {
+	var itemErr error
+	err := svc.ScanPagesWithContext(ctx, in, func(out *dynamodb.ScanOutput, last bool) bool {
+		for _, item := range out.Items {
+			if itemErr = fn(item); itemErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if itemErr != nil {
+		return itemErr
+	}
+
+	return err
+}
+
+// backfillStatus sets the active status to the campaigns stored
+// before the status was recorded, which were always active
+func backfillStatus(ctx context.Context, svc *dynamodb.DynamoDB, table string) error {
+	in := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		ExpressionAttributeNames: map[string]*string{
+			"#p":      aws.String("partition"),
+			"#k":      aws.String("key"),
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":partition": {
+				S: aws.String("campaigns"),
+			},
+		},
+		FilterExpression:     aws.String("#p = :partition AND attribute_not_exists(#status)"),
+		ProjectionExpression: aws.String("#p, #k"),
+	}
+
+	return scanItems(ctx, svc, in, func(item map[string]*dynamodb.AttributeValue) error {
+		update := &dynamodb.UpdateItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				"partition": item["partition"],
+				"key":       item["key"],
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#status": aws.String("status"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":status": {
+					S: aws.String("ACTIVE"),
+				},
+			},
+			// the status set after the scan is kept
+			ConditionExpression: aws.String("attribute_not_exists(#status)"),
+			UpdateExpression:    aws.String("set #status=:status"),
+		}
+		_, err := svc.UpdateItemWithContext(ctx, update)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// backfillSegmentEnd sets the sort key of the index of the campaigns of a segment sorted
+// by end time, its value is the segment key and the end time of the campaign
+func backfillSegmentEnd(ctx context.Context, svc *dynamodb.DynamoDB, table string) error {
+	in := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		ExpressionAttributeNames: map[string]*string{
+			"#p":  aws.String("partition"),
+			"#k":  aws.String("key"),
+			"#ss": aws.String("secondSort"),
+			"#3s": aws.String("thirdSort"),
+			"#5s": aws.String("fifthSort"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":partition": {
+				S: aws.String("campaigns"),
+			},
+		},
+		FilterExpression:     aws.String("#p = :partition AND attribute_not_exists(#5s)"),
+		ProjectionExpression: aws.String("#p, #k, #ss, #3s"),
+	}
+
+	return scanItems(ctx, svc, in, func(item map[string]*dynamodb.AttributeValue) error {
+		thirdSort, secondSort := item["thirdSort"], item["secondSort"]
+		if thirdSort == nil || secondSort == nil {
+			return nil
+		}
+		update := &dynamodb.UpdateItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				"partition": item["partition"],
+				"key":       item["key"],
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#5s": aws.String("fifthSort"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":fifthSort": {
+					S: aws.String(aws.StringValue(thirdSort.S) + ":" + aws.StringValue(secondSort.S)),
+				},
+			},
+			// the campaigns stored after the scan already have it
+			ConditionExpression: aws.String("attribute_not_exists(#5s)"),
+			UpdateExpression:    aws.String("set #5s=:fifthSort"),
+		}
+		_, err := svc.UpdateItemWithContext(ctx, update)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// normalizeEndTime sets the end time of the campaigns and the sort keys that
+// include it in the layout of the sort keys, the times that can't be parsed
+// are kept so the rest of the campaigns are migrated
+func normalizeEndTime(ctx context.Context, svc *dynamodb.DynamoDB, table string) error {
+	in := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		ExpressionAttributeNames: map[string]*string{
+			"#p":       aws.String("partition"),
+			"#k":       aws.String("key"),
+			"#endTime": aws.String("end_time"),
+			"#ss":      aws.String("secondSort"),
+			"#3s":      aws.String("thirdSort"),
+			"#5s":      aws.String("fifthSort"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":partition": {
+				S: aws.String("campaigns"),
+			},
+		},
+		FilterExpression:     aws.String("#p = :partition AND attribute_exists(#endTime)"),
+		ProjectionExpression: aws.String("#p, #k, #endTime, #ss, #3s, #5s"),
+	}
+
+	return scanItems(ctx, svc, in, func(item map[string]*dynamodb.AttributeValue) error {
+		endTime := stringAttribute(item, "end_time")
+		normalized, err := NormalizeTime(endTime)
+		if err != nil {
+			return nil
+		}
+		update := &dynamodb.UpdateItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				"partition": item["partition"],
+				"key":       item["key"],
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#endTime": aws.String("end_time"),
+				"#ss":      aws.String("secondSort"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":endTime": {
+					S: aws.String(normalized),
+				},
+				":previous": {
+					S: aws.String(endTime),
+				},
+			},
+			// the campaigns updated after the scan are already normalized
+			ConditionExpression: aws.String("#endTime = :previous"),
+			UpdateExpression:    aws.String("set #endTime=:endTime, #ss=:endTime"),
+		}
+		updated := endTime != normalized || stringAttribute(item, "secondSort") != normalized
+		if thirdSort := stringAttribute(item, "thirdSort"); thirdSort != "" {
+			fifthSort := thirdSort + ":" + normalized
+			updated = updated || stringAttribute(item, "fifthSort") != fifthSort
+			update.ExpressionAttributeNames["#5s"] = aws.String("fifthSort")
+			update.ExpressionAttributeValues[":fifthSort"] = &dynamodb.AttributeValue{S: aws.String(fifthSort)}
+			update.UpdateExpression = aws.String("set #endTime=:endTime, #ss=:endTime, #5s=:fifthSort")
+		}
+		if !updated {
+			return nil
+		}
+
+		_, err = svc.UpdateItemWithContext(ctx, update)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// stringAttribute returns the string value of the attribute of the item, it's empty when it's missing
+func stringAttribute(item map[string]*dynamodb.AttributeValue, name string) string {
+	if av, ok := item[name]; ok && av != nil {
+		return aws.StringValue(av.S)
+	}
+
+	return ""
+}
+
+// itemKey returns the key of the item of the table
+func itemKey(partition, key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"partition": {
+			S: aws.String(partition),
+		},
+		"key": {
+			S: aws.String(key),
+		},
+	}
+}
@@ -0,0 +1,300 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Table describes a dynamo table, its key, the global secondary
+// indexes and the types of items it stores
+type Table struct {
+	Name string
+	// Partition and Key are the hash and range keys of the table
+	Partition string
+	Key       string
+	Indexes   []Index
+	Items     []Item
+}
+
+// Index is a global secondary index of a table, its items are all projected
+type Index struct {
+	Name      string
+	Partition string
+	Sort      string
+}
+
+// Item describes a type of item of a table and the values of its attributes
+type Item struct {
+	Name string
+	// Partition and Key are the values of the keys of the table
+	Partition string
+	Key       string
+	// Attributes are the values of the sort keys of the indexes
+	// that the item uses and the other attributes of the item
+	Attributes map[string]string
+}
+
+// Trinacia is the single table of the storages, the items share the sort keys
+// of the indexes so each type of item gives them its own meaning
+var Trinacia = Table{
+	Name:      "trinacia",
+	Partition: "partition",
+	Key:       "key",
+	Indexes: []Index{
+		{Name: "partition-sort-index", Partition: "partition", Sort: "sort"},
+		{Name: "partition-secondSort-index", Partition: "partition", Sort: "secondSort"},
+		{Name: "partition-thirdSort-index", Partition: "partition", Sort: "thirdSort"},
+		{Name: "partition-fourthSort-index", Partition: "partition", Sort: "fourthSort"},
+		{Name: "partition-fifthSort-index", Partition: "partition", Sort: "fifthSort"},
+	},
+	Items: []Item{
+		{
+			Name:      "user",
+			Partition: "users",
+			Key:       "user id",
+			Attributes: map[string]string{
+				"sort":          "creation time",
+				"id":            "user id",
+				"name":          "name",
+				"email":         "email",
+				"creation_time": "creation time",
+			},
+		},
+		{
+			Name:      "facebook",
+			Partition: "user id",
+			Key:       "facebook",
+			Attributes: map[string]string{
+				"access_token": "user access token",
+				"pages":        "pages of the user",
+				"ad_accounts":  "ad accounts of the user",
+			},
+		},
+		{
+			Name:      "campaign",
+			Partition: "campaigns",
+			Key:       "campaign id",
+			Attributes: map[string]string{
+				"sort":       "user id",
+				"secondSort": "end time",
+				"thirdSort":  "user id:segment",
+				"fourthSort": "platform",
+				"fifthSort":  "user id:segment:end time",
+				"plaform":    "platform",
+				"segment":    "segment",
+				"ad_account": "ad account",
+				"id":         "campaign id",
+				"status":     "status",
+				"start_time": "start time",
+				"end_time":   "end time",
+				"budget":     "daily budget",
+				"targeting":  "targeting chromosomes",
+				"media":      "media of the ads",
+			},
+		},
+		{
+			Name:      "segments",
+			Partition: "user id",
+			Key:       "segments",
+			Attributes: map[string]string{
+				"names":               "segment names in creation order",
+				"<segment>":           "initial targeting population",
+				"evolution:<segment>": "evolution settings",
+			},
+		},
+		{
+			Name:      "version",
+			Partition: versionPartition,
+			Key:       versionKey,
+			Attributes: map[string]string{
+				versionAttribute: "last applied migration",
+				"updated_at":     "time of the last migration",
+			},
+		},
+	},
+}
+
+// pollInterval is the time between the checks of the status of the indexes
+var pollInterval = 5 * time.Second
+
+// Create creates the table with its indexes and waits until it's active
+func (t Table) Create(ctx context.Context, svc *dynamodb.DynamoDB) error {
+	if _, err := svc.CreateTableWithContext(ctx, t.createInput()); err != nil {
+		return err
+	}
+
+	return svc.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(t.Name),
+	})
+}
+
+// Update adds the indexes that the table doesn't have, one at a time because
+// dynamo only creates an index per update, and waits until they are active
+func (t Table) Update(ctx context.Context, svc *dynamodb.DynamoDB) error {
+	out, err := svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(t.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, index := range t.missingIndexes(out.Table) {
+		in := &dynamodb.UpdateTableInput{
+			TableName:            aws.String(t.Name),
+			AttributeDefinitions: t.attributeDefinitions(),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{
+					Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+						IndexName:  aws.String(index.Name),
+						KeySchema:  keySchema(index.Partition, index.Sort),
+						Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+					},
+				},
+			},
+		}
+		if _, err := svc.UpdateTableWithContext(ctx, in); err != nil {
+			return err
+		}
+		if err := t.waitIndexes(ctx, svc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply creates the table when it doesn't exist or updates it
+func (t Table) Apply(ctx context.Context, svc *dynamodb.DynamoDB) error {
+	_, err := svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(t.Name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		return t.Create(ctx, svc)
+	}
+	if err != nil {
+		return err
+	}
+
+	return t.Update(ctx, svc)
+}
+
+// Delete deletes the table and waits until it doesn't exist
+func (t Table) Delete(ctx context.Context, svc *dynamodb.DynamoDB) error {
+	in := &dynamodb.DeleteTableInput{
+		TableName: aws.String(t.Name),
+	}
+	if _, err := svc.DeleteTableWithContext(ctx, in); err != nil {
+		return err
+	}
+
+	return svc.WaitUntilTableNotExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(t.Name),
+	})
+}
+
+func (t Table) createInput() *dynamodb.CreateTableInput {
+	in := &dynamodb.CreateTableInput{
+		TableName:            aws.String(t.Name),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: t.attributeDefinitions(),
+		KeySchema:            keySchema(t.Partition, t.Key),
+	}
+	for _, index := range t.Indexes {
+		in.GlobalSecondaryIndexes = append(in.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  keySchema(index.Partition, index.Sort),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		})
+	}
+
+	return in
+}
+
+// attributeDefinitions returns the string attributes of the keys of the table and its indexes
+func (t Table) attributeDefinitions() []*dynamodb.AttributeDefinition {
+	definitions := []*dynamodb.AttributeDefinition{}
+	defined := map[string]bool{}
+	attributes := []string{t.Partition, t.Key}
+	for _, index := range t.Indexes {
+		attributes = append(attributes, index.Partition, index.Sort)
+	}
+	for _, a := range attributes {
+		if defined[a] {
+			continue
+		}
+		defined[a] = true
+		definitions = append(definitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(a),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+	}
+
+	return definitions
+}
+
+// missingIndexes returns the indexes of the description that the table doesn't have
+func (t Table) missingIndexes(table *dynamodb.TableDescription) []Index {
+	existing := map[string]bool{}
+	if table != nil {
+		for _, index := range table.GlobalSecondaryIndexes {
+			existing[aws.StringValue(index.IndexName)] = true
+		}
+	}
+
+	missing := []Index{}
+	for _, index := range t.Indexes {
+		if !existing[index.Name] {
+			missing = append(missing, index)
+		}
+	}
+
+	return missing
+}
+
+// waitIndexes waits until every index of the table is active
+func (t Table) waitIndexes(ctx context.Context, svc *dynamodb.DynamoDB) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		out, err := svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(t.Name),
+		})
+		if err != nil {
+			return err
+		}
+		active := true
+		for _, index := range out.Table.GlobalSecondaryIndexes {
+			if aws.StringValue(index.IndexStatus) != dynamodb.IndexStatusActive {
+				active = false
+			}
+		}
+		if active {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// keySchema returns the key schema of the partition and sort attributes
+func keySchema(partition, sort string) []*dynamodb.KeySchemaElement {
+	return []*dynamodb.KeySchemaElement{
+		{
+			AttributeName: aws.String(partition),
+			KeyType:       aws.String(dynamodb.KeyTypeHash),
+		},
+		{
+			AttributeName: aws.String(sort),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateInput(t *testing.T) {
+	assert := assert.New(t)
+	in := Trinacia.createInput()
+
+	assert.Equal("trinacia", aws.StringValue(in.TableName))
+	assert.Len(in.GlobalSecondaryIndexes, len(Trinacia.Indexes))
+	attributes := []string{}
+	for _, a := range in.AttributeDefinitions {
+		attributes = append(attributes, aws.StringValue(a.AttributeName))
+	}
+	assert.Equal([]string{"partition", "key", "sort", "secondSort", "thirdSort", "fourthSort", "fifthSort"}, attributes)
+	for _, index := range in.GlobalSecondaryIndexes {
+		assert.Equal("partition", aws.StringValue(index.KeySchema[0].AttributeName))
+		assert.Equal(dynamodb.KeyTypeRange, aws.StringValue(index.KeySchema[1].KeyType))
+	}
+}
+
+func TestMissingIndexes(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Table    *dynamodb.TableDescription
+		Expected []string
+	}{
+		{
+			Name:     "Missing Description",
+			Expected: []string{"partition-sort-index", "partition-secondSort-index", "partition-thirdSort-index", "partition-fourthSort-index", "partition-fifthSort-index"},
+		},
+		{
+			Name:     "Previous Indexes",
+			Table:    describe(Trinacia.Indexes[:3]),
+			Expected: []string{"partition-fourthSort-index", "partition-fifthSort-index"},
+		},
+		{
+			Name:     "Up To Date",
+			Table:    describe(Trinacia.Indexes),
+			Expected: []string{},
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			names := []string{}
+			for _, index := range Trinacia.missingIndexes(tc.Table) {
+				names = append(names, index.Name)
+			}
+			assert.Equal(tc.Expected, names)
+		})
+	}
+}
+
+// describe returns the description of a table with the indexes
+func describe(indexes []Index) *dynamodb.TableDescription {
+	table := &dynamodb.TableDescription{}
+	for _, index := range indexes {
+		table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName: aws.String(index.Name),
+		})
+	}
+
+	return table
+}
+
+func TestValidate(t *testing.T) {
+	up := func(context.Context, *dynamodb.DynamoDB, string) error { return nil }
+	cases := []struct {
+		Name       string
+		Migrations []Migration
+		Error      error
+	}{
+		{
+			Name:       "Migrations",
+			Migrations: Migrations,
+		},
+		{
+			Name:       "Increasing Versions",
+			Migrations: []Migration{{Version: 1, Migrate: up}, {Version: 3, Migrate: up}},
+		},
+		{
+			Name:       "Repeated Version",
+			Migrations: []Migration{{Version: 1, Migrate: up}, {Version: 1, Migrate: up}},
+			Error:      ErrorMigrationOrder,
+		},
+		{
+			Name:       "Decreasing Version",
+			Migrations: []Migration{{Version: 2, Migrate: up}, {Version: 1, Migrate: up}},
+			Error:      ErrorMigrationOrder,
+		},
+		{
+			Name:       "Zero Version",
+			Migrations: []Migration{{Migrate: up}},
+			Error:      ErrorMigrationOrder,
+		},
+		{
+			Name:       "Missing Migrate",
+			Migrations: []Migration{{Version: 1}},
+			Error:      ErrorMigrationOrder,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(tc.Error, validate(tc.Migrations))
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	assert := assert.New(t)
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 4}}
+
+	assert.Len(pending(migrations, 0), 3)
+	assert.Equal([]Migration{{Version: 4}}, pending(migrations, 2))
+	assert.Empty(pending(migrations, 4))
+}
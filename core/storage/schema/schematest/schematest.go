@@ -0,0 +1,63 @@
+// Package schematest creates tables of the schema in a local stand-in of dynamo for the storage tests
+package schematest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"bitbucket.org/backend/core/storage/schema"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// EndpointEnv is the environment variable with the endpoint of
+	// the local stand-in of dynamo used by the storage tests
+	EndpointEnv = "DYNAMODB_ENDPOINT"
+	// region of the local sessions, the local stand-ins accept any region
+	localRegion = "us-west-2"
+)
+
+// localSession returns a session of a local stand-in of dynamo,
+// the local stand-ins accept any credentials
+func localSession() (*session.Session, error) {
+	return session.NewSession(&aws.Config{
+		Region:      aws.String(localRegion),
+		Credentials: credentials.NewStaticCredentials("local", "local", ""),
+	})
+}
+
+// LocalTable creates a copy of the table with a unique name in the local stand-in
+// of dynamo set in EndpointEnv and deletes it at the end of the test, the test is
+// skipped when EndpointEnv isn't set. It returns a session whose requests are sent
+// to the local stand-in and the name of the table
+func LocalTable(t testing.TB, table schema.Table) (*session.Session, string) {
+	t.Helper()
+
+	endpoint := os.Getenv(EndpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s isn't set to the endpoint of a local dynamo", EndpointEnv)
+	}
+	sess, err := localSession()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sess = sess.Copy(aws.NewConfig().WithEndpoint(endpoint))
+	svc := dynamodb.New(sess)
+	table.Name = fmt.Sprintf("%s-%d", table.Name, time.Now().UnixNano())
+	if err := table.Create(context.Background(), svc); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := table.Delete(context.Background(), svc); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	})
+
+	return sess, table.Name
+}
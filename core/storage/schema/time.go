@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// TimeLayout is the layout of the times of the sort keys, the times are
+// in UTC so their lexical order is their chronological order
+const TimeLayout = time.RFC3339
+
+// timeLayouts are the layouts of the times that are normalized: RFC3339, the
+// ISO-8601 times of the Graph API and the default format of go stored before
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+}
+
+// ErrorInvalidTime the time doesn't have any of the accepted layouts
+var ErrorInvalidTime = errors.New("The time must be an ISO-8601 time")
+
+// FormatTime returns the time in the layout of the sort keys
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// NormalizeTime returns the time in the layout of the sort keys
+func NormalizeTime(s string) (string, error) {
+	// the monotonic clock reading of the go format isn't part of the time
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return FormatTime(t), nil
+		}
+	}
+
+	return "", ErrorInvalidTime
+}
@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTime(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Time     string
+		Expected string
+		Error    error
+	}{
+		{
+			Name:     "RFC3339",
+			Time:     "2020-08-01T10:30:00Z",
+			Expected: "2020-08-01T10:30:00Z",
+		},
+		{
+			Name:     "RFC3339 Offset",
+			Time:     "2020-08-01T05:30:00.5-05:00",
+			Expected: "2020-08-01T10:30:00Z",
+		},
+		{
+			Name:     "Graph API",
+			Time:     "2020-08-01T03:30:00-0700",
+			Expected: "2020-08-01T10:30:00Z",
+		},
+		{
+			Name:     "Go Format",
+			Time:     "2020-08-01 12:30:00.123456789 +0200 CEST m=+0.001",
+			Expected: "2020-08-01T10:30:00Z",
+		},
+		{
+			Name:  "Invalid Time",
+			Time:  "next week",
+			Error: ErrorInvalidTime,
+		},
+		{
+			Name:  "Empty Time",
+			Error: ErrorInvalidTime,
+		},
+	}
+	assert := assert.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			normalized, err := NormalizeTime(tc.Time)
+			assert.Equal(tc.Error, err)
+			assert.Equal(tc.Expected, normalized)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -13,18 +14,60 @@ import (
 
 type dynamo struct {
 	svc *dynamodb.DynamoDB
+	// ctx is the context of the requests to dynamo
+	ctx context.Context
+	// table is the name of the table of the items
+	table string
+	// endpoint overrides the endpoint of the session when it isn't empty
+	endpoint string
 }
 
+// Option configures a dynamo storage
+type Option func(*dynamo)
+
 // New isntanciates a session dynamo session to query information
 // about users
-func New(sess *session.Session) Storage {
+func New(sess *session.Session, config ...Option) Storage {
 	d := &dynamo{
-		svc: dynamodb.New(sess),
+		ctx:   context.Background(),
+		table: TableName,
+	}
+
+	for _, fn := range config {
+		fn(d)
 	}
 
+	cfg := aws.NewConfig()
+	if d.endpoint != "" {
+		cfg = cfg.WithEndpoint(d.endpoint)
+	}
+	d.svc = dynamodb.New(sess, cfg)
+
 	return d
 }
 
+// Endpoint sets the endpoint of the requests, like the url of a local stand-in of dynamo
+func Endpoint(url string) Option {
+	return func(d *dynamo) {
+		d.endpoint = url
+	}
+}
+
+// Table sets the name of the table of the items
+func Table(name string) Option {
+	return func(d *dynamo) {
+		d.table = name
+	}
+}
+
+// WithContext returns a copy of the storage whose requests are cancelled with the context
+func (d *dynamo) WithContext(ctx context.Context) Storage {
+	dc := *d
+	dc.ctx = ctx
+
+	return &dc
+}
+
 const (
 	// TableName is the table used to store the data
 	TableName = "trinacia"
@@ -61,7 +104,7 @@ func (d *dynamo) StoreUser(u *entities.User) error {
 	u.CreationTime = creationTime
 
 	in := &dynamodb.UpdateItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String("users"),
@@ -93,7 +136,7 @@ func (d *dynamo) StoreUser(u *entities.User) error {
 		},
 		UpdateExpression: aws.String("set #id=:id, #name=:name, #email=:email, #sort=:creation_time, #creation_time=:creation_time"),
 	}
-	_, err := d.svc.UpdateItem(in)
+	_, err := d.svc.UpdateItemWithContext(d.ctx, in)
 
 	return err
 }
@@ -105,7 +148,7 @@ func (d *dynamo) GetUser(userID string) (*entities.User, error) {
 	u := &entities.User{}
 
 	in := &dynamodb.GetItemInput{
-		TableName: aws.String(TableName),
+		TableName: aws.String(d.table),
 		Key: map[string]*dynamodb.AttributeValue{
 			"partition": {
 				S: aws.String("users"),
@@ -115,7 +158,7 @@ func (d *dynamo) GetUser(userID string) (*entities.User, error) {
 			},
 		},
 	}
-	out, err := d.svc.GetItem(in)
+	out, err := d.svc.GetItemWithContext(d.ctx, in)
 	if err != nil {
 		return nil, err
 	}
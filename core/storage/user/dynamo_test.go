@@ -4,53 +4,30 @@ import (
 	"testing"
 
 	"bitbucket.org/backend/core/entities"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"bitbucket.org/backend/core/storage/schema"
+	"bitbucket.org/backend/core/storage/schema/schematest"
 	"github.com/stretchr/testify/assert"
 )
 
-func testCreateUser(t *testing.T, user *entities.User) {
-	t.Helper()
+// testDynamo returns a storage of a new table of the local stand-in of dynamo
+// set in schematest.EndpointEnv, the table is deleted at the end of the test
+func testDynamo(t *testing.T) Storage {
+	sess, table := schematest.LocalTable(t, schema.Trinacia)
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	storage := New(sess)
+	return New(sess, Table(table))
+}
 
-	err = storage.StoreUser(user)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+func TestDynamo(t *testing.T) {
+	testStorage(t, testDynamo)
 }
 
-func testDeleteItem(t *testing.T, partition, key string) {
+func testCreateUser(t *testing.T, storage Storage, user *entities.User) {
 	t.Helper()
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
+	err := storage.StoreUser(user)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	svc := dynamodb.New(sess)
-	in := &dynamodb.DeleteItemInput{
-		TableName: aws.String(TableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"partition": {
-				S: aws.String(partition),
-			},
-			"key": {
-				S: aws.String(key),
-			},
-		},
-	}
-	if _, err := svc.DeleteItem(in); err != nil {
-		t.Fatalf("err: %s", err)
-	}
 }
 
 func TestStoreUser(t *testing.T) {
@@ -93,20 +70,9 @@ func TestStoreUser(t *testing.T) {
 		},
 	}
 	assert := assert.New(t)
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-
-	storage := New(sess)
+	storage := testDynamo(t)
 
 	for _, tc := range cases {
-		if tc.Error == nil {
-			defer testDeleteItem(t, "users", tc.User.ID)
-		}
 		t.Run(tc.Name, func(t *testing.T) {
 			err := storage.StoreUser(tc.User)
 			assert.Equal(tc.Error, err)
@@ -145,24 +111,15 @@ func TestGetUser(t *testing.T) {
 		},
 	}
 	assert := assert.New(t)
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+	storage := testDynamo(t)
 
 	// create test users
 	for _, tc := range cases {
 		if tc.Error == nil {
-			testCreateUser(t, tc.Expected)
-			defer testDeleteItem(t, "users", tc.ID)
+			testCreateUser(t, storage, tc.Expected)
 		}
 	}
 
-	storage := New(sess)
-
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			user, err := storage.GetUser(tc.ID)
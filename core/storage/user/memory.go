@@ -0,0 +1,82 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bitbucket.org/backend/core/entities"
+)
+
+type memory struct {
+	*users
+	// ctx is the context of the operations, the storage fails once it's done
+	ctx context.Context
+}
+
+// users are the items of a memory storage, they are shared by its copies
+type users struct {
+	mu    sync.RWMutex
+	items map[string]entities.User
+}
+
+// NewMemory returns a storage that keeps the users in memory, it's safe for
+// concurrent use and behaves as the dynamo storage, so it can replace it in
+// tests and local development
+func NewMemory() Storage {
+	return &memory{
+		users: &users{items: map[string]entities.User{}},
+		ctx:   context.Background(),
+	}
+}
+
+// WithContext returns a copy of the storage that fails once the context is
+// done, the copy shares the stored users
+func (m *memory) WithContext(ctx context.Context) Storage {
+	mc := *m
+	mc.ctx = ctx
+
+	return &mc
+}
+
+func (m *memory) StoreUser(u *entities.User) error {
+	if u == nil {
+		return ErrorMissingUser
+	}
+	if u.ID == "" {
+		return ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[u.ID]; ok {
+		return ErrorUserAlreadyExists
+	}
+	u.CreationTime = time.Now().String()
+	m.items[u.ID] = *u
+
+	return nil
+}
+
+func (m *memory) GetUser(userID string) (*entities.User, error) {
+	if userID == "" {
+		return nil, ErrorMissingUserID
+	}
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.items[userID]
+	if !ok {
+		return nil, ErrorInvalidUser
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,145 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"bitbucket.org/backend/core/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStorage is the conformance suite of the storage implementations,
+// newStorage returns an empty storage for every test
+func testStorage(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("Store User", func(t *testing.T) {
+		cases := []struct {
+			Name  string
+			User  *entities.User
+			Error error
+		}{
+			{
+				Name: "New User",
+				User: &entities.User{
+					ID:    "1234",
+					Name:  "Andres",
+					Email: "andres@trinacia.com",
+				},
+			},
+			{
+				Name: "User Already Exists",
+				User: &entities.User{
+					ID:    "1234",
+					Name:  "Andres",
+					Email: "andres@trinacia.com",
+				},
+				Error: ErrorUserAlreadyExists,
+			},
+			{
+				Name: "Missing User ID",
+				User: &entities.User{
+					Name:  "Andres",
+					Email: "andres@trinacia.com",
+				},
+				Error: ErrorMissingUserID,
+			},
+			{
+				Name:  "Nil Pointer Reference",
+				Error: ErrorMissingUser,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := storage.StoreUser(tc.User)
+				assert.Equal(tc.Error, err)
+				if tc.Error != nil {
+					return
+				}
+				assert.NotEmpty(tc.User.CreationTime)
+				u, err := storage.GetUser(tc.User.ID)
+				assert.Nil(err)
+				assert.Equal(tc.User, u)
+			})
+		}
+	})
+
+	t.Run("Get User", func(t *testing.T) {
+		cases := []struct {
+			Name     string
+			ID       string
+			Expected bool
+			Error    error
+		}{
+			{
+				Name:     "Existing User",
+				ID:       "1234",
+				Expected: true,
+			},
+			{
+				Name:  "Invalid User",
+				ID:    "12345",
+				Error: ErrorInvalidUser,
+			},
+			{
+				Name:  "Missing User ID",
+				Error: ErrorMissingUserID,
+			},
+		}
+		assert := assert.New(t)
+		storage := newStorage(t)
+		stored := &entities.User{ID: "1234", Name: "Andres", Email: "andres@trinacia.com"}
+		if !assert.Nil(storage.StoreUser(stored)) {
+			return
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				u, err := storage.GetUser(tc.ID)
+				assert.Equal(tc.Error, err)
+				if !tc.Expected {
+					assert.Nil(u)
+					return
+				}
+				assert.Equal(stored, u)
+			})
+		}
+	})
+
+	t.Run("Canceled Context", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		if !assert.Nil(storage.StoreUser(&entities.User{ID: "1234"})) {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := storage.WithContext(ctx).GetUser("1234")
+		assert.Error(err)
+		_, err = storage.GetUser("1234")
+		assert.Nil(err)
+	})
+
+	t.Run("Concurrent Operations", func(t *testing.T) {
+		assert := assert.New(t)
+		storage := newStorage(t)
+		errs := make(chan error)
+		for i := 0; i < 10; i++ {
+			go func(i int) {
+				id := fmt.Sprint(i)
+				if err := storage.StoreUser(&entities.User{ID: id}); err != nil {
+					errs <- err
+					return
+				}
+				_, err := storage.GetUser(id)
+				errs <- err
+			}(i)
+		}
+		for i := 0; i < 10; i++ {
+			assert.Nil(<-errs)
+		}
+	})
+}
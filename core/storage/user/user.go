@@ -1,9 +1,15 @@
 package user
 
-import "bitbucket.org/backend/core/entities"
+import (
+	"context"
+
+	"bitbucket.org/backend/core/entities"
+)
 
 // Storage interface to get information from database
 type Storage interface {
 	StoreUser(u *entities.User) error
 	GetUser(userID string) (*entities.User, error)
+	// WithContext returns the storage with its requests bound to the context
+	WithContext(ctx context.Context) Storage
 }